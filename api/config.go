@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/config"
+	"git.neds.sh/matty/entain/logging"
+	"git.neds.sh/matty/entain/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Config is the configuration of the API gateway.
+type Config struct {
+	APIEndpoint         string           `config:"api_endpoint" usage:"API endpoint"`
+	GRPCEndpoint        string           `config:"grpc_endpoint" usage:"gRPC server endpoint"`
+	SportsGRPCEndpoint  string           `config:"sports_grpc_endpoint" usage:"Sports gRPC server endpoint"`
+	BettingGRPCEndpoint string           `config:"betting_grpc_endpoint" usage:"Betting gRPC server endpoint"`
+	WalletGRPCEndpoint  string           `config:"wallet_grpc_endpoint" usage:"Wallet gRPC server endpoint"`
+	TLS                 config.ServerTLS `config:"tls"`
+	BackendTLS          config.ClientTLS `config:"backend_tls"`
+	Timeouts            TimeoutsConfig   `config:"timeouts"`
+	Auth                auth.Config      `config:"auth"`
+	Partners            PartnersConfig   `config:"partners"`
+	Tracing             tracing.Config   `config:"tracing"`
+	Log                 logging.Config   `config:"log"`
+}
+
+// TimeoutsConfig configures the timeouts of the HTTP server. Zero disables a timeout.
+type TimeoutsConfig struct {
+	ReadHeader time.Duration `config:"read_header" usage:"Time allowed to read the headers of a request"`
+	Read       time.Duration `config:"read" usage:"Time allowed to read a whole request, including its body"`
+	Write      time.Duration `config:"write" usage:"Time allowed to write a response, from the end of reading the request headers"`
+	Idle       time.Duration `config:"idle" usage:"Time a keep-alive connection is kept open awaiting the next request"`
+	Shutdown   time.Duration `config:"shutdown" usage:"Time allowed for in-flight requests to complete on SIGTERM before they are closed; 0 waits for them"`
+}
+
+// PartnersConfig configures the API keys of partners, and their rate limits.
+type PartnersConfig struct {
+	File string `config:"file" usage:"JSON file of the partners calling the gateway with API keys, and their rate limits and quotas; API keys are disabled when empty"`
+}
+
+// Enabled reports whether partners may call the gateway with API keys.
+func (c PartnersConfig) Enabled() bool {
+	return c.File != ""
+}
+
+// defaultConfig returns the configuration used for any setting that is not configured.
+func defaultConfig() *Config {
+	return &Config{
+		APIEndpoint:         "localhost:8000",
+		GRPCEndpoint:        "localhost:9000",
+		SportsGRPCEndpoint:  "localhost:9001",
+		BettingGRPCEndpoint: "localhost:9002",
+		WalletGRPCEndpoint:  "localhost:9003",
+		Timeouts: TimeoutsConfig{
+			ReadHeader: 10 * time.Second,
+			Idle:       120 * time.Second,
+			Shutdown:   30 * time.Second,
+		},
+		Auth:    auth.DefaultConfig,
+		Tracing: tracing.DefaultConfig,
+		Log:     logging.DefaultConfig,
+	}
+}
+
+// Validate checks every setting of the configuration.
+func (c *Config) Validate() error {
+	for key, address := range map[string]string{
+		"api_endpoint":          c.APIEndpoint,
+		"grpc_endpoint":         c.GRPCEndpoint,
+		"sports_grpc_endpoint":  c.SportsGRPCEndpoint,
+		"betting_grpc_endpoint": c.BettingGRPCEndpoint,
+		"wallet_grpc_endpoint":  c.WalletGRPCEndpoint,
+	} {
+		if err := config.ValidateAddress(key, address); err != nil {
+			return err
+		}
+	}
+
+	if err := c.TLS.Validate("tls"); err != nil {
+		return err
+	}
+
+	if err := c.BackendTLS.Validate("backend_tls"); err != nil {
+		return err
+	}
+
+	for key, timeout := range map[string]time.Duration{
+		"timeouts.read_header": c.Timeouts.ReadHeader,
+		"timeouts.read":        c.Timeouts.Read,
+		"timeouts.write":       c.Timeouts.Write,
+		"timeouts.idle":        c.Timeouts.Idle,
+		"timeouts.shutdown":    c.Timeouts.Shutdown,
+	} {
+		if err := config.ValidateTimeout(key, timeout); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Auth.Validate("auth"); err != nil {
+		return err
+	}
+
+	// Partners alone may call a gateway without a JWKS file, which otherwise forwards requests
+	// without an identity only when configured to be insecure.
+	if !c.Auth.Enabled() && !c.Auth.Insecure && !c.Partners.Enabled() {
+		return errors.New("auth.jwks_file must be set to authenticate requests, or auth.insecure to forward them without an identity")
+	}
+
+	if err := c.Tracing.Validate("tracing"); err != nil {
+		return err
+	}
+
+	return c.Log.Validate("log")
+}
+
+// dialOptions returns the options backends are dialled with, propagating the trace, ID and
+// caller identity of each request.
+func (c *Config) dialOptions() ([]grpc.DialOption, error) {
+	opts := []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(tracing.UnaryClientInterceptor, logging.UnaryClientInterceptor, auth.UnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(tracing.StreamClientInterceptor, logging.StreamClientInterceptor, auth.StreamClientInterceptor),
+	}
+
+	if !c.BackendTLS.Enabled {
+		return append(opts, grpc.WithInsecure()), nil
+	}
+
+	tlsConfig, err := c.BackendTLS.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))), nil
+}
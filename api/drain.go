@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// drainer ends the streams proxied by the gateway once it starts draining. Streamed responses,
+// such as those of /v1/watch-races, last until the client goes away, so would otherwise hold up a
+// graceful shutdown until its deadline. Requests that are not streamed are left to complete.
+type drainer struct {
+	draining chan struct{}
+	once     sync.Once
+}
+
+func newDrainer() *drainer {
+	return &drainer{draining: make(chan struct{})}
+}
+
+// drain starts draining.
+func (d *drainer) drain() {
+	d.once.Do(func() { close(d.draining) })
+}
+
+// Handler cancels the requests handled by next that are streaming their response, recognised by
+// flushing it, once draining starts.
+func (d *drainer) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		sw := &streamWriter{ResponseWriter: w, flusher: flusher, draining: d.draining, cancel: cancel}
+
+		go func() {
+			select {
+			case <-d.draining:
+				if sw.isStreaming() {
+					cancel()
+				}
+			case <-ctx.Done():
+			}
+		}()
+
+		next.ServeHTTP(sw, r.WithContext(ctx))
+	})
+}
+
+// streamWriter records whether a response is being streamed.
+type streamWriter struct {
+	http.ResponseWriter
+	flusher   http.Flusher
+	draining  <-chan struct{}
+	cancel    context.CancelFunc
+	streaming int32
+}
+
+func (w *streamWriter) isStreaming() bool {
+	return atomic.LoadInt32(&w.streaming) == 1
+}
+
+// Flush marks the response as streamed, cancelling it should draining have started.
+func (w *streamWriter) Flush() {
+	atomic.StoreInt32(&w.streaming, 1)
+
+	select {
+	case <-w.draining:
+		w.cancel()
+	default:
+	}
+
+	w.flusher.Flush()
+}
@@ -3,10 +3,22 @@ module git.neds.sh/matty/entain/api
 go 1.16
 
 require (
+	git.neds.sh/matty/entain/auth v0.0.0-00010101000000-000000000000
+	git.neds.sh/matty/entain/config v0.0.0-00010101000000-000000000000
+	git.neds.sh/matty/entain/logging v0.0.0-00010101000000-000000000000
+	git.neds.sh/matty/entain/tracing v0.0.0-00010101000000-000000000000
 	github.com/golang/protobuf v1.4.3
 	github.com/grpc-ecosystem/grpc-gateway/v2 v2.3.0
+	github.com/prometheus/client_golang v1.9.0
 	google.golang.org/genproto v0.0.0-20210226172003-ab064af71705
 	google.golang.org/grpc v1.36.0
 	google.golang.org/grpc/cmd/protoc-gen-go-grpc v1.1.0
 	google.golang.org/protobuf v1.25.1-0.20201208041424-160c7477e0e8
 )
+
+replace (
+	git.neds.sh/matty/entain/auth => ../auth
+	git.neds.sh/matty/entain/config => ../config
+	git.neds.sh/matty/entain/logging => ../logging
+	git.neds.sh/matty/entain/tracing => ../tracing
+)
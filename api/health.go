@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// readinessTimeout bounds the health check of each backend made by a readiness probe.
+const readinessTimeout = 2 * time.Second
+
+// backend is a service the gateway forwards requests to.
+type backend struct {
+	name string
+	conn *grpc.ClientConn
+}
+
+// readiness reports the gateway ready once every backend is: serving, according to its gRPC
+// health service, or reachable should it not implement one.
+type readiness struct {
+	backends []backend
+}
+
+// readinessResponse is the body of a readiness probe.
+type readinessResponse struct {
+	Ready    bool              `json:"ready"`
+	Backends map[string]string `json:"backends"`
+}
+
+// healthz reports the gateway is alive: able to serve requests, whether or not its backends are.
+func healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// ServeHTTP checks the backends concurrently, responding 200 if all are ready and 503 if not,
+// with the state of each backend.
+func (h *readiness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp := readinessResponse{Ready: true, Backends: make(map[string]string, len(h.backends))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, b := range h.backends {
+		wg.Add(1)
+
+		go func(b backend) {
+			defer wg.Done()
+
+			state, ready := check(r.Context(), b.conn)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			resp.Backends[b.name] = state
+			resp.Ready = resp.Ready && ready
+		}(b)
+	}
+
+	wg.Wait()
+
+	code := http.StatusOK
+	if !resp.Ready {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// check returns the state of a backend, and whether it is ready.
+func check(ctx context.Context, conn *grpc.ClientConn) (string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+		// The backend answered, but has no health service to ask.
+		return "REACHABLE", true
+	case err != nil:
+		return status.Convert(err).Message(), false
+	default:
+		return resp.Status.String(), resp.Status == grpc_health_v1.HealthCheckResponse_SERVING
+	}
+}
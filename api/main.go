@@ -2,44 +2,202 @@ package main
 
 import (
 	"context"
-	"flag"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"git.neds.sh/matty/entain/api/proto/betting"
 	"git.neds.sh/matty/entain/api/proto/racing"
+	"git.neds.sh/matty/entain/api/proto/sports"
+	"git.neds.sh/matty/entain/api/proto/wallet"
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/config"
+	"git.neds.sh/matty/entain/logging"
+	"git.neds.sh/matty/entain/tracing"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
-)
 
-var (
-	apiEndpoint  = flag.String("api-endpoint", "localhost:8000", "API endpoint")
-	grpcEndpoint = flag.String("grpc-endpoint", "localhost:9000", "gRPC server endpoint")
+	// Registers the error detail types, so that details returned by services can be rendered.
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
 )
 
+// flushTimeout bounds the time spent exporting the spans yet to be exported on exit.
+const flushTimeout = 5 * time.Second
+
 func main() {
-	flag.Parse()
+	cfg := defaultConfig()
+	if err := config.Load("api", cfg, os.Args[1:]); err != nil {
+		log.Fatalf("failed loading config: %s\n", err)
+	}
 
-	if err := run(); err != nil {
-		log.Printf("failed running api server: %s\n", err)
+	if err := logging.Setup("api", cfg.Log); err != nil {
+		log.Fatalf("failed setting up logging: %s\n", err)
+	}
+
+	if err := run(cfg); err != nil {
+		logging.FromContext(context.Background()).WithError(err).Error("failed running api server")
 	}
 }
 
-func run() error {
+func run(cfg *Config) error {
+	// The server drains on SIGTERM, or an interrupt when run from a terminal.
+	shutdown, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	flushTraces, err := tracing.Setup("api", cfg.Tracing)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+
+		if err := flushTraces(ctx); err != nil {
+			logging.FromContext(ctx).WithError(err).Error("failed flushing traces")
+		}
+	}()
+
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	mux := runtime.NewServeMux()
-	if err := racing.RegisterRacingHandlerFromEndpoint(
-		ctx,
-		mux,
-		*grpcEndpoint,
-		[]grpc.DialOption{grpc.WithInsecure()},
-	); err != nil {
+	opts, err := cfg.dialOptions()
+	if err != nil {
+		return err
+	}
+
+	var partners *partnerStore
+	if cfg.Partners.Enabled() {
+		if partners, err = loadPartners(cfg.Partners.File); err != nil {
+			return err
+		}
+
+		// The requests of partners are limited once routed, by the RPC method they call.
+		limiter := newRateLimiter()
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(limiter.UnaryClientInterceptor),
+			grpc.WithChainStreamInterceptor(limiter.StreamClientInterceptor),
+		)
+	}
+
+	mux := runtime.NewServeMux(withRouteRecorder())
+
+	// Each backend is dialled once, its connection shared by its handlers and readiness probes.
+	var backends []backend
+
+	for _, b := range []struct {
+		name     string
+		endpoint string
+		register func(context.Context, *runtime.ServeMux, *grpc.ClientConn) error
+	}{
+		{"racing", cfg.GRPCEndpoint, racing.RegisterRacingHandler},
+		{"sports", cfg.SportsGRPCEndpoint, sports.RegisterSportsHandler},
+		{"betting", cfg.BettingGRPCEndpoint, betting.RegisterBettingHandler},
+		{"wallet", cfg.WalletGRPCEndpoint, wallet.RegisterWalletHandler},
+	} {
+		conn, err := grpc.DialContext(ctx, b.endpoint, opts...)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		backends = append(backends, backend{name: b.name, conn: conn})
+
+		if err := b.register(ctx, mux, conn); err != nil {
+			return err
+		}
+	}
+
+	// Requests are authenticated by their API key, should they have one, or bearer token.
+	var gateway http.Handler = mux
+	switch {
+	case cfg.Auth.Enabled():
+		verifier, err := auth.NewVerifier(cfg.Auth)
+		if err != nil {
+			return err
+		}
+
+		gateway = auth.Handler(verifier, mux)
+	case partners != nil:
+		gateway = requireAPIKey(mux)
+	default:
+		logging.FromContext(ctx).Warn("auth.insecure is set, requests are forwarded without an identity")
+	}
+
+	if partners != nil {
+		gateway = partnerHandler(partners, mux, gateway)
+	}
+
+	drainer := newDrainer()
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/healthz", healthz)
+	handler.Handle("/readyz", &readiness{backends: backends})
+	handler.Handle("/metrics", promhttp.Handler())
+	// Requests to the gateway are identified, traced, logged, measured and authenticated, in that
+	// order.
+	handler.Handle("/", logging.RequestIDHandler(tracing.Handler(logging.AccessLogHandler(instrument(drainer.Handler(gateway))))))
+
+	server := &http.Server{
+		Addr:              cfg.APIEndpoint,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.Timeouts.ReadHeader,
+		ReadTimeout:       cfg.Timeouts.Read,
+		WriteTimeout:      cfg.Timeouts.Write,
+		IdleTimeout:       cfg.Timeouts.Idle,
+	}
+
+	if cfg.TLS.Enabled() {
+		if server.TLSConfig, err = cfg.TLS.Config(); err != nil {
+			return err
+		}
+	}
+
+	served := make(chan error, 1)
+
+	go func() {
+		if cfg.TLS.Enabled() {
+			// The certificate is held by the TLS config.
+			served <- server.ListenAndServeTLS("", "")
+		} else {
+			served <- server.ListenAndServe()
+		}
+	}()
+
+	logger := logging.FromContext(shutdown)
+	logger.Infof("API server listening on: %s", cfg.APIEndpoint)
+
+	select {
+	case err := <-served:
+		return err
+	case <-shutdown.Done():
+	}
+
+	logger.Infof("draining API server, for up to %s", cfg.Timeouts.Shutdown)
+
+	drainCtx := context.Background()
+	if cfg.Timeouts.Shutdown > 0 {
+		var cancelDrain context.CancelFunc
+		drainCtx, cancelDrain = context.WithTimeout(drainCtx, cfg.Timeouts.Shutdown)
+		defer cancelDrain()
+	}
+
+	drainer.drain()
+
+	if err := server.Shutdown(drainCtx); errors.Is(err, context.DeadlineExceeded) {
+		logger.Warnf("shutdown deadline of %s passed, closing in-flight requests", cfg.Timeouts.Shutdown)
+		return server.Close()
+	} else if err != nil {
 		return err
 	}
 
-	log.Printf("API server listening on: %s\n", *apiEndpoint)
+	logger.Info("API server stopped")
 
-	return http.ListenAndServe(*apiEndpoint, mux)
+	return nil
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"git.neds.sh/matty/entain/tracing"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/metadata"
+)
+
+// unmatchedRoute labels requests that match no route of the gateway, keeping the cardinality of
+// the metrics bounded whatever paths are requested.
+const unmatchedRoute = "unmatched"
+
+var (
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of requests completed by the gateway, by RPC method and status code.",
+	}, []string{"method", "rpc_method", "code"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken by the gateway to complete requests, by RPC method. Streams are timed until they end.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "rpc_method"})
+)
+
+// routeKey keys the route of a request in its context.
+type routeKey struct{}
+
+// route is the RPC method a request was routed to, recorded as the gateway routes it.
+type route struct {
+	rpcMethod string
+}
+
+// withRouteRecorder records the RPC method each request is routed to by the gateway, for metrics
+// to be labelled with and its span to be named for. Metadata annotators are called once a request is routed, with the
+// method in its context.
+func withRouteRecorder() runtime.ServeMuxOption {
+	return runtime.WithMetadata(func(ctx context.Context, r *http.Request) metadata.MD {
+		rpcMethod, ok := runtime.RPCMethod(ctx)
+		if !ok {
+			return nil
+		}
+
+		if rt, ok := ctx.Value(routeKey{}).(*route); ok {
+			rt.rpcMethod = rpcMethod
+		}
+
+		tracing.SetRoute(ctx, rpcMethod)
+
+		return nil
+	})
+}
+
+// instrument records the count, status code and latency of the requests handled by next.
+func instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		rt := &route{rpcMethod: unmatchedRoute}
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), routeKey{}, rt)))
+
+		requests.WithLabelValues(r.Method, rt.rpcMethod, strconv.Itoa(sw.code)).Inc()
+		requestDuration.WithLabelValues(r.Method, rt.rpcMethod).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code = code
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush passes flushes through, so that streamed responses are not buffered.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"git.neds.sh/matty/entain/auth"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyHeader holds the API key a partner authenticates with.
+const apiKeyHeader = "X-API-Key"
+
+// defaultRoute keys the rate limit applied to each route a partner has no rate limit for.
+const defaultRoute = "*"
+
+// partner is a B2B partner calling the gateway with API keys.
+type partner struct {
+	// ID identifies the partner, which calls services as the subject partners/{id}.
+	ID string `json:"id"`
+	// KeyHashes are the hex SHA-256 hashes of the API keys issued to the partner. Several keys
+	// may be valid at once, so that keys can be rotated.
+	KeyHashes []string `json:"key_hashes"`
+	// Roles are the roles granted to the partner.
+	Roles []string `json:"roles"`
+	// RateLimit limits the rate of the requests made by the partner, across all routes.
+	RateLimit limit `json:"rate_limit"`
+	// RouteRateLimits limit the rate of the requests made by the partner to each route, keyed by
+	// RPC method, e.g. /racing.Racing/ListRaces, or * for every route not otherwise limited.
+	RouteRateLimits map[string]limit `json:"route_rate_limits"`
+	// DailyQuota is the number of requests the partner may make each UTC day; zero is unlimited.
+	DailyQuota int64 `json:"daily_quota"`
+}
+
+// routeRateLimit returns the rate limit of the partner's requests to a route.
+func (p *partner) routeRateLimit(route string) limit {
+	if l, ok := p.RouteRateLimits[route]; ok {
+		return l
+	}
+
+	return p.RouteRateLimits[defaultRoute]
+}
+
+// partnersFile is the layout of the partners file.
+type partnersFile struct {
+	Partners []*partner `json:"partners"`
+}
+
+// partnerStore looks up partners by their API keys.
+type partnerStore struct {
+	byKeyHash map[[sha256.Size]byte]*partner
+}
+
+// loadPartners reads the partners, and the hashes of their API keys, from a JSON file.
+func loadPartners(file string) (*partnerStore, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var f partnersFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("reading partners %s: %w", file, err)
+	}
+
+	store := &partnerStore{byKeyHash: make(map[[sha256.Size]byte]*partner)}
+	ids := make(map[string]bool, len(f.Partners))
+
+	for i, p := range f.Partners {
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("partners %s: partners[%d] %w", file, i, err)
+		}
+
+		if ids[p.ID] {
+			return nil, fmt.Errorf("partners %s: partner %q is listed twice", file, p.ID)
+		}
+
+		ids[p.ID] = true
+
+		for _, h := range p.KeyHashes {
+			b, err := hex.DecodeString(h)
+			if err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("partners %s: partner %q has key hash %q, which is not a hex SHA-256 hash", file, p.ID, h)
+			}
+
+			var hash [sha256.Size]byte
+			copy(hash[:], b)
+
+			if _, ok := store.byKeyHash[hash]; ok {
+				return nil, fmt.Errorf("partners %s: key hash %s is listed twice", file, h)
+			}
+
+			store.byKeyHash[hash] = p
+		}
+	}
+
+	return store, nil
+}
+
+// validate checks the partner has an ID, a key hash and valid limits.
+func (p *partner) validate() error {
+	if strings.TrimSpace(p.ID) == "" {
+		return errors.New("id must be set")
+	}
+
+	if len(p.KeyHashes) == 0 {
+		return fmt.Errorf("%q must have a key hash", p.ID)
+	}
+
+	if err := p.RateLimit.validate(); err != nil {
+		return fmt.Errorf("%q rate_limit %w", p.ID, err)
+	}
+
+	for route, l := range p.RouteRateLimits {
+		if route != defaultRoute && !strings.HasPrefix(route, "/") {
+			return fmt.Errorf("%q route_rate_limits has route %q, which is not an RPC method of the form /package.Service/Method", p.ID, route)
+		}
+
+		if err := l.validate(); err != nil {
+			return fmt.Errorf("%q route_rate_limits[%q] %w", p.ID, route, err)
+		}
+	}
+
+	if p.DailyQuota < 0 {
+		return fmt.Errorf("%q daily_quota must not be negative", p.ID)
+	}
+
+	return nil
+}
+
+// lookup returns the partner an API key was issued to.
+func (s *partnerStore) lookup(key string) (*partner, bool) {
+	p, ok := s.byKeyHash[sha256.Sum256([]byte(key))]
+
+	return p, ok
+}
+
+// partnerKey keys the partner request being served in a context.
+type partnerKey struct{}
+
+// partnerRequest is a request made by a partner, whose rate limits are reported in its response
+// headers.
+type partnerRequest struct {
+	partner *partner
+	header  http.Header
+}
+
+// partnerFromContext returns the partner request a context is for, if any.
+func partnerFromContext(ctx context.Context) (*partnerRequest, bool) {
+	pr, ok := ctx.Value(partnerKey{}).(*partnerRequest)
+
+	return pr, ok
+}
+
+// partnerHandler authenticates requests carrying an API key as the partner it was issued to,
+// serving them with mux, and passes requests without one to next. Requests with an unknown key
+// are rejected with 401 Unauthorized, as the gateway renders an Unauthenticated error.
+func partnerHandler(store *partnerStore, mux *runtime.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(apiKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		p, ok := store.lookup(key)
+		if !ok {
+			_, outbound := runtime.MarshalerForRequest(mux, r)
+			runtime.HTTPError(r.Context(), mux, outbound, w, r, status.Error(codes.Unauthenticated, "invalid API key"))
+
+			return
+		}
+
+		ctx := auth.NewContext(r.Context(), &auth.Identity{Subject: "partners/" + p.ID, Roles: p.Roles})
+		ctx = context.WithValue(ctx, partnerKey{}, &partnerRequest{partner: p, header: w.Header()})
+
+		mux.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireAPIKey rejects every request with 401 Unauthorized, for requests without an API key
+// when partners are the only callers authenticated.
+func requireAPIKey(mux *runtime.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		runtime.HTTPError(r.Context(), mux, outbound, w, r, status.Errorf(codes.Unauthenticated, "missing %s header", apiKeyHeader))
+	})
+}
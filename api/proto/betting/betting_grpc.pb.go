@@ -0,0 +1,233 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.6.1
+// source: betting/betting.proto
+
+package betting
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// BettingClient is the client API for Betting service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BettingClient interface {
+	// PlaceBet places a bet on a race that is open for betting. Win, place and each-way
+	// bets are placed on a runner at its current fixed-odds price, and exotic bets on a selection
+	// of runners, paying tote dividends.
+	// Its total stake is reserved from the account of the caller, which must hold the funds.
+	// Should the wallet fail to answer, the bet is left PENDING and UNAVAILABLE returned: the bet
+	// is placed by a retry of the request, or by the service once the wallet answers.
+	PlaceBet(ctx context.Context, in *PlaceBetRequest, opts ...grpc.CallOption) (*Bet, error)
+	// GetBet returns a single bet by its resource name.
+	GetBet(ctx context.Context, in *GetBetRequest, opts ...grpc.CallOption) (*Bet, error)
+	// ListSettlements returns the settlement ledger of a bet, oldest first.
+	ListSettlements(ctx context.Context, in *ListSettlementsRequest, opts ...grpc.CallOption) (*ListSettlementsResponse, error)
+	// QuoteBet checks a bet could be placed, returning its number of combinations and total stake
+	// without placing it.
+	QuoteBet(ctx context.Context, in *QuoteBetRequest, opts ...grpc.CallOption) (*QuoteBetResponse, error)
+}
+
+type bettingClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBettingClient(cc grpc.ClientConnInterface) BettingClient {
+	return &bettingClient{cc}
+}
+
+func (c *bettingClient) PlaceBet(ctx context.Context, in *PlaceBetRequest, opts ...grpc.CallOption) (*Bet, error) {
+	out := new(Bet)
+	err := c.cc.Invoke(ctx, "/betting.Betting/PlaceBet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bettingClient) GetBet(ctx context.Context, in *GetBetRequest, opts ...grpc.CallOption) (*Bet, error) {
+	out := new(Bet)
+	err := c.cc.Invoke(ctx, "/betting.Betting/GetBet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bettingClient) ListSettlements(ctx context.Context, in *ListSettlementsRequest, opts ...grpc.CallOption) (*ListSettlementsResponse, error) {
+	out := new(ListSettlementsResponse)
+	err := c.cc.Invoke(ctx, "/betting.Betting/ListSettlements", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bettingClient) QuoteBet(ctx context.Context, in *QuoteBetRequest, opts ...grpc.CallOption) (*QuoteBetResponse, error) {
+	out := new(QuoteBetResponse)
+	err := c.cc.Invoke(ctx, "/betting.Betting/QuoteBet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BettingServer is the server API for Betting service.
+// All implementations must embed UnimplementedBettingServer
+// for forward compatibility
+type BettingServer interface {
+	// PlaceBet places a bet on a race that is open for betting. Win, place and each-way
+	// bets are placed on a runner at its current fixed-odds price, and exotic bets on a selection
+	// of runners, paying tote dividends.
+	// Its total stake is reserved from the account of the caller, which must hold the funds.
+	// Should the wallet fail to answer, the bet is left PENDING and UNAVAILABLE returned: the bet
+	// is placed by a retry of the request, or by the service once the wallet answers.
+	PlaceBet(context.Context, *PlaceBetRequest) (*Bet, error)
+	// GetBet returns a single bet by its resource name.
+	GetBet(context.Context, *GetBetRequest) (*Bet, error)
+	// ListSettlements returns the settlement ledger of a bet, oldest first.
+	ListSettlements(context.Context, *ListSettlementsRequest) (*ListSettlementsResponse, error)
+	// QuoteBet checks a bet could be placed, returning its number of combinations and total stake
+	// without placing it.
+	QuoteBet(context.Context, *QuoteBetRequest) (*QuoteBetResponse, error)
+	mustEmbedUnimplementedBettingServer()
+}
+
+// UnimplementedBettingServer must be embedded to have forward compatible implementations.
+type UnimplementedBettingServer struct {
+}
+
+func (UnimplementedBettingServer) PlaceBet(context.Context, *PlaceBetRequest) (*Bet, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlaceBet not implemented")
+}
+func (UnimplementedBettingServer) GetBet(context.Context, *GetBetRequest) (*Bet, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBet not implemented")
+}
+func (UnimplementedBettingServer) ListSettlements(context.Context, *ListSettlementsRequest) (*ListSettlementsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSettlements not implemented")
+}
+func (UnimplementedBettingServer) QuoteBet(context.Context, *QuoteBetRequest) (*QuoteBetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QuoteBet not implemented")
+}
+func (UnimplementedBettingServer) mustEmbedUnimplementedBettingServer() {}
+
+// UnsafeBettingServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BettingServer will
+// result in compilation errors.
+type UnsafeBettingServer interface {
+	mustEmbedUnimplementedBettingServer()
+}
+
+func RegisterBettingServer(s grpc.ServiceRegistrar, srv BettingServer) {
+	s.RegisterService(&Betting_ServiceDesc, srv)
+}
+
+func _Betting_PlaceBet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlaceBetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BettingServer).PlaceBet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/betting.Betting/PlaceBet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BettingServer).PlaceBet(ctx, req.(*PlaceBetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Betting_GetBet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BettingServer).GetBet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/betting.Betting/GetBet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BettingServer).GetBet(ctx, req.(*GetBetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Betting_ListSettlements_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSettlementsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BettingServer).ListSettlements(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/betting.Betting/ListSettlements",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BettingServer).ListSettlements(ctx, req.(*ListSettlementsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Betting_QuoteBet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuoteBetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BettingServer).QuoteBet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/betting.Betting/QuoteBet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BettingServer).QuoteBet(ctx, req.(*QuoteBetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Betting_ServiceDesc is the grpc.ServiceDesc for Betting service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Betting_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "betting.Betting",
+	HandlerType: (*BettingServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PlaceBet",
+			Handler:    _Betting_PlaceBet_Handler,
+		},
+		{
+			MethodName: "GetBet",
+			Handler:    _Betting_GetBet_Handler,
+		},
+		{
+			MethodName: "ListSettlements",
+			Handler:    _Betting_ListSettlements_Handler,
+		},
+		{
+			MethodName: "QuoteBet",
+			Handler:    _Betting_QuoteBet_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "betting/betting.proto",
+}
@@ -8,6 +8,7 @@ package racing
 
 import (
 	context "context"
+	empty "github.com/golang/protobuf/ptypes/empty"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -24,6 +25,47 @@ const _ = grpc.SupportPackageIsVersion7
 type RacingClient interface {
 	// ListRaces returns a list of all races.
 	ListRaces(ctx context.Context, in *ListRacesRequest, opts ...grpc.CallOption) (*ListRacesResponse, error)
+	// ListNextToJump returns the next visible races open for betting across all meetings,
+	// soonest to jump first.
+	ListNextToJump(ctx context.Context, in *ListNextToJumpRequest, opts ...grpc.CallOption) (*ListNextToJumpResponse, error)
+	// GetRace returns a single race by its resource name.
+	GetRace(ctx context.Context, in *GetRaceRequest, opts ...grpc.CallOption) (*Race, error)
+	// CreateRace creates a new race.
+	CreateRace(ctx context.Context, in *CreateRaceRequest, opts ...grpc.CallOption) (*Race, error)
+	// UpdateRace updates the fields of a race selected by the update mask.
+	UpdateRace(ctx context.Context, in *UpdateRaceRequest, opts ...grpc.CallOption) (*Race, error)
+	// DeleteRace deletes a race.
+	DeleteRace(ctx context.Context, in *DeleteRaceRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	// TransitionRaceStatus moves a race into a new status, if the lifecycle permits it.
+	TransitionRaceStatus(ctx context.Context, in *TransitionRaceStatusRequest, opts ...grpc.CallOption) (*Race, error)
+	// ListRaceStatusTransitions returns the status history of a race, oldest first.
+	ListRaceStatusTransitions(ctx context.Context, in *ListRaceStatusTransitionsRequest, opts ...grpc.CallOption) (*ListRaceStatusTransitionsResponse, error)
+	// WatchRaces streams a snapshot of the races matching a filter, followed by changes to them.
+	WatchRaces(ctx context.Context, in *WatchRacesRequest, opts ...grpc.CallOption) (Racing_WatchRacesClient, error)
+	// ListMeetings returns a list of meetings.
+	ListMeetings(ctx context.Context, in *ListMeetingsRequest, opts ...grpc.CallOption) (*ListMeetingsResponse, error)
+	// GetMeeting returns a single meeting by its resource name.
+	GetMeeting(ctx context.Context, in *GetMeetingRequest, opts ...grpc.CallOption) (*Meeting, error)
+	// ListRunners returns the runners of a race, ordered by saddle number.
+	ListRunners(ctx context.Context, in *ListRunnersRequest, opts ...grpc.CallOption) (*ListRunnersResponse, error)
+	// ScratchRunner withdraws a runner from its race, recording when it was scratched.
+	ScratchRunner(ctx context.Context, in *ScratchRunnerRequest, opts ...grpc.CallOption) (*Runner, error)
+	// GetRaceResult returns the result of a race.
+	GetRaceResult(ctx context.Context, in *GetRaceResultRequest, opts ...grpc.CallOption) (*Result, error)
+	// PublishResult publishes, or corrects, the result of a race, moving the race to INTERIM or FINAL.
+	// A FINAL race must be returned to INTERIM, e.g. following a protest, before its result is corrected.
+	PublishResult(ctx context.Context, in *PublishResultRequest, opts ...grpc.CallOption) (*Result, error)
+	// ListPrices returns the current fixed-odds prices of the runners in a race, or their full history.
+	ListPrices(ctx context.Context, in *ListPricesRequest, opts ...grpc.CallOption) (*ListPricesResponse, error)
+	// StreamPrices streams the current fixed-odds prices of the runners in a race, followed by each update to them.
+	StreamPrices(ctx context.Context, in *StreamPricesRequest, opts ...grpc.CallOption) (Racing_StreamPricesClient, error)
+	// UpdatePrices sets new fixed-odds prices for runners in a race that is open for betting.
+	UpdatePrices(ctx context.Context, in *UpdatePricesRequest, opts ...grpc.CallOption) (*UpdatePricesResponse, error)
+	// GetPoolApproximates returns the tote pools of a race, with their approximate dividends, or
+	// the dividends declared on its result once the race has been run.
+	GetPoolApproximates(ctx context.Context, in *GetPoolApproximatesRequest, opts ...grpc.CallOption) (*PoolApproximates, error)
+	// AddPoolInvestments invests in the tote pools of a race that is open for betting.
+	AddPoolInvestments(ctx context.Context, in *AddPoolInvestmentsRequest, opts ...grpc.CallOption) (*PoolApproximates, error)
 }
 
 type racingClient struct {
@@ -43,12 +85,270 @@ func (c *racingClient) ListRaces(ctx context.Context, in *ListRacesRequest, opts
 	return out, nil
 }
 
+func (c *racingClient) ListNextToJump(ctx context.Context, in *ListNextToJumpRequest, opts ...grpc.CallOption) (*ListNextToJumpResponse, error) {
+	out := new(ListNextToJumpResponse)
+	err := c.cc.Invoke(ctx, "/racing.Racing/ListNextToJump", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) GetRace(ctx context.Context, in *GetRaceRequest, opts ...grpc.CallOption) (*Race, error) {
+	out := new(Race)
+	err := c.cc.Invoke(ctx, "/racing.Racing/GetRace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) CreateRace(ctx context.Context, in *CreateRaceRequest, opts ...grpc.CallOption) (*Race, error) {
+	out := new(Race)
+	err := c.cc.Invoke(ctx, "/racing.Racing/CreateRace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) UpdateRace(ctx context.Context, in *UpdateRaceRequest, opts ...grpc.CallOption) (*Race, error) {
+	out := new(Race)
+	err := c.cc.Invoke(ctx, "/racing.Racing/UpdateRace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) DeleteRace(ctx context.Context, in *DeleteRaceRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/racing.Racing/DeleteRace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) TransitionRaceStatus(ctx context.Context, in *TransitionRaceStatusRequest, opts ...grpc.CallOption) (*Race, error) {
+	out := new(Race)
+	err := c.cc.Invoke(ctx, "/racing.Racing/TransitionRaceStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) ListRaceStatusTransitions(ctx context.Context, in *ListRaceStatusTransitionsRequest, opts ...grpc.CallOption) (*ListRaceStatusTransitionsResponse, error) {
+	out := new(ListRaceStatusTransitionsResponse)
+	err := c.cc.Invoke(ctx, "/racing.Racing/ListRaceStatusTransitions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) WatchRaces(ctx context.Context, in *WatchRacesRequest, opts ...grpc.CallOption) (Racing_WatchRacesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Racing_ServiceDesc.Streams[0], "/racing.Racing/WatchRaces", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &racingWatchRacesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Racing_WatchRacesClient interface {
+	Recv() (*WatchRacesResponse, error)
+	grpc.ClientStream
+}
+
+type racingWatchRacesClient struct {
+	grpc.ClientStream
+}
+
+func (x *racingWatchRacesClient) Recv() (*WatchRacesResponse, error) {
+	m := new(WatchRacesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *racingClient) ListMeetings(ctx context.Context, in *ListMeetingsRequest, opts ...grpc.CallOption) (*ListMeetingsResponse, error) {
+	out := new(ListMeetingsResponse)
+	err := c.cc.Invoke(ctx, "/racing.Racing/ListMeetings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) GetMeeting(ctx context.Context, in *GetMeetingRequest, opts ...grpc.CallOption) (*Meeting, error) {
+	out := new(Meeting)
+	err := c.cc.Invoke(ctx, "/racing.Racing/GetMeeting", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) ListRunners(ctx context.Context, in *ListRunnersRequest, opts ...grpc.CallOption) (*ListRunnersResponse, error) {
+	out := new(ListRunnersResponse)
+	err := c.cc.Invoke(ctx, "/racing.Racing/ListRunners", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) ScratchRunner(ctx context.Context, in *ScratchRunnerRequest, opts ...grpc.CallOption) (*Runner, error) {
+	out := new(Runner)
+	err := c.cc.Invoke(ctx, "/racing.Racing/ScratchRunner", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) GetRaceResult(ctx context.Context, in *GetRaceResultRequest, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, "/racing.Racing/GetRaceResult", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) PublishResult(ctx context.Context, in *PublishResultRequest, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, "/racing.Racing/PublishResult", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) ListPrices(ctx context.Context, in *ListPricesRequest, opts ...grpc.CallOption) (*ListPricesResponse, error) {
+	out := new(ListPricesResponse)
+	err := c.cc.Invoke(ctx, "/racing.Racing/ListPrices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) StreamPrices(ctx context.Context, in *StreamPricesRequest, opts ...grpc.CallOption) (Racing_StreamPricesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Racing_ServiceDesc.Streams[1], "/racing.Racing/StreamPrices", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &racingStreamPricesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Racing_StreamPricesClient interface {
+	Recv() (*Price, error)
+	grpc.ClientStream
+}
+
+type racingStreamPricesClient struct {
+	grpc.ClientStream
+}
+
+func (x *racingStreamPricesClient) Recv() (*Price, error) {
+	m := new(Price)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *racingClient) UpdatePrices(ctx context.Context, in *UpdatePricesRequest, opts ...grpc.CallOption) (*UpdatePricesResponse, error) {
+	out := new(UpdatePricesResponse)
+	err := c.cc.Invoke(ctx, "/racing.Racing/UpdatePrices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) GetPoolApproximates(ctx context.Context, in *GetPoolApproximatesRequest, opts ...grpc.CallOption) (*PoolApproximates, error) {
+	out := new(PoolApproximates)
+	err := c.cc.Invoke(ctx, "/racing.Racing/GetPoolApproximates", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) AddPoolInvestments(ctx context.Context, in *AddPoolInvestmentsRequest, opts ...grpc.CallOption) (*PoolApproximates, error) {
+	out := new(PoolApproximates)
+	err := c.cc.Invoke(ctx, "/racing.Racing/AddPoolInvestments", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RacingServer is the server API for Racing service.
 // All implementations must embed UnimplementedRacingServer
 // for forward compatibility
 type RacingServer interface {
 	// ListRaces returns a list of all races.
 	ListRaces(context.Context, *ListRacesRequest) (*ListRacesResponse, error)
+	// ListNextToJump returns the next visible races open for betting across all meetings,
+	// soonest to jump first.
+	ListNextToJump(context.Context, *ListNextToJumpRequest) (*ListNextToJumpResponse, error)
+	// GetRace returns a single race by its resource name.
+	GetRace(context.Context, *GetRaceRequest) (*Race, error)
+	// CreateRace creates a new race.
+	CreateRace(context.Context, *CreateRaceRequest) (*Race, error)
+	// UpdateRace updates the fields of a race selected by the update mask.
+	UpdateRace(context.Context, *UpdateRaceRequest) (*Race, error)
+	// DeleteRace deletes a race.
+	DeleteRace(context.Context, *DeleteRaceRequest) (*empty.Empty, error)
+	// TransitionRaceStatus moves a race into a new status, if the lifecycle permits it.
+	TransitionRaceStatus(context.Context, *TransitionRaceStatusRequest) (*Race, error)
+	// ListRaceStatusTransitions returns the status history of a race, oldest first.
+	ListRaceStatusTransitions(context.Context, *ListRaceStatusTransitionsRequest) (*ListRaceStatusTransitionsResponse, error)
+	// WatchRaces streams a snapshot of the races matching a filter, followed by changes to them.
+	WatchRaces(*WatchRacesRequest, Racing_WatchRacesServer) error
+	// ListMeetings returns a list of meetings.
+	ListMeetings(context.Context, *ListMeetingsRequest) (*ListMeetingsResponse, error)
+	// GetMeeting returns a single meeting by its resource name.
+	GetMeeting(context.Context, *GetMeetingRequest) (*Meeting, error)
+	// ListRunners returns the runners of a race, ordered by saddle number.
+	ListRunners(context.Context, *ListRunnersRequest) (*ListRunnersResponse, error)
+	// ScratchRunner withdraws a runner from its race, recording when it was scratched.
+	ScratchRunner(context.Context, *ScratchRunnerRequest) (*Runner, error)
+	// GetRaceResult returns the result of a race.
+	GetRaceResult(context.Context, *GetRaceResultRequest) (*Result, error)
+	// PublishResult publishes, or corrects, the result of a race, moving the race to INTERIM or FINAL.
+	// A FINAL race must be returned to INTERIM, e.g. following a protest, before its result is corrected.
+	PublishResult(context.Context, *PublishResultRequest) (*Result, error)
+	// ListPrices returns the current fixed-odds prices of the runners in a race, or their full history.
+	ListPrices(context.Context, *ListPricesRequest) (*ListPricesResponse, error)
+	// StreamPrices streams the current fixed-odds prices of the runners in a race, followed by each update to them.
+	StreamPrices(*StreamPricesRequest, Racing_StreamPricesServer) error
+	// UpdatePrices sets new fixed-odds prices for runners in a race that is open for betting.
+	UpdatePrices(context.Context, *UpdatePricesRequest) (*UpdatePricesResponse, error)
+	// GetPoolApproximates returns the tote pools of a race, with their approximate dividends, or
+	// the dividends declared on its result once the race has been run.
+	GetPoolApproximates(context.Context, *GetPoolApproximatesRequest) (*PoolApproximates, error)
+	// AddPoolInvestments invests in the tote pools of a race that is open for betting.
+	AddPoolInvestments(context.Context, *AddPoolInvestmentsRequest) (*PoolApproximates, error)
 	mustEmbedUnimplementedRacingServer()
 }
 
@@ -59,6 +359,63 @@ type UnimplementedRacingServer struct {
 func (UnimplementedRacingServer) ListRaces(context.Context, *ListRacesRequest) (*ListRacesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListRaces not implemented")
 }
+func (UnimplementedRacingServer) ListNextToJump(context.Context, *ListNextToJumpRequest) (*ListNextToJumpResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNextToJump not implemented")
+}
+func (UnimplementedRacingServer) GetRace(context.Context, *GetRaceRequest) (*Race, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRace not implemented")
+}
+func (UnimplementedRacingServer) CreateRace(context.Context, *CreateRaceRequest) (*Race, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateRace not implemented")
+}
+func (UnimplementedRacingServer) UpdateRace(context.Context, *UpdateRaceRequest) (*Race, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRace not implemented")
+}
+func (UnimplementedRacingServer) DeleteRace(context.Context, *DeleteRaceRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRace not implemented")
+}
+func (UnimplementedRacingServer) TransitionRaceStatus(context.Context, *TransitionRaceStatusRequest) (*Race, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransitionRaceStatus not implemented")
+}
+func (UnimplementedRacingServer) ListRaceStatusTransitions(context.Context, *ListRaceStatusTransitionsRequest) (*ListRaceStatusTransitionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRaceStatusTransitions not implemented")
+}
+func (UnimplementedRacingServer) WatchRaces(*WatchRacesRequest, Racing_WatchRacesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchRaces not implemented")
+}
+func (UnimplementedRacingServer) ListMeetings(context.Context, *ListMeetingsRequest) (*ListMeetingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMeetings not implemented")
+}
+func (UnimplementedRacingServer) GetMeeting(context.Context, *GetMeetingRequest) (*Meeting, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMeeting not implemented")
+}
+func (UnimplementedRacingServer) ListRunners(context.Context, *ListRunnersRequest) (*ListRunnersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRunners not implemented")
+}
+func (UnimplementedRacingServer) ScratchRunner(context.Context, *ScratchRunnerRequest) (*Runner, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScratchRunner not implemented")
+}
+func (UnimplementedRacingServer) GetRaceResult(context.Context, *GetRaceResultRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRaceResult not implemented")
+}
+func (UnimplementedRacingServer) PublishResult(context.Context, *PublishResultRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PublishResult not implemented")
+}
+func (UnimplementedRacingServer) ListPrices(context.Context, *ListPricesRequest) (*ListPricesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPrices not implemented")
+}
+func (UnimplementedRacingServer) StreamPrices(*StreamPricesRequest, Racing_StreamPricesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPrices not implemented")
+}
+func (UnimplementedRacingServer) UpdatePrices(context.Context, *UpdatePricesRequest) (*UpdatePricesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePrices not implemented")
+}
+func (UnimplementedRacingServer) GetPoolApproximates(context.Context, *GetPoolApproximatesRequest) (*PoolApproximates, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPoolApproximates not implemented")
+}
+func (UnimplementedRacingServer) AddPoolInvestments(context.Context, *AddPoolInvestmentsRequest) (*PoolApproximates, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddPoolInvestments not implemented")
+}
 func (UnimplementedRacingServer) mustEmbedUnimplementedRacingServer() {}
 
 // UnsafeRacingServer may be embedded to opt out of forward compatibility for this service.
@@ -90,6 +447,354 @@ func _Racing_ListRaces_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Racing_ListNextToJump_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNextToJumpRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).ListNextToJump(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/ListNextToJump",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).ListNextToJump(ctx, req.(*ListNextToJumpRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_GetRace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).GetRace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/GetRace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).GetRace(ctx, req.(*GetRaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_CreateRace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).CreateRace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/CreateRace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).CreateRace(ctx, req.(*CreateRaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_UpdateRace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).UpdateRace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/UpdateRace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).UpdateRace(ctx, req.(*UpdateRaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_DeleteRace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).DeleteRace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/DeleteRace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).DeleteRace(ctx, req.(*DeleteRaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_TransitionRaceStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransitionRaceStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).TransitionRaceStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/TransitionRaceStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).TransitionRaceStatus(ctx, req.(*TransitionRaceStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_ListRaceStatusTransitions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRaceStatusTransitionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).ListRaceStatusTransitions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/ListRaceStatusTransitions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).ListRaceStatusTransitions(ctx, req.(*ListRaceStatusTransitionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_WatchRaces_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRacesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RacingServer).WatchRaces(m, &racingWatchRacesServer{stream})
+}
+
+type Racing_WatchRacesServer interface {
+	Send(*WatchRacesResponse) error
+	grpc.ServerStream
+}
+
+type racingWatchRacesServer struct {
+	grpc.ServerStream
+}
+
+func (x *racingWatchRacesServer) Send(m *WatchRacesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Racing_ListMeetings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMeetingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).ListMeetings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/ListMeetings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).ListMeetings(ctx, req.(*ListMeetingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_GetMeeting_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMeetingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).GetMeeting(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/GetMeeting",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).GetMeeting(ctx, req.(*GetMeetingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_ListRunners_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRunnersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).ListRunners(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/ListRunners",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).ListRunners(ctx, req.(*ListRunnersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_ScratchRunner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScratchRunnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).ScratchRunner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/ScratchRunner",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).ScratchRunner(ctx, req.(*ScratchRunnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_GetRaceResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRaceResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).GetRaceResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/GetRaceResult",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).GetRaceResult(ctx, req.(*GetRaceResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_PublishResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).PublishResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/PublishResult",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).PublishResult(ctx, req.(*PublishResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_ListPrices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPricesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).ListPrices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/ListPrices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).ListPrices(ctx, req.(*ListPricesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_StreamPrices_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamPricesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RacingServer).StreamPrices(m, &racingStreamPricesServer{stream})
+}
+
+type Racing_StreamPricesServer interface {
+	Send(*Price) error
+	grpc.ServerStream
+}
+
+type racingStreamPricesServer struct {
+	grpc.ServerStream
+}
+
+func (x *racingStreamPricesServer) Send(m *Price) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Racing_UpdatePrices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePricesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).UpdatePrices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/UpdatePrices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).UpdatePrices(ctx, req.(*UpdatePricesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_GetPoolApproximates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPoolApproximatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).GetPoolApproximates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/GetPoolApproximates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).GetPoolApproximates(ctx, req.(*GetPoolApproximatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_AddPoolInvestments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddPoolInvestmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).AddPoolInvestments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/AddPoolInvestments",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).AddPoolInvestments(ctx, req.(*AddPoolInvestmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Racing_ServiceDesc is the grpc.ServiceDesc for Racing service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -101,7 +806,86 @@ var Racing_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListRaces",
 			Handler:    _Racing_ListRaces_Handler,
 		},
+		{
+			MethodName: "ListNextToJump",
+			Handler:    _Racing_ListNextToJump_Handler,
+		},
+		{
+			MethodName: "GetRace",
+			Handler:    _Racing_GetRace_Handler,
+		},
+		{
+			MethodName: "CreateRace",
+			Handler:    _Racing_CreateRace_Handler,
+		},
+		{
+			MethodName: "UpdateRace",
+			Handler:    _Racing_UpdateRace_Handler,
+		},
+		{
+			MethodName: "DeleteRace",
+			Handler:    _Racing_DeleteRace_Handler,
+		},
+		{
+			MethodName: "TransitionRaceStatus",
+			Handler:    _Racing_TransitionRaceStatus_Handler,
+		},
+		{
+			MethodName: "ListRaceStatusTransitions",
+			Handler:    _Racing_ListRaceStatusTransitions_Handler,
+		},
+		{
+			MethodName: "ListMeetings",
+			Handler:    _Racing_ListMeetings_Handler,
+		},
+		{
+			MethodName: "GetMeeting",
+			Handler:    _Racing_GetMeeting_Handler,
+		},
+		{
+			MethodName: "ListRunners",
+			Handler:    _Racing_ListRunners_Handler,
+		},
+		{
+			MethodName: "ScratchRunner",
+			Handler:    _Racing_ScratchRunner_Handler,
+		},
+		{
+			MethodName: "GetRaceResult",
+			Handler:    _Racing_GetRaceResult_Handler,
+		},
+		{
+			MethodName: "PublishResult",
+			Handler:    _Racing_PublishResult_Handler,
+		},
+		{
+			MethodName: "ListPrices",
+			Handler:    _Racing_ListPrices_Handler,
+		},
+		{
+			MethodName: "UpdatePrices",
+			Handler:    _Racing_UpdatePrices_Handler,
+		},
+		{
+			MethodName: "GetPoolApproximates",
+			Handler:    _Racing_GetPoolApproximates_Handler,
+		},
+		{
+			MethodName: "AddPoolInvestments",
+			Handler:    _Racing_AddPoolInvestments_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchRaces",
+			Handler:       _Racing_WatchRaces_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamPrices",
+			Handler:       _Racing_StreamPrices_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "racing/racing.proto",
 }
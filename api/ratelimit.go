@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Policies limiting the requests of partners, labelling the requests they reject.
+const (
+	keyPolicy   = "key"
+	routePolicy = "route"
+	quotaPolicy = "daily_quota"
+)
+
+var rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_rate_limited_total",
+	Help: "Total number of partner requests rejected by the gateway for exceeding a rate limit or quota, by partner and policy.",
+}, []string{"partner", "policy"})
+
+// limit is a token bucket rate limit, allowing Rate requests a second on average in bursts of up
+// to Burst requests. A zero rate is unlimited.
+type limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int64   `json:"burst"`
+}
+
+func (l limit) validate() error {
+	switch {
+	case l.Rate < 0 || math.IsInf(l.Rate, 0) || math.IsNaN(l.Rate):
+		return errors.New("rate must be a non-negative number of requests a second")
+	case l.Rate > 0 && l.Burst < 1:
+		return errors.New("burst must be at least 1")
+	}
+
+	return nil
+}
+
+// bucket is the token bucket of a limit, holding a token for each request it allows.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// refill adds the tokens accrued since the bucket was last refilled, up to its burst.
+func (b *bucket) refill(l limit, now time.Time) {
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.updated).Seconds()*l.Rate)
+	b.updated = now
+}
+
+// dailyUsage counts the requests made by a partner in a UTC day.
+type dailyUsage struct {
+	day   time.Time
+	count int64
+}
+
+// allowance is the state of a policy limiting a request, once it has been applied.
+type allowance struct {
+	policy    string
+	limit     int64
+	window    time.Duration
+	remaining int64
+	// reset is the time until the policy allows limit requests again.
+	reset time.Duration
+	// exhausted is set when the policy allows no more requests until retry has passed.
+	exhausted bool
+	retry     time.Duration
+}
+
+// rateLimiter limits the requests of partners by the token buckets of their key and each route,
+// and their daily quota. Buckets and usage are held in memory, so are per gateway instance and
+// reset when it restarts.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	usage   map[string]*dailyUsage
+	now     func() time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[string]*bucket),
+		usage:   make(map[string]*dailyUsage),
+		now:     time.Now,
+	}
+}
+
+// UnaryClientInterceptor rejects unary calls made for partners exceeding their limits with
+// ResourceExhausted, rendered by the gateway as 429 Too Many Requests.
+func (l *rateLimiter) UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if err := l.limit(ctx, method); err != nil {
+		return err
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// StreamClientInterceptor rejects streaming calls made for partners exceeding their limits,
+// counting each stream as a single request.
+func (l *rateLimiter) StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if err := l.limit(ctx, method); err != nil {
+		return nil, err
+	}
+
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// limit applies the limits of the partner a call is made for, if any, to the call, reporting
+// them in the headers of the partner's response.
+func (l *rateLimiter) limit(ctx context.Context, method string) error {
+	pr, ok := partnerFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	allowances, exceeded := l.take(pr.partner, method)
+	setRateLimitHeaders(pr.header, allowances)
+
+	if exceeded == nil {
+		return nil
+	}
+
+	rateLimited.WithLabelValues(pr.partner.ID, exceeded.policy).Inc()
+
+	retry := ceilSeconds(exceeded.retry)
+	pr.header.Set("Retry-After", strconv.FormatInt(retry, 10))
+
+	var message string
+	switch exceeded.policy {
+	case quotaPolicy:
+		message = fmt.Sprintf("daily quota of %d requests exceeded", exceeded.limit)
+	case routePolicy:
+		message = fmt.Sprintf("rate limit of %s exceeded", method)
+	default:
+		message = "rate limit exceeded"
+	}
+
+	st, err := status.New(codes.ResourceExhausted, message).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: ptypes.DurationProto(time.Duration(retry) * time.Second),
+	})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, message)
+	}
+
+	return st.Err()
+}
+
+// take applies the key and route rate limits and daily quota of a partner to a request to a
+// route. The request is only counted against them should all allow it; otherwise the policy
+// allowing it the latest is returned as exceeded.
+func (l *rateLimiter) take(p *partner, route string) ([]allowance, *allowance) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	type limitedBucket struct {
+		policy string
+		limit  limit
+		bucket *bucket
+	}
+
+	var buckets []limitedBucket
+
+	for _, b := range []struct {
+		policy string
+		key    string
+		limit  limit
+	}{
+		{keyPolicy, p.ID, p.RateLimit},
+		{routePolicy, p.ID + " " + route, p.routeRateLimit(route)},
+	} {
+		if b.limit.Rate == 0 {
+			continue
+		}
+
+		bkt, ok := l.buckets[b.key]
+		if !ok {
+			bkt = &bucket{tokens: float64(b.limit.Burst), updated: now}
+			l.buckets[b.key] = bkt
+		}
+
+		bkt.refill(b.limit, now)
+		buckets = append(buckets, limitedBucket{b.policy, b.limit, bkt})
+	}
+
+	var usage *dailyUsage
+
+	if p.DailyQuota > 0 {
+		day := now.UTC().Truncate(24 * time.Hour)
+
+		usage = l.usage[p.ID]
+		if usage == nil || !usage.day.Equal(day) {
+			usage = &dailyUsage{day: day}
+			l.usage[p.ID] = usage
+		}
+	}
+
+	allowed := usage == nil || usage.count < p.DailyQuota
+	for _, b := range buckets {
+		allowed = allowed && b.bucket.tokens >= 1
+	}
+
+	if allowed {
+		for _, b := range buckets {
+			b.bucket.tokens--
+		}
+
+		if usage != nil {
+			usage.count++
+		}
+	}
+
+	allowances := make([]allowance, 0, len(buckets)+1)
+
+	for _, b := range buckets {
+		a := allowance{
+			policy:    b.policy,
+			limit:     b.limit.Burst,
+			window:    time.Duration(float64(b.limit.Burst) / b.limit.Rate * float64(time.Second)),
+			remaining: int64(b.bucket.tokens),
+			reset:     time.Duration((float64(b.limit.Burst) - b.bucket.tokens) / b.limit.Rate * float64(time.Second)),
+		}
+
+		if b.bucket.tokens < 1 {
+			a.exhausted = true
+			a.retry = time.Duration(math.Ceil((1 - b.bucket.tokens) / b.limit.Rate * float64(time.Second)))
+		}
+
+		allowances = append(allowances, a)
+	}
+
+	if usage != nil {
+		a := allowance{
+			policy:    quotaPolicy,
+			limit:     p.DailyQuota,
+			window:    24 * time.Hour,
+			remaining: p.DailyQuota - usage.count,
+			reset:     usage.day.Add(24 * time.Hour).Sub(now),
+		}
+
+		if a.remaining == 0 {
+			a.exhausted = true
+			a.retry = a.reset
+		}
+
+		allowances = append(allowances, a)
+	}
+
+	if allowed {
+		return allowances, nil
+	}
+
+	var exceeded *allowance
+
+	for i := range allowances {
+		if a := &allowances[i]; a.exhausted && (exceeded == nil || a.retry > exceeded.retry) {
+			exceeded = a
+		}
+	}
+
+	return allowances, exceeded
+}
+
+// setRateLimitHeaders reports the policies limiting a request in the RateLimit headers of its
+// response: RateLimit-Policy lists every policy, and RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset describe the policy closest to being exceeded.
+func setRateLimitHeaders(header http.Header, allowances []allowance) {
+	if len(allowances) == 0 {
+		return
+	}
+
+	closest := allowances[0]
+	policies := make([]string, len(allowances))
+
+	for i, a := range allowances {
+		policies[i] = fmt.Sprintf("%d;w=%d", a.limit, ceilSeconds(a.window))
+
+		if a.remaining < closest.remaining {
+			closest = a
+		}
+	}
+
+	header.Set("RateLimit-Policy", strings.Join(policies, ", "))
+	header.Set("RateLimit-Limit", strconv.FormatInt(closest.limit, 10))
+	header.Set("RateLimit-Remaining", strconv.FormatInt(closest.remaining, 10))
+	header.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(closest.reset), 10))
+}
+
+// ceilSeconds rounds a duration up to whole seconds.
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
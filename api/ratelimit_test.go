@@ -0,0 +1,263 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	listRaces = "/racing.Racing/ListRaces"
+	getRace   = "/racing.Racing/GetRace"
+)
+
+// request is a request made by a partner after the one before it, and the policy it should be
+// rejected by, if any.
+type request struct {
+	after  time.Duration
+	route  string
+	policy string
+	retry  time.Duration
+}
+
+func TestRateLimiterTake(t *testing.T) {
+	tests := []struct {
+		name     string
+		partner  partner
+		requests []request
+	}{
+		{
+			name:    "burst of the key",
+			partner: partner{RateLimit: limit{Rate: 1, Burst: 2}},
+			requests: []request{
+				{},
+				{},
+				{policy: keyPolicy, retry: time.Second},
+			},
+		},
+		{
+			name:    "tokens are refilled at the rate",
+			partner: partner{RateLimit: limit{Rate: 2, Burst: 1}},
+			requests: []request{
+				{},
+				{after: 250 * time.Millisecond, policy: keyPolicy, retry: 250 * time.Millisecond},
+				{after: 250 * time.Millisecond},
+			},
+		},
+		{
+			name:    "tokens are refilled up to the burst",
+			partner: partner{RateLimit: limit{Rate: 1, Burst: 1}},
+			requests: []request{
+				{},
+				{after: time.Hour},
+				{policy: keyPolicy, retry: time.Second},
+			},
+		},
+		{
+			name:    "retry is rounded up",
+			partner: partner{RateLimit: limit{Rate: 3, Burst: 1}},
+			requests: []request{
+				{},
+				{policy: keyPolicy, retry: 333333334},
+			},
+		},
+		{
+			name:    "retry is rounded up for tokens just short of one",
+			partner: partner{RateLimit: limit{Rate: 3, Burst: 1}},
+			requests: []request{
+				{},
+				{after: 333333333, policy: keyPolicy, retry: 1},
+				{after: 1},
+			},
+		},
+		{
+			name: "route limit",
+			partner: partner{
+				RateLimit:       limit{Rate: 10, Burst: 10},
+				RouteRateLimits: map[string]limit{defaultRoute: {Rate: 1, Burst: 1}},
+			},
+			requests: []request{
+				{},
+				{policy: routePolicy, retry: time.Second},
+				{route: getRace},
+			},
+		},
+		{
+			name:    "routes without a limit",
+			partner: partner{RouteRateLimits: map[string]limit{listRaces: {Rate: 1, Burst: 1}}},
+			requests: []request{
+				{},
+				{policy: routePolicy, retry: time.Second},
+				{route: getRace},
+				{route: getRace},
+			},
+		},
+		{
+			name:    "daily quota resets at midnight UTC",
+			partner: partner{DailyQuota: 2},
+			requests: []request{
+				{},
+				{},
+				{after: 30 * time.Minute, policy: quotaPolicy, retry: 30 * time.Minute},
+				{after: 30 * time.Minute},
+			},
+		},
+		{
+			name:    "rejected requests are not counted",
+			partner: partner{RateLimit: limit{Rate: 1, Burst: 1}, DailyQuota: 2},
+			requests: []request{
+				{},
+				{policy: keyPolicy, retry: time.Second},
+				{after: time.Second},
+				{after: time.Second, policy: quotaPolicy, retry: time.Hour - 2*time.Second},
+			},
+		},
+		{
+			name:    "policy allowing a request the latest is exceeded",
+			partner: partner{RateLimit: limit{Rate: 1, Burst: 1}, DailyQuota: 1},
+			requests: []request{
+				{},
+				{policy: quotaPolicy, retry: time.Hour},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)
+
+			l := newRateLimiter()
+			l.now = func() time.Time { return now }
+
+			p := tt.partner
+			p.ID = "acme"
+
+			for i, r := range tt.requests {
+				now = now.Add(r.after)
+
+				route := r.route
+				if route == "" {
+					route = listRaces
+				}
+
+				_, exceeded := l.take(&p, route)
+
+				switch {
+				case exceeded == nil && r.policy != "":
+					t.Errorf("request %d allowed, want %s exceeded", i, r.policy)
+				case exceeded != nil && r.policy == "":
+					t.Errorf("request %d exceeded %s, want allowed", i, exceeded.policy)
+				case exceeded != nil && (exceeded.policy != r.policy || exceeded.retry != r.retry):
+					t.Errorf("request %d exceeded %s retrying after %v, want %s retrying after %v", i, exceeded.policy, exceeded.retry, r.policy, r.retry)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterAllowances(t *testing.T) {
+	l := newRateLimiter()
+	now := time.Date(2026, 10, 18, 18, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	p := &partner{ID: "acme", RateLimit: limit{Rate: 2, Burst: 10}, DailyQuota: 100}
+
+	for i := 0; i < 4; i++ {
+		l.take(p, listRaces)
+	}
+
+	allowances, exceeded := l.take(p, listRaces)
+	if exceeded != nil {
+		t.Fatalf("take() exceeded %s, want allowed", exceeded.policy)
+	}
+
+	want := []allowance{
+		{policy: keyPolicy, limit: 10, window: 5 * time.Second, remaining: 5, reset: 2500 * time.Millisecond},
+		{policy: quotaPolicy, limit: 100, window: 24 * time.Hour, remaining: 95, reset: 6 * time.Hour},
+	}
+
+	if !reflect.DeepEqual(allowances, want) {
+		t.Errorf("take() allowances = %+v, want %+v", allowances, want)
+	}
+}
+
+func TestBucketRefill(t *testing.T) {
+	start := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		limit   limit
+		want    float64
+	}{
+		{name: "nothing elapsed", tokens: 0.5, limit: limit{Rate: 1, Burst: 5}, want: 0.5},
+		{name: "partial token", tokens: 0, elapsed: 250 * time.Millisecond, limit: limit{Rate: 2, Burst: 5}, want: 0.5},
+		{name: "whole tokens", tokens: 1, elapsed: 2 * time.Second, limit: limit{Rate: 1.5, Burst: 5}, want: 4},
+		{name: "capped at the burst", tokens: 4, elapsed: time.Minute, limit: limit{Rate: 1, Burst: 5}, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &bucket{tokens: tt.tokens, updated: start}
+			b.refill(tt.limit, start.Add(tt.elapsed))
+
+			if b.tokens != tt.want || !b.updated.Equal(start.Add(tt.elapsed)) {
+				t.Errorf("refill() = %v tokens at %v, want %v tokens at %v", b.tokens, b.updated, tt.want, start.Add(tt.elapsed))
+			}
+		})
+	}
+}
+
+func TestLimitValidate(t *testing.T) {
+	tests := []struct {
+		limit limit
+		valid bool
+	}{
+		{limit: limit{}, valid: true},
+		{limit: limit{Rate: 0.5, Burst: 1}, valid: true},
+		{limit: limit{Rate: 1}, valid: false},
+		{limit: limit{Rate: -1, Burst: 1}, valid: false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.limit.validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v validate() error = %v, want valid %v", tt.limit, err, tt.valid)
+		}
+	}
+}
+
+func TestSetRateLimitHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowances []allowance
+		want       http.Header
+	}{
+		{name: "no policies", want: http.Header{}},
+		{
+			name: "closest policy is described",
+			allowances: []allowance{
+				{policy: keyPolicy, limit: 10, window: 5 * time.Second, remaining: 7, reset: 1500 * time.Millisecond},
+				{policy: quotaPolicy, limit: 100, window: 24 * time.Hour, remaining: 3, reset: 90*time.Minute + time.Millisecond},
+			},
+			want: http.Header{
+				"Ratelimit-Policy":    {"10;w=5, 100;w=86400"},
+				"Ratelimit-Limit":     {"100"},
+				"Ratelimit-Remaining": {"3"},
+				"Ratelimit-Reset":     {"5401"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			setRateLimitHeaders(header, tt.allowances)
+
+			if !reflect.DeepEqual(header, tt.want) {
+				t.Errorf("setRateLimitHeaders() = %v, want %v", header, tt.want)
+			}
+		})
+	}
+}
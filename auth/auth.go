@@ -0,0 +1,64 @@
+// Package auth identifies the callers of the gateway and the services behind it.
+//
+// The gateway authenticates each request by its JWT bearer token, verified against the public
+// keys of a local JWKS file, and forwards the identity the token holds, its subject and roles, to
+// services in the x-auth-subject and x-auth-roles metadata of the calls made for the request.
+// Services trust that metadata, so must only be reachable by the gateway and each other, e.g.
+// over mutual TLS; the gateway replaces any identity metadata sent by its callers.
+package auth
+
+import (
+	"context"
+)
+
+const (
+	// RoleTrader is the role of traders, who manage the races offered.
+	RoleTrader = "trader"
+	// RoleAdmin is the role of administrators, who may do anything a trader may.
+	RoleAdmin = "admin"
+	// RoleService is the role of services calling each other on their own behalf, rather than
+	// for a person.
+	RoleService = "service"
+)
+
+// Identity is the authenticated identity of a caller.
+type Identity struct {
+	// Subject identifies the caller, e.g. the sub claim of its token.
+	Subject string
+	// Roles are the roles granted to the caller.
+	Roles []string
+}
+
+// HasRole reports whether the identity has been granted any of the roles. A nil identity, an
+// unauthenticated caller, has none.
+func (i *Identity) HasRole(roles ...string) bool {
+	if i == nil {
+		return false
+	}
+
+	for _, granted := range i.Roles {
+		for _, role := range roles {
+			if granted == role {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// identityKey keys the identity of a caller in a context.
+type identityKey struct{}
+
+// NewContext returns a context for a call made by the identity.
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity of the caller of a context, or nil and false if the caller is
+// unauthenticated.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+
+	return id, ok && id != nil
+}
@@ -0,0 +1,8 @@
+module git.neds.sh/matty/entain/auth
+
+go 1.16
+
+require (
+	google.golang.org/grpc v1.36.0
+	gopkg.in/square/go-jose.v2 v2.5.1
+)
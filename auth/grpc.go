@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// subjectMetadata holds the subject of the caller a call is made for.
+	subjectMetadata = "x-auth-subject"
+	// rolesMetadata holds the roles of the caller a call is made for, one value per role.
+	rolesMetadata = "x-auth-roles"
+)
+
+// UnaryServerInterceptor identifies the caller of each unary call by its identity metadata.
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(withIncomingIdentity(ctx), req)
+}
+
+// StreamServerInterceptor identifies the caller of each streaming call by its identity metadata.
+func StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &identifiedStream{ServerStream: ss, ctx: withIncomingIdentity(ss.Context())})
+}
+
+// UnaryClientInterceptor forwards the identity of the caller of each unary call made in its
+// metadata, replacing any identity metadata already present.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(withOutgoingIdentity(ctx), method, req, reply, cc, opts...)
+}
+
+// StreamClientInterceptor forwards the identity of the caller of each streaming call made in its
+// metadata, replacing any identity metadata already present.
+func StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(withOutgoingIdentity(ctx), desc, cc, method, opts...)
+}
+
+// ServiceDialOptions identify the calls made over a connection as made by the named service, on
+// its own behalf.
+func ServiceDialOptions(service string) []grpc.DialOption {
+	id := &Identity{Subject: service, Roles: []string{RoleService}}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return UnaryClientInterceptor(NewContext(ctx, id), method, req, reply, cc, invoker, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return StreamClientInterceptor(NewContext(ctx, id), desc, cc, method, streamer, opts...)
+		}),
+	}
+}
+
+func withIncomingIdentity(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	subjects := md.Get(subjectMetadata)
+	if len(subjects) == 0 || subjects[0] == "" {
+		return ctx
+	}
+
+	return NewContext(ctx, &Identity{Subject: subjects[0], Roles: md.Get(rolesMetadata)})
+}
+
+func withOutgoingIdentity(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+
+	// Identity metadata may only be set from an authenticated identity, never passed through
+	// from the caller of the gateway, e.g. in a Grpc-Metadata-X-Auth-Roles header.
+	delete(md, subjectMetadata)
+	delete(md, rolesMetadata)
+
+	if id, ok := FromContext(ctx); ok {
+		md.Set(subjectMetadata, id.Subject)
+		md.Set(rolesMetadata, id.Roles...)
+	}
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// identifiedStream is a stream whose context holds the identity of its caller.
+type identifiedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identifiedStream) Context() context.Context {
+	return s.ctx
+}
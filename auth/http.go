@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Handler authenticates each request handled by next by its bearer token, responding 401
+// Unauthorized to requests without a valid token. The body of the response is an error in the
+// form returned by the gateway for calls failing with Unauthenticated.
+func Handler(v *Verifier, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credentials := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+		if len(credentials) != 2 || !strings.EqualFold(credentials[0], "Bearer") || strings.TrimSpace(credentials[1]) == "" {
+			unauthorized(w, `Bearer`, "missing bearer token")
+			return
+		}
+
+		id, err := v.Verify(strings.TrimSpace(credentials[1]))
+		if err != nil {
+			unauthorized(w, `Bearer error="invalid_token"`, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// errorBody is the body of an error response, as rendered by the gateway.
+type errorBody struct {
+	Code    codes.Code    `json:"code"`
+	Message string        `json:"message"`
+	Details []interface{} `json:"details"`
+}
+
+func unauthorized(w http.ResponseWriter, challenge, message string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+
+	_ = json.NewEncoder(w).Encode(errorBody{Code: codes.Unauthenticated, Message: message, Details: []interface{}{}})
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// Config configures the authentication of bearer tokens.
+type Config struct {
+	JWKSFile string        `config:"jwks_file" usage:"JWKS file of the public keys bearer tokens are signed with; required unless insecure is set"`
+	Issuer   string        `config:"issuer" usage:"Issuer bearer tokens must be issued by, in their iss claim; any issuer when empty"`
+	Audience string        `config:"audience" usage:"Audience bearer tokens must be issued for, in their aud claim; any audience when empty"`
+	Leeway   time.Duration `config:"leeway" usage:"Clock skew allowed when checking the exp, nbf and iat claims of bearer tokens"`
+	Insecure bool          `config:"insecure" usage:"Forward requests without an identity when jwks_file is empty; for local development only"`
+}
+
+// DefaultConfig configures no JWKS file, which must be set unless Insecure is, allowing a minute of
+// clock skew.
+var DefaultConfig = Config{Leeway: time.Minute}
+
+// Enabled reports whether bearer tokens are authenticated.
+func (c Config) Enabled() bool {
+	return c.JWKSFile != ""
+}
+
+// Validate checks the authentication configured under key.
+func (c Config) Validate(key string) error {
+	if !c.Enabled() && (c.Issuer != "" || c.Audience != "") {
+		return fmt.Errorf("%s.jwks_file must be set to check the issuer or audience of tokens", key)
+	}
+
+	if c.Enabled() && c.Insecure {
+		return fmt.Errorf("%s.insecure must not be set with %s.jwks_file", key, key)
+	}
+
+	if c.Leeway < 0 {
+		return fmt.Errorf("%s.leeway must not be negative", key)
+	}
+
+	return nil
+}
+
+// signingAlgorithms are the algorithms tokens may be signed with: only asymmetric algorithms,
+// whose public keys may be shared in a JWKS file.
+var signingAlgorithms = map[string]bool{
+	string(jose.RS256): true, string(jose.RS384): true, string(jose.RS512): true,
+	string(jose.PS256): true, string(jose.PS384): true, string(jose.PS512): true,
+	string(jose.ES256): true, string(jose.ES384): true, string(jose.ES512): true,
+	string(jose.EdDSA): true,
+}
+
+// Verifier authenticates bearer tokens.
+type Verifier struct {
+	keys     jose.JSONWebKeySet
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+// NewVerifier reads the keys tokens are verified with from the configured JWKS file.
+func NewVerifier(cfg Config) (*Verifier, error) {
+	data, err := ioutil.ReadFile(cfg.JWKSFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("reading JWKS %s: %w", cfg.JWKSFile, err)
+	}
+
+	if len(keys.Keys) == 0 {
+		return nil, fmt.Errorf("JWKS %s holds no keys", cfg.JWKSFile)
+	}
+
+	for _, key := range keys.Keys {
+		if !key.IsPublic() {
+			return nil, fmt.Errorf("JWKS %s holds key %q, which is not a public key", cfg.JWKSFile, key.KeyID)
+		}
+	}
+
+	return &Verifier{keys: keys, issuer: cfg.Issuer, audience: cfg.Audience, leeway: cfg.Leeway}, nil
+}
+
+// claims are the claims of a bearer token.
+type claims struct {
+	jwt.Claims
+	Roles roles `json:"roles"`
+}
+
+// roles are the roles claimed by a token, as an array or a space-separated string.
+type roles []string
+
+func (r *roles) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*r = strings.Fields(s)
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("roles must be an array or a space-separated string")
+	}
+
+	*r = list
+
+	return nil
+}
+
+// Verify authenticates a bearer token, returning the identity it holds. The token must be signed
+// by a key of the JWKS, be current, and name its subject.
+func (v *Verifier) Verify(token string) (*Identity, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, errors.New("token is malformed")
+	}
+
+	if len(parsed.Headers) != 1 {
+		return nil, errors.New("token must have a single signature")
+	}
+
+	header := parsed.Headers[0]
+	if !signingAlgorithms[header.Algorithm] {
+		return nil, fmt.Errorf("token is signed with unsupported algorithm %q", header.Algorithm)
+	}
+
+	key, err := v.key(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var c claims
+	if err := parsed.Claims(key.Key, &c); err != nil {
+		return nil, errors.New("token signature is invalid")
+	}
+
+	expected := jwt.Expected{Issuer: v.issuer, Time: time.Now()}
+	if v.audience != "" {
+		expected.Audience = jwt.Audience{v.audience}
+	}
+
+	if err := c.ValidateWithLeeway(expected, v.leeway); err != nil {
+		return nil, fmt.Errorf("token is invalid: %s", strings.TrimPrefix(err.Error(), "square/go-jose/jwt: validation failed, "))
+	}
+
+	if c.Expiry == nil {
+		return nil, errors.New("token must expire")
+	}
+
+	if c.Subject == "" {
+		return nil, errors.New("token must name its subject")
+	}
+
+	return &Identity{Subject: c.Subject, Roles: c.Roles}, nil
+}
+
+// key returns the key a token was signed with: the key of the JWKS named by its kid header, or
+// the only key should it not name one.
+func (v *Verifier) key(header jose.Header) (jose.JSONWebKey, error) {
+	var candidates []jose.JSONWebKey
+
+	if header.KeyID != "" {
+		candidates = v.keys.Key(header.KeyID)
+	} else if len(v.keys.Keys) == 1 {
+		candidates = v.keys.Keys
+	}
+
+	if len(candidates) == 0 {
+		return jose.JSONWebKey{}, errors.New("token is signed with an unknown key")
+	}
+
+	key := candidates[0]
+	if key.Algorithm != "" && key.Algorithm != header.Algorithm {
+		return jose.JSONWebKey{}, errors.New("token is signed with an algorithm its key does not allow")
+	}
+
+	return key, nil
+}
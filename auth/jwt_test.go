@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func generateKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+// writeJWKS writes a JWKS file holding keys, returning its path.
+func writeJWKS(t *testing.T, keys ...jose.JSONWebKey) string {
+	t.Helper()
+
+	data, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := ioutil.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// sign returns a token of claims, signed by a key named kid.
+func sign(t *testing.T, alg jose.SignatureAlgorithm, key interface{}, kid string, claims ...interface{}) string {
+	t.Helper()
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: jose.JSONWebKey{Key: key, KeyID: kid}}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := jwt.Signed(signer)
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+
+	token, err := builder.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestVerify(t *testing.T) {
+	var (
+		key   = generateKey(t, elliptic.P256())
+		other = generateKey(t, elliptic.P256())
+		now   = time.Now()
+	)
+
+	verifier, err := NewVerifier(Config{
+		JWKSFile: writeJWKS(t, jose.JSONWebKey{Key: &key.PublicKey, KeyID: "current", Algorithm: string(jose.ES256)}),
+		Issuer:   "https://auth.example.com",
+		Audience: "racing",
+		Leeway:   time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// current are the claims of a token the verifier accepts, adjusted by each test.
+	current := func(adjust func(c *jwt.Claims)) jwt.Claims {
+		c := jwt.Claims{
+			Issuer:   "https://auth.example.com",
+			Audience: jwt.Audience{"racing"},
+			Subject:  "alice",
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+		}
+		if adjust != nil {
+			adjust(&c)
+		}
+
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  *Identity
+		// err is part of the error wanted, when the token is refused.
+		err string
+	}{
+		{
+			name:  "valid",
+			token: sign(t, jose.ES256, key, "current", current(nil)),
+			want:  &Identity{Subject: "alice"},
+		},
+		{
+			name:  "roles as an array",
+			token: sign(t, jose.ES256, key, "current", current(nil), map[string]interface{}{"roles": []string{"trader", "admin"}}),
+			want:  &Identity{Subject: "alice", Roles: []string{"trader", "admin"}},
+		},
+		{
+			name:  "roles as a string",
+			token: sign(t, jose.ES256, key, "current", current(nil), map[string]interface{}{"roles": "trader admin"}),
+			want:  &Identity{Subject: "alice", Roles: []string{"trader", "admin"}},
+		},
+		{
+			name:  "without a key ID, verified by the only key",
+			token: sign(t, jose.ES256, key, "", current(nil)),
+			want:  &Identity{Subject: "alice"},
+		},
+		{
+			name:  "expired within the leeway",
+			token: sign(t, jose.ES256, key, "current", current(func(c *jwt.Claims) { c.Expiry = jwt.NewNumericDate(now.Add(-30 * time.Second)) })),
+			want:  &Identity{Subject: "alice"},
+		},
+		{
+			name:  "expired",
+			token: sign(t, jose.ES256, key, "current", current(func(c *jwt.Claims) { c.Expiry = jwt.NewNumericDate(now.Add(-time.Hour)) })),
+			err:   "token is expired",
+		},
+		{
+			name:  "not yet valid",
+			token: sign(t, jose.ES256, key, "current", current(func(c *jwt.Claims) { c.NotBefore = jwt.NewNumericDate(now.Add(time.Hour)) })),
+			err:   "token not valid yet",
+		},
+		{
+			name:  "without expiry",
+			token: sign(t, jose.ES256, key, "current", current(func(c *jwt.Claims) { c.Expiry = nil })),
+			err:   "token must expire",
+		},
+		{
+			name:  "wrong audience",
+			token: sign(t, jose.ES256, key, "current", current(func(c *jwt.Claims) { c.Audience = jwt.Audience{"wallet"} })),
+			err:   "invalid audience",
+		},
+		{
+			name:  "wrong issuer",
+			token: sign(t, jose.ES256, key, "current", current(func(c *jwt.Claims) { c.Issuer = "https://elsewhere.example.com" })),
+			err:   "invalid issuer",
+		},
+		{
+			name:  "without subject",
+			token: sign(t, jose.ES256, key, "current", current(func(c *jwt.Claims) { c.Subject = "" })),
+			err:   "token must name its subject",
+		},
+		{
+			name:  "wrong key",
+			token: sign(t, jose.ES256, other, "current", current(nil)),
+			err:   "token signature is invalid",
+		},
+		{
+			name:  "unknown key",
+			token: sign(t, jose.ES256, other, "other", current(nil)),
+			err:   "token is signed with an unknown key",
+		},
+		{
+			name:  "algorithm the key does not allow",
+			token: sign(t, jose.ES384, generateKey(t, elliptic.P384()), "current", current(nil)),
+			err:   "token is signed with an algorithm its key does not allow",
+		},
+		{
+			name:  "symmetric algorithm",
+			token: sign(t, jose.HS256, []byte("a shared secret of at least 32 bytes"), "current", current(nil)),
+			err:   `unsupported algorithm "HS256"`,
+		},
+		{
+			name:  "malformed",
+			token: "not.a.token",
+			err:   "token is malformed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := verifier.Verify(tt.token)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Verify() error = %v, want %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(id, tt.want) {
+				t.Errorf("Verify() = %+v, want %+v", id, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	key := generateKey(t, elliptic.P256())
+
+	tests := []struct {
+		name string
+		keys []jose.JSONWebKey
+		ok   bool
+	}{
+		{name: "public key", keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "current"}}, ok: true},
+		{name: "private key", keys: []jose.JSONWebKey{{Key: key, KeyID: "current"}}},
+		{name: "no keys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(Config{JWKSFile: writeJWKS(t, tt.keys...)})
+			if (err == nil) != tt.ok {
+				t.Errorf("NewVerifier() error = %v, want ok %t", err, tt.ok)
+			}
+		})
+	}
+}
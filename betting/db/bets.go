@@ -0,0 +1,412 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	_ "github.com/mattn/go-sqlite3"
+
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	"git.neds.sh/matty/entain/migrate"
+)
+
+// BetsRepo provides repository access to bets.
+type BetsRepo interface {
+	// Init will initialise our bets repository.
+	Init() error
+
+	// Get will return a single bet by its ID, or ErrNotFound if it does not exist.
+	Get(ctx context.Context, id int64) (*betting.Bet, error)
+
+	// GetByRequestID will return the bet placed for an account by a request, or ErrNotFound if
+	// there is none. Request IDs are unique to each account.
+	GetByRequestID(ctx context.Context, accountID, requestID string) (*betting.Bet, error)
+
+	// Place will insert a new bet placed by a request, returning it with its assigned ID. If the
+	// request has already placed a bet for the account, that bet is returned instead and created
+	// is false.
+	Place(ctx context.Context, requestID string, bet *betting.Bet) (placed *betting.Bet, created bool, err error)
+
+	// Confirm will move a PENDING bet to PLACED once its funds are reserved, returning it. A bet
+	// that is no longer pending is returned as it is, or ErrNotFound if it has been deleted.
+	Confirm(ctx context.Context, id int64) (*betting.Bet, error)
+
+	// Delete will remove a bet that is PENDING, e.g. one its account could not fund. Deleting a
+	// bet that does not exist, or is no longer pending, does nothing.
+	Delete(ctx context.Context, id int64) error
+
+	// ListPending will return the bets that have been PENDING since before a time, ordered by ID.
+	ListPending(ctx context.Context, before time.Time) ([]*betting.Bet, error)
+
+	// ListByRace will return the bets placed on a race, ordered by ID.
+	ListByRace(ctx context.Context, raceID int64) ([]*betting.Bet, error)
+
+	// ListSettledRaces will return the IDs of the races with settled bets.
+	ListSettledRaces(ctx context.Context) ([]int64, error)
+
+	// Settle will move a bet into a new status with the given payout, recording the change in
+	// its settlement ledger within the same transaction: any previous settlement is reversed
+	// before the new one is recorded. Settling a bet as PLACED only reverses its settlement.
+	Settle(ctx context.Context, id int64, status betting.Bet_Status, payout int64) (*betting.Bet, error)
+
+	// ListSettlements will return the settlement ledger of a bet, oldest first, or ErrNotFound if
+	// the bet does not exist.
+	ListSettlements(ctx context.Context, id int64) ([]*betting.Settlement, error)
+}
+
+type betsRepo struct {
+	db   *sql.DB
+	init sync.Once
+}
+
+// NewBetsRepo creates a new bets repository.
+func NewBetsRepo(db *sql.DB) BetsRepo {
+	return &betsRepo{db: db}
+}
+
+// Init migrates the bets repository schema.
+func (r *betsRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		err = migrate.Apply(r.db, migrations)
+	})
+
+	return err
+}
+
+func (r *betsRepo) Get(ctx context.Context, id int64) (*betting.Bet, error) {
+	bets, err := r.query(ctx, " WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bets) == 0 {
+		return nil, betNotFound(id)
+	}
+
+	return bets[0], nil
+}
+
+func (r *betsRepo) GetByRequestID(ctx context.Context, accountID, requestID string) (*betting.Bet, error) {
+	bets, err := r.query(ctx, " WHERE account_id = ? AND request_id = ?", accountID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bets) == 0 {
+		return nil, betRequestNotFound(accountID, requestID)
+	}
+
+	return bets[0], nil
+}
+
+func (r *betsRepo) Place(ctx context.Context, requestID string, bet *betting.Bet) (*betting.Bet, bool, error) {
+	legs, err := encodeLegs(bet.Legs)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Concurrent retries of a request race to insert; the unique account_id and request_id let
+	// exactly one win.
+	res, err := r.db.ExecContext(
+		ctx,
+		`INSERT OR IGNORE INTO bets (request_id, account_id, race_id, runner_id, type, stake, price, place_price, status, placed_at, legs, boxed, flexi, combinations, total_stake) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+		requestID,
+		bet.AccountId,
+		bet.RaceId,
+		bet.RunnerId,
+		bet.Type.String(),
+		bet.Stake,
+		bet.Price,
+		bet.PlacePrice,
+		bet.Status.String(),
+		time.Now().UTC().Format(time.RFC3339Nano),
+		legs,
+		bet.Boxed,
+		bet.Flexi,
+		bet.Combinations,
+		bet.TotalStake,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	placed, err := r.GetByRequestID(ctx, bet.AccountId, requestID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return placed, inserted == 1, nil
+}
+
+func (r *betsRepo) Confirm(ctx context.Context, id int64) (*betting.Bet, error) {
+	if _, err := r.db.ExecContext(ctx, `UPDATE bets SET status = ? WHERE id = ? AND status = ?`, betting.Bet_PLACED.String(), id, betting.Bet_PENDING.String()); err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, id)
+}
+
+func (r *betsRepo) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM bets WHERE id = ? AND status = ?`, id, betting.Bet_PENDING.String())
+
+	return err
+}
+
+func (r *betsRepo) ListPending(ctx context.Context, before time.Time) ([]*betting.Bet, error) {
+	return r.query(ctx, " WHERE status = ? AND julianday(placed_at) < julianday(?) ORDER BY id", betting.Bet_PENDING.String(), before.UTC().Format(time.RFC3339Nano))
+}
+
+func (r *betsRepo) ListByRace(ctx context.Context, raceID int64) ([]*betting.Bet, error) {
+	return r.query(ctx, " WHERE race_id = ? ORDER BY id", raceID)
+}
+
+func (r *betsRepo) ListSettledRaces(ctx context.Context) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT race_id FROM bets WHERE status NOT IN (?, ?) ORDER BY race_id`, betting.Bet_PLACED.String(), betting.Bet_PENDING.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *betsRepo) Settle(ctx context.Context, id int64, status betting.Bet_Status, payout int64) (*betting.Bet, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bets, err := r.queryTx(ctx, tx, " WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bets) == 0 {
+		return nil, betNotFound(id)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	if current := bets[0]; current.Status != betting.Bet_PLACED {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO settlements (bet_id, type, status, amount, created_at) VALUES (?,?,?,?,?)`,
+			id, betting.Settlement_REVERSAL.String(), betting.Bet_PLACED.String(), -current.Payout, now,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	settledAt := sql.NullString{String: now, Valid: status != betting.Bet_PLACED}
+
+	if settledAt.Valid {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO settlements (bet_id, type, status, amount, created_at) VALUES (?,?,?,?,?)`,
+			id, betting.Settlement_SETTLEMENT.String(), status.String(), payout, now,
+		); err != nil {
+			return nil, err
+		}
+	} else {
+		payout = 0
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE bets SET status = ?, payout = ?, settled_at = ? WHERE id = ?`,
+		status.String(), payout, settledAt, id,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, id)
+}
+
+func (r *betsRepo) ListSettlements(ctx context.Context, id int64) ([]*betting.Settlement, error) {
+	if _, err := r.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, getBetQueries()[settlementsList], id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settlements []*betting.Settlement
+
+	for rows.Next() {
+		var (
+			settlement betting.Settlement
+			entryType  string
+			status     string
+			createdAt  time.Time
+		)
+
+		if err := rows.Scan(&settlement.Id, &settlement.BetId, &entryType, &status, &settlement.Amount, &createdAt); err != nil {
+			return nil, err
+		}
+
+		ts, err := ptypes.TimestampProto(createdAt)
+		if err != nil {
+			return nil, err
+		}
+
+		settlement.Type = betting.Settlement_Type(betting.Settlement_Type_value[entryType])
+		settlement.Status = betting.Bet_Status(betting.Bet_Status_value[status])
+		settlement.CreateTime = ts
+
+		settlements = append(settlements, &settlement)
+	}
+
+	return settlements, rows.Err()
+}
+
+// query selects the bets matching a condition.
+func (r *betsRepo) query(ctx context.Context, condition string, args ...interface{}) ([]*betting.Bet, error) {
+	rows, err := r.db.QueryContext(ctx, getBetQueries()[betsList]+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBets(rows)
+}
+
+// queryTx selects the bets matching a condition within a transaction.
+func (r *betsRepo) queryTx(ctx context.Context, tx *sql.Tx, condition string, args ...interface{}) ([]*betting.Bet, error) {
+	rows, err := tx.QueryContext(ctx, getBetQueries()[betsList]+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBets(rows)
+}
+
+// scanBets reads bets from rows selected by the betsList query.
+func scanBets(rows *sql.Rows) ([]*betting.Bet, error) {
+	defer rows.Close()
+
+	var bets []*betting.Bet
+
+	for rows.Next() {
+		var (
+			bet       betting.Bet
+			betType   string
+			status    string
+			placedAt  time.Time
+			settledAt sql.NullTime
+			legs      string
+		)
+
+		if err := rows.Scan(
+			&bet.Id,
+			&bet.AccountId,
+			&bet.RaceId,
+			&bet.RunnerId,
+			&betType,
+			&bet.Stake,
+			&bet.Price,
+			&status,
+			&placedAt,
+			&bet.PlacePrice,
+			&bet.Payout,
+			&settledAt,
+			&legs,
+			&bet.Boxed,
+			&bet.Flexi,
+			&bet.Combinations,
+			&bet.TotalStake,
+		); err != nil {
+			return nil, err
+		}
+
+		ts, err := ptypes.TimestampProto(placedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if settledAt.Valid {
+			if bet.SettleTime, err = ptypes.TimestampProto(settledAt.Time); err != nil {
+				return nil, err
+			}
+		}
+
+		if bet.Legs, err = decodeLegs(legs); err != nil {
+			return nil, err
+		}
+
+		bet.Type = betting.Bet_Type(betting.Bet_Type_value[betType])
+		bet.Status = betting.Bet_Status(betting.Bet_Status_value[status])
+		bet.PlaceTime = ts
+
+		bets = append(bets, &bet)
+	}
+
+	return bets, rows.Err()
+}
+
+// encodeLegs stores the legs of an exotic bet in a single column, as a JSON array holding the
+// saddle numbers selected by each leg. Bets without legs store an empty string.
+func encodeLegs(legs []*betting.Bet_Leg) (string, error) {
+	if len(legs) == 0 {
+		return "", nil
+	}
+
+	numbers := make([][]int64, len(legs))
+	for i, leg := range legs {
+		numbers[i] = leg.RunnerNumbers
+	}
+
+	encoded, err := json.Marshal(numbers)
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoded), nil
+}
+
+// decodeLegs reads the legs of a bet stored by encodeLegs.
+func decodeLegs(s string) ([]*betting.Bet_Leg, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var numbers [][]int64
+	if err := json.Unmarshal([]byte(s), &numbers); err != nil {
+		return nil, err
+	}
+
+	legs := make([]*betting.Bet_Leg, len(numbers))
+	for i, n := range numbers {
+		legs[i] = &betting.Bet_Leg{RunnerNumbers: n}
+	}
+
+	return legs, nil
+}
@@ -0,0 +1,166 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/betting/db"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+)
+
+// newRepo returns a bets repository over a fresh database.
+func newRepo(t *testing.T) db.BetsRepo {
+	t.Helper()
+
+	bettingDB, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "betting.db")+"?_txlock=immediate&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bettingDB.Close() })
+
+	repo := db.NewBetsRepo(bettingDB)
+	if err := repo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+// winBet returns a win bet of an account on runner 1 of race 1.
+func winBet(accountID string, stake int64) *betting.Bet {
+	return &betting.Bet{
+		AccountId:    accountID,
+		RaceId:       1,
+		RunnerId:     1,
+		Type:         betting.Bet_WIN,
+		Stake:        stake,
+		Price:        2.5,
+		Status:       betting.Bet_PLACED,
+		Combinations: 1,
+		TotalStake:   stake,
+	}
+}
+
+func TestPlace(t *testing.T) {
+	tests := []struct {
+		name      string
+		accountID string
+		requestID string
+		stake     int64
+		created   bool
+		// id is the ID of the bet returned.
+		id int64
+	}{
+		{name: "first request", accountID: "alice", requestID: "1", stake: 100, created: true, id: 1},
+		{name: "retried request", accountID: "alice", requestID: "1", stake: 100, id: 1},
+		{name: "another request", accountID: "alice", requestID: "2", stake: 200, created: true, id: 2},
+		{name: "same request of another account", accountID: "bob", requestID: "1", stake: 300, created: true, id: 3},
+	}
+
+	repo := newRepo(t)
+
+	for _, tt := range tests {
+		placed, created, err := repo.Place(context.Background(), tt.requestID, winBet(tt.accountID, tt.stake))
+		if err != nil {
+			t.Fatalf("%s: Place() error = %v", tt.name, err)
+		}
+
+		if created != tt.created || placed.Id != tt.id || placed.AccountId != tt.accountID || placed.Stake != tt.stake {
+			t.Errorf("%s: Place() = bet %d of %q staking %d, created %v; want bet %d of %q staking %d, created %v", tt.name, placed.Id, placed.AccountId, placed.Stake, created, tt.id, tt.accountID, tt.stake, tt.created)
+		}
+	}
+}
+
+func TestGetByRequestID(t *testing.T) {
+	ctx := context.Background()
+
+	repo := newRepo(t)
+
+	if _, _, err := repo.Place(ctx, "1", winBet("alice", 100)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		accountID string
+		requestID string
+		found     bool
+	}{
+		{name: "placed", accountID: "alice", requestID: "1", found: true},
+		{name: "another account", accountID: "bob", requestID: "1"},
+		{name: "another request", accountID: "alice", requestID: "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bet, err := repo.GetByRequestID(ctx, tt.accountID, tt.requestID)
+
+			switch {
+			case tt.found && (err != nil || bet.AccountId != tt.accountID):
+				t.Errorf("GetByRequestID() = %v, %v; want the bet of %q", bet, err, tt.accountID)
+			case !tt.found && !errors.Is(err, db.ErrNotFound):
+				t.Errorf("GetByRequestID() error = %v, want %v", err, db.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestPendingBets(t *testing.T) {
+	ctx := context.Background()
+
+	repo := newRepo(t)
+
+	pending := winBet("alice", 100)
+	pending.Status = betting.Bet_PENDING
+
+	var ids []int64
+
+	for _, requestID := range []string{"1", "2"} {
+		bet, _, err := repo.Place(ctx, requestID, pending)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, bet.Id)
+	}
+
+	if bets, err := repo.ListPending(ctx, time.Now().Add(-time.Hour)); err != nil || len(bets) != 0 {
+		t.Errorf("ListPending() an hour ago = %d bets, %v; want none", len(bets), err)
+	}
+
+	if bets, err := repo.ListPending(ctx, time.Now().Add(time.Second)); err != nil || len(bets) != 2 {
+		t.Fatalf("ListPending() = %d bets, %v; want 2", len(bets), err)
+	}
+
+	placed, err := repo.Confirm(ctx, ids[0])
+	if err != nil || placed.Status != betting.Bet_PLACED {
+		t.Fatalf("Confirm() = %v, %v; want the bet PLACED", placed, err)
+	}
+
+	// Placed bets are no longer deleted; pending ones are.
+	for _, id := range ids {
+		if err := repo.Delete(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if bet, err := repo.Get(ctx, ids[0]); err != nil || bet.Status != betting.Bet_PLACED {
+		t.Errorf("Get() placed bet = %v, %v; want it kept", bet, err)
+	}
+
+	if _, err := repo.Get(ctx, ids[1]); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Get() pending bet error = %v, want %v", err, db.ErrNotFound)
+	}
+
+	if _, err := repo.Confirm(ctx, ids[1]); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Confirm() deleted bet error = %v, want %v", err, db.ErrNotFound)
+	}
+
+	if bets, err := repo.ListPending(ctx, time.Now().Add(time.Second)); err != nil || len(bets) != 0 {
+		t.Errorf("ListPending() = %d bets, %v; want none", len(bets), err)
+	}
+}
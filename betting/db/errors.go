@@ -0,0 +1,27 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrNotFound is returned, wrapped, when a requested bet does not exist.
+var ErrNotFound = errors.New("not found")
+
+// IsBusy reports whether an error is SQLite failing to lock the database within its busy timeout,
+// as it may while concurrent calls write to it.
+func IsBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
+
+func betNotFound(id int64) error {
+	return fmt.Errorf("bet %d %w", id, ErrNotFound)
+}
+
+func betRequestNotFound(accountID, requestID string) error {
+	return fmt.Errorf("bet placed for account %q by request %q %w", accountID, requestID, ErrNotFound)
+}
@@ -0,0 +1,72 @@
+package db
+
+// migrations bring the betting schema up to date. Each is applied once, in order, and recorded in
+// the schema_migrations table. Released migrations must never be edited; append a new one instead.
+var migrations = []string{
+	// 1: bets, unique by the request that placed them.
+	`CREATE TABLE bets (
+		id INTEGER PRIMARY KEY,
+		request_id TEXT NOT NULL UNIQUE,
+		account_id TEXT NOT NULL,
+		race_id INTEGER NOT NULL,
+		runner_id INTEGER NOT NULL,
+		type TEXT NOT NULL,
+		stake INTEGER NOT NULL,
+		price REAL NOT NULL,
+		status TEXT NOT NULL,
+		placed_at DATETIME NOT NULL
+	);
+	CREATE INDEX bets_race_id ON bets(race_id);`,
+
+	// 2: each-way bets, settlement, and the settlement ledger.
+	`ALTER TABLE bets ADD COLUMN place_price REAL NOT NULL DEFAULT 0;
+	ALTER TABLE bets ADD COLUMN payout INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE bets ADD COLUMN settled_at DATETIME;
+	CREATE TABLE settlements (
+		id INTEGER PRIMARY KEY,
+		bet_id INTEGER NOT NULL REFERENCES bets(id),
+		type TEXT NOT NULL,
+		status TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+	CREATE INDEX settlements_bet_id ON settlements(bet_id);`,
+
+	// 3: exotic bets, with their legs held as a JSON array of saddle numbers per leg.
+	`ALTER TABLE bets ADD COLUMN legs TEXT NOT NULL DEFAULT '';
+	ALTER TABLE bets ADD COLUMN boxed INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE bets ADD COLUMN flexi INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE bets ADD COLUMN combinations INTEGER NOT NULL DEFAULT 1;
+	ALTER TABLE bets ADD COLUMN total_stake INTEGER NOT NULL DEFAULT 0;
+	UPDATE bets SET total_stake = CASE type WHEN 'EACH_WAY' THEN 2 * stake ELSE stake END;`,
+
+	// 4: bets unique by the request that placed them within their account, so that the request
+	// IDs chosen by one account cannot collide with another's. SQLite cannot drop a constraint,
+	// so the table is rebuilt.
+	`CREATE TABLE bets_by_account (
+		id INTEGER PRIMARY KEY,
+		request_id TEXT NOT NULL,
+		account_id TEXT NOT NULL,
+		race_id INTEGER NOT NULL,
+		runner_id INTEGER NOT NULL,
+		type TEXT NOT NULL,
+		stake INTEGER NOT NULL,
+		price REAL NOT NULL,
+		status TEXT NOT NULL,
+		placed_at DATETIME NOT NULL,
+		place_price REAL NOT NULL DEFAULT 0,
+		payout INTEGER NOT NULL DEFAULT 0,
+		settled_at DATETIME,
+		legs TEXT NOT NULL DEFAULT '',
+		boxed INTEGER NOT NULL DEFAULT 0,
+		flexi INTEGER NOT NULL DEFAULT 0,
+		combinations INTEGER NOT NULL DEFAULT 1,
+		total_stake INTEGER NOT NULL DEFAULT 0,
+		UNIQUE (account_id, request_id)
+	);
+	INSERT INTO bets_by_account (id, request_id, account_id, race_id, runner_id, type, stake, price, status, placed_at, place_price, payout, settled_at, legs, boxed, flexi, combinations, total_stake)
+		SELECT id, request_id, account_id, race_id, runner_id, type, stake, price, status, placed_at, place_price, payout, settled_at, legs, boxed, flexi, combinations, total_stake FROM bets;
+	DROP TABLE bets;
+	ALTER TABLE bets_by_account RENAME TO bets;
+	CREATE INDEX bets_race_id ON bets(race_id);`,
+}
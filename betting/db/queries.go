@@ -0,0 +1,44 @@
+package db
+
+const (
+	betsList        = "list"
+	settlementsList = "settlements"
+)
+
+func getBetQueries() map[string]string {
+	return map[string]string{
+		betsList: `
+			SELECT
+				id,
+				account_id,
+				race_id,
+				runner_id,
+				type,
+				stake,
+				price,
+				status,
+				placed_at,
+				place_price,
+				payout,
+				settled_at,
+				legs,
+				boxed,
+				flexi,
+				combinations,
+				total_stake
+			FROM bets
+		`,
+		settlementsList: `
+			SELECT
+				id,
+				bet_id,
+				type,
+				status,
+				amount,
+				created_at
+			FROM settlements
+			WHERE bet_id = ?
+			ORDER BY id
+		`,
+	}
+}
@@ -0,0 +1,356 @@
+// Package exotics defines the exotic bet types: bets on a selection of runners finishing in the
+// first few places, paying tote dividends.
+package exotics
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// Definition describes an exotic bet type.
+type Definition struct {
+	// Positions is the number of finishing positions the bet selects runners for.
+	Positions int
+	// Ordered is whether the runners must finish in the order selected.
+	Ordered bool
+	// Dividend is the type of the dividend the bet is paid.
+	Dividend racing.Dividend_BetType
+}
+
+// Definitions holds the definition of each exotic bet type.
+var Definitions = map[betting.Bet_Type]Definition{
+	betting.Bet_QUINELLA:   {Positions: 2, Ordered: false, Dividend: racing.Dividend_QUINELLA},
+	betting.Bet_EXACTA:     {Positions: 2, Ordered: true, Dividend: racing.Dividend_EXACTA},
+	betting.Bet_TRIFECTA:   {Positions: 3, Ordered: true, Dividend: racing.Dividend_TRIFECTA},
+	betting.Bet_FIRST_FOUR: {Positions: 4, Ordered: true, Dividend: racing.Dividend_FIRST_FOUR},
+}
+
+// IsExotic reports whether a bet type is exotic.
+func IsExotic(t betting.Bet_Type) bool {
+	_, ok := Definitions[t]
+	return ok
+}
+
+// Combinations returns the combinations covered by an exotic bet, each listing the saddle
+// numbers of its runners in finishing order. The runners of unordered combinations are sorted.
+// A bet whose legs do not suit its type covers no combinations.
+func Combinations(bet *betting.Bet) [][]int64 {
+	def, ok := Definitions[bet.Type]
+	if !ok {
+		return nil
+	}
+
+	legs := make([][]int64, def.Positions)
+
+	switch {
+	case bet.Boxed && len(bet.Legs) == 1:
+		for i := range legs {
+			legs[i] = bet.Legs[0].RunnerNumbers
+		}
+	case !bet.Boxed && len(bet.Legs) == def.Positions:
+		for i := range legs {
+			legs[i] = bet.Legs[i].RunnerNumbers
+		}
+	default:
+		return nil
+	}
+
+	var (
+		combinations [][]int64
+		seen         = make(map[string]bool)
+		current      = make([]int64, 0, def.Positions)
+		used         = make(map[int64]bool)
+	)
+
+	var pick func(position int)
+	pick = func(position int) {
+		if position == len(legs) {
+			combination := append([]int64(nil), current...)
+			if !def.Ordered {
+				sort.Slice(combination, func(i, j int) bool { return combination[i] < combination[j] })
+			}
+
+			if key := key(combination); !seen[key] {
+				seen[key] = true
+				combinations = append(combinations, combination)
+			}
+
+			return
+		}
+
+		for _, number := range legs[position] {
+			if used[number] {
+				continue
+			}
+
+			used[number] = true
+			current = append(current, number)
+
+			pick(position + 1)
+
+			current = current[:len(current)-1]
+			used[number] = false
+		}
+	}
+
+	pick(0)
+
+	return combinations
+}
+
+// Count returns the number of combinations covered by an exotic bet, as Combinations would list
+// them, without listing them, so that bets covering too many combinations to list can be
+// rejected.
+//
+// Boxed bets cover the permutations, or for unordered types the subsets, of their runners. Bets
+// with a leg for each position cover the selections of a distinct runner from each leg: the
+// product of the number of runners in each leg, less the selections that repeat a runner, which
+// are excluded by inclusion-exclusion over the ways positions may share a runner.
+func Count(bet *betting.Bet) int64 {
+	def, ok := Definitions[bet.Type]
+	if !ok {
+		return 0
+	}
+
+	switch {
+	case bet.Boxed && len(bet.Legs) == 1:
+		n := int64(len(distinct(bet.Legs[0].RunnerNumbers)))
+		if def.Ordered {
+			return permutations(n, int64(def.Positions))
+		}
+
+		return binomial(n, int64(def.Positions))
+	case !bet.Boxed && len(bet.Legs) == def.Positions:
+		legs := make([]map[int64]bool, len(bet.Legs))
+		for i, leg := range bet.Legs {
+			legs[i] = distinct(leg.RunnerNumbers)
+		}
+
+		ordered := distinctSelections(legs)
+		if def.Ordered {
+			return ordered
+		}
+
+		if def.Positions == 2 {
+			// A pair of runners both in each leg is selected in either order, but covers a single
+			// unordered combination.
+			return ordered - binomial(int64(len(intersect(legs[0], legs[1]))), 2)
+		}
+
+		return int64(len(Combinations(bet)))
+	}
+
+	return 0
+}
+
+// distinctSelections counts the ways of selecting a runner from each leg, no runner selected
+// twice. Summing over each partition of the legs into blocks sharing a runner, the selections
+// of a runner common to each block are counted with the sign and weight of the partition's
+// Möbius function: the product of (-1)^(|B|-1) (|B|-1)! over its blocks B.
+func distinctSelections(legs []map[int64]bool) int64 {
+	var total int64
+
+	var partition func(i int, blocks [][]int)
+	partition = func(i int, blocks [][]int) {
+		if i == len(legs) {
+			term := int64(1)
+
+			for _, block := range blocks {
+				common := legs[block[0]]
+				for _, leg := range block[1:] {
+					common = intersect(common, legs[leg])
+				}
+
+				term *= int64(len(common))
+
+				for k := 1; k < len(block); k++ {
+					term *= -int64(k)
+				}
+			}
+
+			total += term
+
+			return
+		}
+
+		for b := range blocks {
+			blocks[b] = append(blocks[b], i)
+			partition(i+1, blocks)
+			blocks[b] = blocks[b][:len(blocks[b])-1]
+		}
+
+		partition(i+1, append(blocks, []int{i}))
+	}
+
+	partition(0, nil)
+
+	return total
+}
+
+// distinct returns the set of runner numbers selected.
+func distinct(numbers []int64) map[int64]bool {
+	set := make(map[int64]bool, len(numbers))
+	for _, number := range numbers {
+		set[number] = true
+	}
+
+	return set
+}
+
+// intersect returns the runner numbers in both sets.
+func intersect(a, b map[int64]bool) map[int64]bool {
+	common := make(map[int64]bool)
+	for number := range a {
+		if b[number] {
+			common[number] = true
+		}
+	}
+
+	return common
+}
+
+// permutations returns the number of ordered selections of k of n runners.
+func permutations(n, k int64) int64 {
+	if k > n {
+		return 0
+	}
+
+	count := int64(1)
+	for i := int64(0); i < k; i++ {
+		count *= n - i
+	}
+
+	return count
+}
+
+// binomial returns the number of unordered selections of k of n runners.
+func binomial(n, k int64) int64 {
+	if k < 0 || k > n {
+		return 0
+	}
+
+	count := int64(1)
+	for i := int64(1); i <= k; i++ {
+		count = count * (n - k + i) / i
+	}
+
+	return count
+}
+
+// Dividend is a dividend declared on the winning selection of an exotic bet type.
+type Dividend struct {
+	// RunnerNumbers are the saddle numbers of the runners in the winning selection, in finishing
+	// order.
+	RunnerNumbers []int64
+	// Amount is the return per $1 unit, including the stake.
+	Amount float64
+}
+
+// Dividends looks up the dividends declared for an exotic bet type in the result of a race,
+// identifying the runners of each winning selection by their saddle numbers among the runners
+// of the race.
+func Dividends(t betting.Bet_Type, result *racing.Result, runners []*racing.Runner) []Dividend {
+	def, ok := Definitions[t]
+	if !ok {
+		return nil
+	}
+
+	numbers := make(map[int64]int64, len(runners))
+	for _, runner := range runners {
+		numbers[runner.Id] = runner.SaddleNumber
+	}
+
+	var dividends []Dividend
+
+	for _, dividend := range result.Dividends {
+		if dividend.BetType != def.Dividend {
+			continue
+		}
+
+		selection := make([]int64, len(dividend.RunnerIds))
+		for i, id := range dividend.RunnerIds {
+			selection[i] = numbers[id]
+		}
+
+		dividends = append(dividends, Dividend{RunnerNumbers: selection, Amount: dividend.Amount})
+	}
+
+	return dividends
+}
+
+// Covers reports whether a combination covered by a bet matches the winning selection of a
+// dividend, given as saddle numbers in finishing order.
+func Covers(def Definition, combination, winning []int64) bool {
+	if len(combination) != len(winning) {
+		return false
+	}
+
+	if !def.Ordered {
+		winning = append([]int64(nil), winning...)
+		sort.Slice(winning, func(i, j int) bool { return winning[i] < winning[j] })
+	}
+
+	return key(combination) == key(winning)
+}
+
+// ErrStakeOverflow is returned by Cost for a bet whose total stake is too large to represent.
+var ErrStakeOverflow = errors.New("exotics: total stake overflows")
+
+// Cost returns the total stake of an exotic bet with the given number of combinations, in cents.
+// A flexi bet spreads its stake across its combinations, while any other stakes it on each.
+func Cost(bet *betting.Bet, combinations int64) (int64, error) {
+	if combinations == 0 {
+		return 0, nil
+	}
+
+	if bet.Flexi {
+		return bet.Stake, nil
+	}
+
+	if bet.Stake > math.MaxInt64/combinations {
+		return 0, ErrStakeOverflow
+	}
+
+	return bet.Stake * combinations, nil
+}
+
+// Unit returns the stake of an exotic bet with the given number of combinations on each of them,
+// in cents.
+func Unit(bet *betting.Bet, combinations int64) float64 {
+	if combinations == 0 {
+		return 0
+	}
+
+	if bet.Flexi {
+		return float64(bet.Stake) / float64(combinations)
+	}
+
+	return float64(bet.Stake)
+}
+
+// FlexiPercent returns the percentage of the dividend paid on each combination of a flexi bet,
+// which is paid per $1 unit.
+func FlexiPercent(bet *betting.Bet, combinations int64) float64 {
+	if !bet.Flexi {
+		return 0
+	}
+
+	// A unit stake in cents is the percentage of a $1 unit.
+	return Unit(bet, combinations)
+}
+
+// key identifies a combination.
+func key(combination []int64) string {
+	parts := make([]string, len(combination))
+	for i, number := range combination {
+		parts[i] = strconv.FormatInt(number, 10)
+	}
+
+	return strings.Join(parts, "-")
+}
@@ -0,0 +1,131 @@
+package exotics_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"git.neds.sh/matty/entain/betting/exotics"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+)
+
+// legs builds the legs of a bet, each selecting the given runner numbers.
+func legs(numbers ...[]int64) []*betting.Bet_Leg {
+	l := make([]*betting.Bet_Leg, len(numbers))
+	for i, n := range numbers {
+		l[i] = &betting.Bet_Leg{RunnerNumbers: n}
+	}
+
+	return l
+}
+
+// runners returns the runner numbers from first to last.
+func runners(first, last int64) []int64 {
+	var numbers []int64
+	for n := first; n <= last; n++ {
+		numbers = append(numbers, n)
+	}
+
+	return numbers
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name string
+		bet  *betting.Bet
+		want int64
+	}{
+		{name: "not exotic", bet: &betting.Bet{Type: betting.Bet_WIN}, want: 0},
+		{name: "boxed quinella", bet: &betting.Bet{Type: betting.Bet_QUINELLA, Boxed: true, Legs: legs(runners(1, 4))}, want: 6},
+		{name: "boxed exacta", bet: &betting.Bet{Type: betting.Bet_EXACTA, Boxed: true, Legs: legs(runners(1, 4))}, want: 12},
+		{name: "boxed trifecta", bet: &betting.Bet{Type: betting.Bet_TRIFECTA, Boxed: true, Legs: legs(runners(1, 5))}, want: 60},
+		{name: "boxed first four", bet: &betting.Bet{Type: betting.Bet_FIRST_FOUR, Boxed: true, Legs: legs(runners(1, 6))}, want: 360},
+		{name: "boxed with too few runners", bet: &betting.Bet{Type: betting.Bet_TRIFECTA, Boxed: true, Legs: legs(runners(1, 2))}, want: 0},
+		{name: "boxed with too many legs", bet: &betting.Bet{Type: betting.Bet_QUINELLA, Boxed: true, Legs: legs(runners(1, 2), runners(1, 2))}, want: 0},
+		{name: "legs for too few positions", bet: &betting.Bet{Type: betting.Bet_TRIFECTA, Legs: legs(runners(1, 2), runners(1, 2))}, want: 0},
+		{name: "straight exacta", bet: &betting.Bet{Type: betting.Bet_EXACTA, Legs: legs([]int64{1}, []int64{2})}, want: 1},
+		{name: "exacta repeating the only runner", bet: &betting.Bet{Type: betting.Bet_EXACTA, Legs: legs([]int64{1}, []int64{1})}, want: 0},
+		{name: "standout exacta", bet: &betting.Bet{Type: betting.Bet_EXACTA, Legs: legs([]int64{1}, runners(1, 5))}, want: 4},
+		{name: "exacta with overlapping legs", bet: &betting.Bet{Type: betting.Bet_EXACTA, Legs: legs(runners(1, 3), runners(2, 5))}, want: 10},
+		{name: "quinella legs selecting a pair both ways", bet: &betting.Bet{Type: betting.Bet_QUINELLA, Legs: legs(runners(1, 3), runners(1, 3))}, want: 3},
+		{name: "quinella with disjoint legs", bet: &betting.Bet{Type: betting.Bet_QUINELLA, Legs: legs(runners(1, 2), runners(3, 5))}, want: 6},
+		{name: "trifecta with overlapping legs", bet: &betting.Bet{Type: betting.Bet_TRIFECTA, Legs: legs(runners(1, 2), runners(1, 3), runners(2, 4))}, want: 7},
+		{name: "first four with the same legs", bet: &betting.Bet{Type: betting.Bet_FIRST_FOUR, Legs: legs(runners(1, 11), runners(1, 11), runners(1, 11), runners(1, 11))}, want: 7920},
+		{name: "first four of a full field", bet: &betting.Bet{Type: betting.Bet_FIRST_FOUR, Boxed: true, Legs: legs(runners(1, 24))}, want: 255024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exotics.Count(tt.bet); got != tt.want {
+				t.Errorf("Count() = %d, want %d", got, tt.want)
+			}
+
+			// Listing every combination of the largest bets is what counting them avoids.
+			if tt.want > 10000 {
+				return
+			}
+
+			if listed := int64(len(exotics.Combinations(tt.bet))); listed != tt.want {
+				t.Errorf("len(Combinations()) = %d, want %d", listed, tt.want)
+			}
+		})
+	}
+}
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		name         string
+		bet          *betting.Bet
+		combinations int64
+		total        int64
+		unit         float64
+		err          error
+	}{
+		{name: "no combinations", bet: &betting.Bet{Stake: 100}, combinations: 0, total: 0, unit: 0},
+		{name: "stake on each combination", bet: &betting.Bet{Stake: 100}, combinations: 6, total: 600, unit: 100},
+		{name: "flexi stake spread across combinations", bet: &betting.Bet{Stake: 100, Flexi: true}, combinations: 8, total: 100, unit: 12.5},
+		{name: "overflowing total", bet: &betting.Bet{Stake: math.MaxInt64 / 2}, combinations: 3, err: exotics.ErrStakeOverflow, unit: math.MaxInt64 / 2},
+		{name: "largest total", bet: &betting.Bet{Stake: math.MaxInt64 / 3}, combinations: 3, total: math.MaxInt64 / 3 * 3, unit: math.MaxInt64 / 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, err := exotics.Cost(tt.bet, tt.combinations)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Cost() error = %v, want %v", err, tt.err)
+			}
+
+			if total != tt.total {
+				t.Errorf("Cost() = %d, want %d", total, tt.total)
+			}
+
+			if unit := exotics.Unit(tt.bet, tt.combinations); unit != tt.unit {
+				t.Errorf("Unit() = %v, want %v", unit, tt.unit)
+			}
+		})
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		name        string
+		betType     betting.Bet_Type
+		combination []int64
+		winning     []int64
+		want        bool
+	}{
+		{name: "quinella in either order", betType: betting.Bet_QUINELLA, combination: []int64{1, 2}, winning: []int64{2, 1}, want: true},
+		{name: "exacta in order", betType: betting.Bet_EXACTA, combination: []int64{1, 2}, winning: []int64{1, 2}, want: true},
+		{name: "exacta out of order", betType: betting.Bet_EXACTA, combination: []int64{1, 2}, winning: []int64{2, 1}, want: false},
+		{name: "trifecta missing a runner", betType: betting.Bet_TRIFECTA, combination: []int64{1, 2, 3}, winning: []int64{1, 2, 4}, want: false},
+		{name: "different lengths", betType: betting.Bet_TRIFECTA, combination: []int64{1, 2, 3}, winning: []int64{1, 2}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exotics.Covers(exotics.Definitions[tt.betType], tt.combination, tt.winning); got != tt.want {
+				t.Errorf("Covers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Package funding reserves the total stakes of bets from the wallet, placing bets once their
+// stakes are reserved.
+package funding
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/betting/db"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	"git.neds.sh/matty/entain/logging"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+)
+
+// RefusedError is returned when the wallet refuses to reserve the total stake of a bet, e.g. for
+// insufficient funds, in which case the bet is deleted.
+type RefusedError struct {
+	// Err is the error returned by the wallet.
+	Err error
+}
+
+func (e *RefusedError) Error() string {
+	return "reserving funds refused: " + e.Err.Error()
+}
+
+func (e *RefusedError) Unwrap() error {
+	return e.Err
+}
+
+// Funder places PENDING bets by reserving their total stake from the accounts they are placed
+// for.
+type Funder struct {
+	betsRepo db.BetsRepo
+	wallet   wallet.WalletClient
+}
+
+// NewFunder creates a new funder, placing the bets in the given repository once their stakes are
+// reserved by the given wallet client.
+func NewFunder(betsRepo db.BetsRepo, walletClient wallet.WalletClient) *Funder {
+	return &Funder{betsRepo, walletClient}
+}
+
+// Fund reserves the total stake of a PENDING bet from its account, once, referenced by the bet,
+// then places the bet, returning it. A bet that is no longer pending is returned as it is.
+//
+// Should the wallet refuse, the bet is deleted and a RefusedError returned. Should the wallet fail
+// to answer, the bet is left pending, so that a retry may fund it.
+func (f *Funder) Fund(ctx context.Context, bet *betting.Bet) (*betting.Bet, error) {
+	if bet.Status != betting.Bet_PENDING {
+		return bet, nil
+	}
+
+	var (
+		name    = betName(bet.Id)
+		account = "accounts/" + bet.AccountId
+	)
+
+	_, err := f.wallet.ReserveFunds(ctx, &wallet.ReserveFundsRequest{
+		Name:      account,
+		RequestId: name,
+		Reference: name,
+		Amount:    bet.TotalStake,
+	})
+
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return nil, err
+	default:
+		if err := f.betsRepo.Delete(ctx, bet.Id); err != nil {
+			return nil, err
+		}
+
+		return nil, &RefusedError{Err: err}
+	}
+
+	placed, err := f.betsRepo.Confirm(ctx, bet.Id)
+	if !errors.Is(err, db.ErrNotFound) {
+		return placed, err
+	}
+
+	// A concurrent attempt to fund the bet was refused, and deleted it, before this one reserved
+	// its stake, which is released again.
+	if _, err := f.wallet.ReleaseFunds(ctx, &wallet.ReleaseFundsRequest{Name: account, RequestId: name + "/abandonment", Reference: name}); err != nil {
+		return nil, err
+	}
+
+	return nil, status.Errorf(codes.Aborted, "bet %q was deleted while its funds were reserved", name)
+}
+
+// Run funds the bets left PENDING for longer than age, every interval, until the context is done.
+// Bets are left pending when the wallet fails to answer the request placing them, which its
+// caller may never retry.
+func (f *Funder) Run(ctx context.Context, interval, age time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		f.fundPending(ctx, time.Now().Add(-age))
+	}
+}
+
+// fundPending funds the bets that have been PENDING since before a time.
+func (f *Funder) fundPending(ctx context.Context, before time.Time) {
+	bets, err := f.betsRepo.ListPending(ctx, before)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Warn("funding: listing pending bets failed")
+		return
+	}
+
+	for _, bet := range bets {
+		logger := logging.FromContext(ctx).WithFields(logrus.Fields{
+			"bet_id":     bet.Id,
+			"account_id": bet.AccountId,
+			"stake":      bet.TotalStake,
+		})
+
+		var refused *RefusedError
+
+		switch _, err := f.Fund(ctx, bet); {
+		case errors.As(err, &refused):
+			logger.WithError(refused.Err).Info("funding: pending bet refused by the wallet and deleted")
+		case err != nil:
+			logger.WithError(err).Warn("funding: funding pending bet failed")
+		default:
+			logger.Info("funding: pending bet placed")
+		}
+	}
+}
+
+// betName builds the resource name of a bet, which references the funds reserved for it.
+func betName(id int64) string {
+	return "bets/" + strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,259 @@
+package funding
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/betting/db"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	walletdb "git.neds.sh/matty/entain/wallet/db"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+	walletservice "git.neds.sh/matty/entain/wallet/service"
+)
+
+// walletClient calls a wallet service in process, as the betting service, failing to answer
+// reservations while unavailable is set. Methods other than those the funder calls are left
+// unimplemented.
+type walletClient struct {
+	wallet.WalletClient
+	service     walletservice.Wallet
+	unavailable bool
+}
+
+// serviceContext returns a context for a call made by the betting service.
+func serviceContext(ctx context.Context) context.Context {
+	return auth.NewContext(ctx, &auth.Identity{Subject: "betting", Roles: []string{auth.RoleService}})
+}
+
+func (c *walletClient) ReserveFunds(ctx context.Context, in *wallet.ReserveFundsRequest, _ ...grpc.CallOption) (*wallet.Transaction, error) {
+	if c.unavailable {
+		return nil, status.Error(codes.Unavailable, "wallet unavailable")
+	}
+
+	return c.service.ReserveFunds(serviceContext(ctx), in)
+}
+
+func (c *walletClient) ReleaseFunds(ctx context.Context, in *wallet.ReleaseFundsRequest, _ ...grpc.CallOption) (*wallet.Transaction, error) {
+	return c.service.ReleaseFunds(serviceContext(ctx), in)
+}
+
+// fixture is a funder over fresh betting and wallet databases, with alice holding the 1000
+// cents she deposited.
+type fixture struct {
+	funder   *Funder
+	wallet   *walletClient
+	betsRepo db.BetsRepo
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	open := func(name string) *sql.DB {
+		conn, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), name)+"?_txlock=immediate&_busy_timeout=5000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		return conn
+	}
+
+	betsRepo := db.NewBetsRepo(open("betting.db"))
+	if err := betsRepo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	accountsRepo := walletdb.NewAccountsRepo(open("wallet.db"))
+	if err := accountsRepo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := accountsRepo.Create("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fixture{wallet: &walletClient{service: walletservice.NewWalletService(accountsRepo)}, betsRepo: betsRepo}
+	f.funder = NewFunder(betsRepo, f.wallet)
+
+	if _, err := f.wallet.service.Deposit(serviceContext(context.Background()), &wallet.DepositRequest{Name: "accounts/alice", RequestId: "deposit", Amount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+// place stores a PENDING win bet of alice's for a stake, placed by a request.
+func (f *fixture) place(t *testing.T, requestID string, stake int64) *betting.Bet {
+	t.Helper()
+
+	bet, _, err := f.betsRepo.Place(context.Background(), requestID, &betting.Bet{AccountId: "alice", RaceId: 1, RunnerId: 10, Type: betting.Bet_WIN, Stake: stake, Price: 3.5, Status: betting.Bet_PENDING, Combinations: 1, TotalStake: stake})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bet
+}
+
+// reserved returns the funds reserved from alice's account.
+func (f *fixture) reserved(t *testing.T) int64 {
+	t.Helper()
+
+	account, err := f.wallet.service.GetAccount(auth.NewContext(context.Background(), &auth.Identity{Subject: "alice"}), &wallet.GetAccountRequest{Name: "accounts/alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return account.Reserved
+}
+
+func TestFund(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		stake       int64
+		unavailable bool
+		// code is that of the error funding the bet, if any.
+		code codes.Code
+		// refused is whether the bet is refused by the wallet, and deleted.
+		refused bool
+		// status is that of the bet once funded, if it is kept.
+		status   betting.Bet_Status
+		reserved int64
+	}{
+		{name: "funded", stake: 100, status: betting.Bet_PLACED, reserved: 100},
+		{name: "the whole balance", stake: 1000, status: betting.Bet_PLACED, reserved: 1000},
+		{name: "insufficient funds", stake: 1001, code: codes.FailedPrecondition, refused: true},
+		{name: "wallet unavailable", stake: 100, unavailable: true, code: codes.Unavailable, status: betting.Bet_PENDING},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.wallet.unavailable = tt.unavailable
+
+			bet := f.place(t, "1", tt.stake)
+
+			funded, err := f.funder.Fund(ctx, bet)
+
+			code := status.Code(err)
+
+			var refused *RefusedError
+			if errors.As(err, &refused) {
+				code = status.Code(refused.Err)
+			}
+
+			if code != tt.code || (refused != nil) != tt.refused {
+				t.Fatalf("Fund() error = %v, want %s, refused %t", err, tt.code, tt.refused)
+			}
+
+			if err == nil && funded.Status != tt.status {
+				t.Errorf("Fund() status = %s, want %s", funded.Status, tt.status)
+			}
+
+			stored, err := f.betsRepo.Get(ctx, bet.Id)
+			switch {
+			case tt.refused && !errors.Is(err, db.ErrNotFound):
+				t.Errorf("Get() error = %v, want the refused bet deleted", err)
+			case !tt.refused && err != nil:
+				t.Fatal(err)
+			case !tt.refused && stored.Status != tt.status:
+				t.Errorf("stored status = %s, want %s", stored.Status, tt.status)
+			}
+
+			if reserved := f.reserved(t); reserved != tt.reserved {
+				t.Errorf("reserved = %d, want %d", reserved, tt.reserved)
+			}
+		})
+	}
+}
+
+func TestFundRetry(t *testing.T) {
+	ctx := context.Background()
+
+	f := newFixture(t)
+	bet := f.place(t, "1", 100)
+
+	f.wallet.unavailable = true
+	if _, err := f.funder.Fund(ctx, bet); status.Code(err) != codes.Unavailable {
+		t.Fatalf("Fund() error = %v, want %s", err, codes.Unavailable)
+	}
+
+	f.wallet.unavailable = false
+
+	funded, err := f.funder.Fund(ctx, bet)
+	if err != nil || funded.Status != betting.Bet_PLACED {
+		t.Fatalf("Fund() retried = %v, %v; want the bet placed", funded, err)
+	}
+
+	// Funding the placed bet again reserves nothing more.
+	again, err := f.funder.Fund(ctx, funded)
+	if err != nil || again.Status != betting.Bet_PLACED {
+		t.Fatalf("Fund() placed bet = %v, %v; want the bet as it is", again, err)
+	}
+
+	if reserved := f.reserved(t); reserved != 100 {
+		t.Errorf("reserved = %d, want 100", reserved)
+	}
+}
+
+func TestFundDeletedBet(t *testing.T) {
+	ctx := context.Background()
+
+	f := newFixture(t)
+	bet := f.place(t, "1", 100)
+
+	// A concurrent attempt, refused by the wallet, deleted the bet.
+	if err := f.betsRepo.Delete(ctx, bet.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.funder.Fund(ctx, bet); status.Code(err) != codes.Aborted {
+		t.Fatalf("Fund() error = %v, want %s", err, codes.Aborted)
+	}
+
+	if reserved := f.reserved(t); reserved != 0 {
+		t.Errorf("reserved = %d, want the reservation released", reserved)
+	}
+}
+
+func TestFundPending(t *testing.T) {
+	ctx := context.Background()
+
+	f := newFixture(t)
+
+	var (
+		funded  = f.place(t, "1", 100)
+		refused = f.place(t, "2", 5000)
+	)
+
+	// Bets pending since after the sweep's cutoff are left to the requests placing them.
+	f.funder.fundPending(ctx, time.Now().Add(-time.Hour))
+
+	if pending, err := f.betsRepo.ListPending(ctx, time.Now().Add(time.Second)); err != nil || len(pending) != 2 {
+		t.Fatalf("ListPending() = %d bets, %v; want both bets left pending", len(pending), err)
+	}
+
+	f.funder.fundPending(ctx, time.Now().Add(time.Second))
+
+	if bet, err := f.betsRepo.Get(ctx, funded.Id); err != nil || bet.Status != betting.Bet_PLACED {
+		t.Errorf("Get() funded bet = %v, %v; want it placed", bet, err)
+	}
+
+	if _, err := f.betsRepo.Get(ctx, refused.Id); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Get() refused bet error = %v, want it deleted", err)
+	}
+
+	if reserved := f.reserved(t); reserved != 100 {
+		t.Errorf("reserved = %d, want 100", reserved)
+	}
+}
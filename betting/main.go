@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"log"
+	"net"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/betting/db"
+	"git.neds.sh/matty/entain/betting/funding"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	"git.neds.sh/matty/entain/betting/service"
+	"git.neds.sh/matty/entain/betting/settlement"
+	"git.neds.sh/matty/entain/logging"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+	"google.golang.org/grpc"
+)
+
+const (
+	// pendingInterval is how often bets left PENDING are funded.
+	pendingInterval = 10 * time.Second
+	// pendingAge is how long a bet is left PENDING, for a retry of the request placing it to fund
+	// it, before it is funded regardless.
+	pendingAge = 30 * time.Second
+)
+
+var (
+	grpcEndpoint       = flag.String("grpc-endpoint", "localhost:9002", "gRPC server endpoint")
+	racingGRPCEndpoint = flag.String("racing-grpc-endpoint", "localhost:9000", "Racing gRPC server endpoint")
+	walletGRPCEndpoint = flag.String("wallet-grpc-endpoint", "localhost:9003", "Wallet gRPC server endpoint")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := logging.Setup("betting", logging.DefaultConfig); err != nil {
+		log.Fatalf("failed setting up logging: %s\n", err)
+	}
+
+	if err := run(); err != nil {
+		log.Fatalf("failed running grpc server: %s\n", err)
+	}
+}
+
+func run() error {
+	conn, err := net.Listen("tcp", *grpcEndpoint)
+	if err != nil {
+		return err
+	}
+
+	// Transactions begin immediately, waiting out those of concurrent calls rather than failing.
+	bettingDB, err := sql.Open("sqlite3", "file:./db/betting.db?_txlock=immediate&_busy_timeout=5000")
+	if err != nil {
+		return err
+	}
+
+	betsRepo := db.NewBetsRepo(bettingDB)
+	if err := betsRepo.Init(); err != nil {
+		return err
+	}
+
+	// Bets are settled on behalf of the betting service, which may see races whatever their
+	// visibility.
+	racingConn, err := grpc.Dial(*racingGRPCEndpoint, append(auth.ServiceDialOptions("betting"), grpc.WithInsecure())...)
+	if err != nil {
+		return err
+	}
+	defer racingConn.Close()
+
+	racingClient := racing.NewRacingClient(racingConn)
+
+	// Funds are reserved, settled and released on behalf of the betting service, which alone may
+	// move them.
+	walletConn, err := grpc.Dial(*walletGRPCEndpoint, append(auth.ServiceDialOptions("betting"), grpc.WithInsecure())...)
+	if err != nil {
+		return err
+	}
+	defer walletConn.Close()
+
+	walletClient := wallet.NewWalletClient(walletConn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go settlement.NewEngine(betsRepo, racingClient, walletClient).Run(ctx)
+	go funding.NewFunder(betsRepo, walletClient).Run(ctx, pendingInterval, pendingAge)
+
+	// Bets are placed for the caller identified by the gateway.
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(auth.UnaryServerInterceptor))
+
+	betting.RegisterBettingServer(
+		grpcServer,
+		service.NewBettingService(
+			betsRepo,
+			racingClient,
+			walletClient,
+		),
+	)
+
+	log.Printf("gRPC server listening on: %s\n", *grpcEndpoint)
+
+	if err := grpcServer.Serve(conn); err != nil {
+		return err
+	}
+
+	return nil
+}
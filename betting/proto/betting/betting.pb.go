@@ -0,0 +1,1202 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        v3.6.1
+// source: betting/betting.proto
+
+package betting
+
+import (
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Type describes what a bet pays out on.
+type Bet_Type int32
+
+const (
+	// The bet type is unknown.
+	Bet_TYPE_UNSPECIFIED Bet_Type = 0
+	// The runner must finish first.
+	Bet_WIN Bet_Type = 1
+	// The runner must finish in the places.
+	Bet_PLACE Bet_Type = 2
+	// A win bet and a place bet of the same stake on the runner.
+	Bet_EACH_WAY Bet_Type = 3
+	// Two runners must finish first and second, in either order.
+	Bet_QUINELLA Bet_Type = 4
+	// Two runners must finish first and second, in order.
+	Bet_EXACTA Bet_Type = 5
+	// Three runners must finish first, second and third, in order.
+	Bet_TRIFECTA Bet_Type = 6
+	// Four runners must finish first, second, third and fourth, in order.
+	Bet_FIRST_FOUR Bet_Type = 7
+)
+
+// Enum value maps for Bet_Type.
+var (
+	Bet_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "WIN",
+		2: "PLACE",
+		3: "EACH_WAY",
+		4: "QUINELLA",
+		5: "EXACTA",
+		6: "TRIFECTA",
+		7: "FIRST_FOUR",
+	}
+	Bet_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"WIN":              1,
+		"PLACE":            2,
+		"EACH_WAY":         3,
+		"QUINELLA":         4,
+		"EXACTA":           5,
+		"TRIFECTA":         6,
+		"FIRST_FOUR":       7,
+	}
+)
+
+func (x Bet_Type) Enum() *Bet_Type {
+	p := new(Bet_Type)
+	*p = x
+	return p
+}
+
+func (x Bet_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Bet_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_betting_betting_proto_enumTypes[0].Descriptor()
+}
+
+func (Bet_Type) Type() protoreflect.EnumType {
+	return &file_betting_betting_proto_enumTypes[0]
+}
+
+func (x Bet_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Bet_Type.Descriptor instead.
+func (Bet_Type) EnumDescriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{6, 0}
+}
+
+// Status describes the lifecycle of a bet.
+type Bet_Status int32
+
+const (
+	// The status is unknown.
+	Bet_STATUS_UNSPECIFIED Bet_Status = 0
+	// The bet has been placed, and awaits the result of the race.
+	Bet_PLACED Bet_Status = 1
+	// The bet has been settled with a payout.
+	Bet_WON Bet_Status = 2
+	// The bet has been settled without a payout.
+	Bet_LOST Bet_Status = 3
+	// The bet has been settled by refunding its stake, as the runner was scratched, the race
+	// abandoned or no dividend declared.
+	Bet_REFUNDED Bet_Status = 4
+	// The bet has been accepted, but its total stake is yet to be reserved, e.g. as the wallet
+	// was unavailable. It is placed once its stake is reserved, or deleted should the account not
+	// hold the funds.
+	Bet_PENDING Bet_Status = 5
+)
+
+// Enum value maps for Bet_Status.
+var (
+	Bet_Status_name = map[int32]string{
+		0: "STATUS_UNSPECIFIED",
+		1: "PLACED",
+		2: "WON",
+		3: "LOST",
+		4: "REFUNDED",
+		5: "PENDING",
+	}
+	Bet_Status_value = map[string]int32{
+		"STATUS_UNSPECIFIED": 0,
+		"PLACED":             1,
+		"WON":                2,
+		"LOST":               3,
+		"REFUNDED":           4,
+		"PENDING":            5,
+	}
+)
+
+func (x Bet_Status) Enum() *Bet_Status {
+	p := new(Bet_Status)
+	*p = x
+	return p
+}
+
+func (x Bet_Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Bet_Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_betting_betting_proto_enumTypes[1].Descriptor()
+}
+
+func (Bet_Status) Type() protoreflect.EnumType {
+	return &file_betting_betting_proto_enumTypes[1]
+}
+
+func (x Bet_Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Bet_Status.Descriptor instead.
+func (Bet_Status) EnumDescriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{6, 1}
+}
+
+// Type describes a ledger entry.
+type Settlement_Type int32
+
+const (
+	// The entry type is unknown.
+	Settlement_TYPE_UNSPECIFIED Settlement_Type = 0
+	// The bet was settled.
+	Settlement_SETTLEMENT Settlement_Type = 1
+	// A previous settlement of the bet was reversed.
+	Settlement_REVERSAL Settlement_Type = 2
+)
+
+// Enum value maps for Settlement_Type.
+var (
+	Settlement_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "SETTLEMENT",
+		2: "REVERSAL",
+	}
+	Settlement_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"SETTLEMENT":       1,
+		"REVERSAL":         2,
+	}
+)
+
+func (x Settlement_Type) Enum() *Settlement_Type {
+	p := new(Settlement_Type)
+	*p = x
+	return p
+}
+
+func (x Settlement_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Settlement_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_betting_betting_proto_enumTypes[2].Descriptor()
+}
+
+func (Settlement_Type) Type() protoreflect.EnumType {
+	return &file_betting_betting_proto_enumTypes[2]
+}
+
+func (x Settlement_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Settlement_Type.Descriptor instead.
+func (Settlement_Type) EnumDescriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{7, 0}
+}
+
+// Request for PlaceBet call.
+type PlaceBetRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// RequestID identifies the placement, so that it can be safely retried: a retry with the same
+	// request_id returns the bet already placed rather than placing another. It must be unique to
+	// each bet of the account, e.g. a UUID, and no longer than 128 characters.
+	RequestId string `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// Bet is the bet to place. Its id, status and place_time are assigned by the server.
+	Bet *Bet `protobuf:"bytes,2,opt,name=bet,proto3" json:"bet,omitempty"`
+}
+
+func (x *PlaceBetRequest) Reset() {
+	*x = PlaceBetRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_betting_betting_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PlaceBetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlaceBetRequest) ProtoMessage() {}
+
+func (x *PlaceBetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_betting_betting_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlaceBetRequest.ProtoReflect.Descriptor instead.
+func (*PlaceBetRequest) Descriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PlaceBetRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *PlaceBetRequest) GetBet() *Bet {
+	if x != nil {
+		return x.Bet
+	}
+	return nil
+}
+
+// Request for GetBet call.
+type GetBetRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name is the resource name of the bet, in the form bets/{id}.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *GetBetRequest) Reset() {
+	*x = GetBetRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_betting_betting_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetBetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBetRequest) ProtoMessage() {}
+
+func (x *GetBetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_betting_betting_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBetRequest.ProtoReflect.Descriptor instead.
+func (*GetBetRequest) Descriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetBetRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Request for ListSettlements call.
+type ListSettlementsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Parent is the resource name of the bet, in the form bets/{id}.
+	Parent string `protobuf:"bytes,1,opt,name=parent,proto3" json:"parent,omitempty"`
+}
+
+func (x *ListSettlementsRequest) Reset() {
+	*x = ListSettlementsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_betting_betting_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListSettlementsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSettlementsRequest) ProtoMessage() {}
+
+func (x *ListSettlementsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_betting_betting_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSettlementsRequest.ProtoReflect.Descriptor instead.
+func (*ListSettlementsRequest) Descriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListSettlementsRequest) GetParent() string {
+	if x != nil {
+		return x.Parent
+	}
+	return ""
+}
+
+// Response to ListSettlements call.
+type ListSettlementsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Settlements []*Settlement `protobuf:"bytes,1,rep,name=settlements,proto3" json:"settlements,omitempty"`
+}
+
+func (x *ListSettlementsResponse) Reset() {
+	*x = ListSettlementsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_betting_betting_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListSettlementsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSettlementsResponse) ProtoMessage() {}
+
+func (x *ListSettlementsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_betting_betting_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSettlementsResponse.ProtoReflect.Descriptor instead.
+func (*ListSettlementsResponse) Descriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListSettlementsResponse) GetSettlements() []*Settlement {
+	if x != nil {
+		return x.Settlements
+	}
+	return nil
+}
+
+// Request for QuoteBet call.
+type QuoteBetRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Bet is the bet to quote.
+	Bet *Bet `protobuf:"bytes,1,opt,name=bet,proto3" json:"bet,omitempty"`
+}
+
+func (x *QuoteBetRequest) Reset() {
+	*x = QuoteBetRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_betting_betting_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QuoteBetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuoteBetRequest) ProtoMessage() {}
+
+func (x *QuoteBetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_betting_betting_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuoteBetRequest.ProtoReflect.Descriptor instead.
+func (*QuoteBetRequest) Descriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *QuoteBetRequest) GetBet() *Bet {
+	if x != nil {
+		return x.Bet
+	}
+	return nil
+}
+
+// Response to QuoteBet call.
+type QuoteBetResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Combinations is the number of combinations the bet is made up of.
+	Combinations int64 `protobuf:"varint,1,opt,name=combinations,proto3" json:"combinations,omitempty"`
+	// TotalStake is the amount the bet costs, in cents.
+	TotalStake int64 `protobuf:"varint,2,opt,name=total_stake,json=totalStake,proto3" json:"total_stake,omitempty"`
+	// FlexiPercent is the percentage of the dividend each combination is paid. Only set for
+	// flexi bets.
+	FlexiPercent float64 `protobuf:"fixed64,3,opt,name=flexi_percent,json=flexiPercent,proto3" json:"flexi_percent,omitempty"`
+}
+
+func (x *QuoteBetResponse) Reset() {
+	*x = QuoteBetResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_betting_betting_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QuoteBetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuoteBetResponse) ProtoMessage() {}
+
+func (x *QuoteBetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_betting_betting_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuoteBetResponse.ProtoReflect.Descriptor instead.
+func (*QuoteBetResponse) Descriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *QuoteBetResponse) GetCombinations() int64 {
+	if x != nil {
+		return x.Combinations
+	}
+	return 0
+}
+
+func (x *QuoteBetResponse) GetTotalStake() int64 {
+	if x != nil {
+		return x.TotalStake
+	}
+	return 0
+}
+
+func (x *QuoteBetResponse) GetFlexiPercent() float64 {
+	if x != nil {
+		return x.FlexiPercent
+	}
+	return 0
+}
+
+// A bet resource: a fixed-odds bet on a runner, or an exotic bet on a selection of runners.
+type Bet struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// ID represents a unique identifier for the bet.
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// AccountID identifies the account the bet is placed for, which is always the account of the
+	// caller placing it, identified by their subject. It may be left empty when placing a bet.
+	AccountId string `protobuf:"bytes,2,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	// RaceID represents a unique identifier for the race bet on.
+	RaceId int64 `protobuf:"varint,3,opt,name=race_id,json=raceId,proto3" json:"race_id,omitempty"`
+	// RunnerID represents a unique identifier for the runner bet on. Not set for exotic bets.
+	RunnerId int64 `protobuf:"varint,4,opt,name=runner_id,json=runnerId,proto3" json:"runner_id,omitempty"`
+	// Type is the type of bet.
+	Type Bet_Type `protobuf:"varint,5,opt,name=type,proto3,enum=betting.Bet_Type" json:"type,omitempty"`
+	// Stake is the amount staked, in cents. EACH_WAY bets stake this amount on each of their win
+	// and place parts, so cost twice the stake. Exotic bets stake this amount on each of their
+	// combinations, unless flexi.
+	Stake int64 `protobuf:"varint,6,opt,name=stake,proto3" json:"stake,omitempty"`
+	// Price is the decimal fixed-odds price the bet is placed at: the win price of WIN and
+	// EACH_WAY bets, and the place price of PLACE bets. It is locked in from the runner's current
+	// price when the bet is placed. If set when placing a bet, the bet is rejected should the
+	// runner's current price differ. Not set for exotic bets.
+	Price float64 `protobuf:"fixed64,7,opt,name=price,proto3" json:"price,omitempty"`
+	// Status is where the bet is in its lifecycle.
+	Status Bet_Status `protobuf:"varint,8,opt,name=status,proto3,enum=betting.Bet_Status" json:"status,omitempty"`
+	// PlaceTime is when the bet was placed.
+	PlaceTime *timestamp.Timestamp `protobuf:"bytes,9,opt,name=place_time,json=placeTime,proto3" json:"place_time,omitempty"`
+	// PlacePrice is the decimal place price locked in for the place part of an EACH_WAY bet.
+	PlacePrice float64 `protobuf:"fixed64,10,opt,name=place_price,json=placePrice,proto3" json:"place_price,omitempty"`
+	// Payout is the amount returned by a settled bet, in cents, including any refunded stake.
+	Payout int64 `protobuf:"varint,11,opt,name=payout,proto3" json:"payout,omitempty"`
+	// SettleTime is when the bet was last settled. Only set for settled bets.
+	SettleTime *timestamp.Timestamp `protobuf:"bytes,12,opt,name=settle_time,json=settleTime,proto3" json:"settle_time,omitempty"`
+	// Legs are the runners selected by an exotic bet, by their saddle numbers. Each leg selects
+	// the runners for a finishing position in turn, e.g. the first leg of a trifecta selects the
+	// runners to finish first. Boxed bets have a single leg, of runners to finish in any order.
+	// The legs of a QUINELLA are unordered.
+	Legs []*Bet_Leg `protobuf:"bytes,13,rep,name=legs,proto3" json:"legs,omitempty"`
+	// Boxed is whether an exotic bet covers every order of the runners in its single leg.
+	Boxed bool `protobuf:"varint,14,opt,name=boxed,proto3" json:"boxed,omitempty"`
+	// Flexi is whether the stake of an exotic bet is its total stake, spread across its
+	// combinations, rather than the stake on each combination.
+	Flexi bool `protobuf:"varint,15,opt,name=flexi,proto3" json:"flexi,omitempty"`
+	// Combinations is the number of combinations the bet is made up of. Assigned by the server.
+	Combinations int64 `protobuf:"varint,16,opt,name=combinations,proto3" json:"combinations,omitempty"`
+	// TotalStake is the amount the bet costs, in cents. Assigned by the server.
+	TotalStake int64 `protobuf:"varint,17,opt,name=total_stake,json=totalStake,proto3" json:"total_stake,omitempty"`
+}
+
+func (x *Bet) Reset() {
+	*x = Bet{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_betting_betting_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Bet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bet) ProtoMessage() {}
+
+func (x *Bet) ProtoReflect() protoreflect.Message {
+	mi := &file_betting_betting_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bet.ProtoReflect.Descriptor instead.
+func (*Bet) Descriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Bet) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Bet) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *Bet) GetRaceId() int64 {
+	if x != nil {
+		return x.RaceId
+	}
+	return 0
+}
+
+func (x *Bet) GetRunnerId() int64 {
+	if x != nil {
+		return x.RunnerId
+	}
+	return 0
+}
+
+func (x *Bet) GetType() Bet_Type {
+	if x != nil {
+		return x.Type
+	}
+	return Bet_TYPE_UNSPECIFIED
+}
+
+func (x *Bet) GetStake() int64 {
+	if x != nil {
+		return x.Stake
+	}
+	return 0
+}
+
+func (x *Bet) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Bet) GetStatus() Bet_Status {
+	if x != nil {
+		return x.Status
+	}
+	return Bet_STATUS_UNSPECIFIED
+}
+
+func (x *Bet) GetPlaceTime() *timestamp.Timestamp {
+	if x != nil {
+		return x.PlaceTime
+	}
+	return nil
+}
+
+func (x *Bet) GetPlacePrice() float64 {
+	if x != nil {
+		return x.PlacePrice
+	}
+	return 0
+}
+
+func (x *Bet) GetPayout() int64 {
+	if x != nil {
+		return x.Payout
+	}
+	return 0
+}
+
+func (x *Bet) GetSettleTime() *timestamp.Timestamp {
+	if x != nil {
+		return x.SettleTime
+	}
+	return nil
+}
+
+func (x *Bet) GetLegs() []*Bet_Leg {
+	if x != nil {
+		return x.Legs
+	}
+	return nil
+}
+
+func (x *Bet) GetBoxed() bool {
+	if x != nil {
+		return x.Boxed
+	}
+	return false
+}
+
+func (x *Bet) GetFlexi() bool {
+	if x != nil {
+		return x.Flexi
+	}
+	return false
+}
+
+func (x *Bet) GetCombinations() int64 {
+	if x != nil {
+		return x.Combinations
+	}
+	return 0
+}
+
+func (x *Bet) GetTotalStake() int64 {
+	if x != nil {
+		return x.TotalStake
+	}
+	return 0
+}
+
+// An entry in the settlement ledger of a bet. Entries are never modified: a bet whose result is
+// corrected has its settlement reversed by a REVERSAL entry, before being settled again.
+type Settlement struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// ID represents a unique identifier for the entry.
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// BetID represents a unique identifier for the bet settled.
+	BetId int64 `protobuf:"varint,2,opt,name=bet_id,json=betId,proto3" json:"bet_id,omitempty"`
+	// Type is whether the entry settles the bet or reverses its settlement.
+	Type Settlement_Type `protobuf:"varint,3,opt,name=type,proto3,enum=betting.Settlement_Type" json:"type,omitempty"`
+	// Status is the status the entry moved the bet into.
+	Status Bet_Status `protobuf:"varint,4,opt,name=status,proto3,enum=betting.Bet_Status" json:"status,omitempty"`
+	// Amount is the amount paid out by the entry, in cents. Negative for reversals.
+	Amount int64 `protobuf:"varint,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	// CreateTime is when the entry was recorded.
+	CreateTime *timestamp.Timestamp `protobuf:"bytes,6,opt,name=create_time,json=createTime,proto3" json:"create_time,omitempty"`
+}
+
+func (x *Settlement) Reset() {
+	*x = Settlement{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_betting_betting_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Settlement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Settlement) ProtoMessage() {}
+
+func (x *Settlement) ProtoReflect() protoreflect.Message {
+	mi := &file_betting_betting_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Settlement.ProtoReflect.Descriptor instead.
+func (*Settlement) Descriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Settlement) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Settlement) GetBetId() int64 {
+	if x != nil {
+		return x.BetId
+	}
+	return 0
+}
+
+func (x *Settlement) GetType() Settlement_Type {
+	if x != nil {
+		return x.Type
+	}
+	return Settlement_TYPE_UNSPECIFIED
+}
+
+func (x *Settlement) GetStatus() Bet_Status {
+	if x != nil {
+		return x.Status
+	}
+	return Bet_STATUS_UNSPECIFIED
+}
+
+func (x *Settlement) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Settlement) GetCreateTime() *timestamp.Timestamp {
+	if x != nil {
+		return x.CreateTime
+	}
+	return nil
+}
+
+// A leg of an exotic bet.
+type Bet_Leg struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// RunnerNumbers are the saddle numbers of the runners selected.
+	RunnerNumbers []int64 `protobuf:"varint,1,rep,packed,name=runner_numbers,json=runnerNumbers,proto3" json:"runner_numbers,omitempty"`
+}
+
+func (x *Bet_Leg) Reset() {
+	*x = Bet_Leg{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_betting_betting_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Bet_Leg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bet_Leg) ProtoMessage() {}
+
+func (x *Bet_Leg) ProtoReflect() protoreflect.Message {
+	mi := &file_betting_betting_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bet_Leg.ProtoReflect.Descriptor instead.
+func (*Bet_Leg) Descriptor() ([]byte, []int) {
+	return file_betting_betting_proto_rawDescGZIP(), []int{6, 0}
+}
+
+func (x *Bet_Leg) GetRunnerNumbers() []int64 {
+	if x != nil {
+		return x.RunnerNumbers
+	}
+	return nil
+}
+
+var File_betting_betting_proto protoreflect.FileDescriptor
+
+var file_betting_betting_proto_rawDesc = []byte{
+	0x0a, 0x15, 0x62, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x2f, 0x62, 0x65, 0x74, 0x74, 0x69, 0x6e,
+	0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x62, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67,
+	0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x22, 0x50, 0x0a, 0x0f, 0x50, 0x6c, 0x61, 0x63, 0x65, 0x42, 0x65, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x49, 0x64, 0x12, 0x1e, 0x0a, 0x03, 0x62, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0c, 0x2e, 0x62, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x65, 0x74, 0x52, 0x03,
+	0x62, 0x65, 0x74, 0x22, 0x23, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x42, 0x65, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x30, 0x0a, 0x16, 0x4c, 0x69, 0x73, 0x74,
+	0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x22, 0x50, 0x0a, 0x17, 0x4c, 0x69,
+	0x73, 0x74, 0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x0b, 0x73, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d,
+	0x65, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x62, 0x65, 0x74,
+	0x74, 0x69, 0x6e, 0x67, 0x2e, 0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x52,
+	0x0b, 0x73, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x22, 0x31, 0x0a, 0x0f,
+	0x51, 0x75, 0x6f, 0x74, 0x65, 0x42, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x1e, 0x0a, 0x03, 0x62, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x62,
+	0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x65, 0x74, 0x52, 0x03, 0x62, 0x65, 0x74, 0x22,
+	0x7c, 0x0a, 0x10, 0x51, 0x75, 0x6f, 0x74, 0x65, 0x42, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x6f, 0x6d, 0x62, 0x69, 0x6e, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x63, 0x6f, 0x6d, 0x62, 0x69,
+	0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x6f, 0x74, 0x61, 0x6c,
+	0x5f, 0x73, 0x74, 0x61, 0x6b, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x74, 0x6f,
+	0x74, 0x61, 0x6c, 0x53, 0x74, 0x61, 0x6b, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x66, 0x6c, 0x65, 0x78,
+	0x69, 0x5f, 0x70, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x0c, 0x66, 0x6c, 0x65, 0x78, 0x69, 0x50, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x22, 0xb4, 0x06,
+	0x0a, 0x03, 0x42, 0x65, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x61, 0x63, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x72, 0x61, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x72, 0x61, 0x63, 0x65, 0x49, 0x64, 0x12, 0x1b, 0x0a,
+	0x09, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x08, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x12, 0x25, 0x0a, 0x04, 0x74, 0x79,
+	0x70, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x62, 0x65, 0x74, 0x74, 0x69,
+	0x6e, 0x67, 0x2e, 0x42, 0x65, 0x74, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x6b, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x05, 0x73, 0x74, 0x61, 0x6b, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x12, 0x2b, 0x0a,
+	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e,
+	0x62, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x65, 0x74, 0x2e, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x39, 0x0a, 0x0a, 0x70, 0x6c,
+	0x61, 0x63, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x70, 0x6c, 0x61, 0x63,
+	0x65, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x5f, 0x70,
+	0x72, 0x69, 0x63, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0a, 0x70, 0x6c, 0x61, 0x63,
+	0x65, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x61, 0x79, 0x6f, 0x75, 0x74,
+	0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x70, 0x61, 0x79, 0x6f, 0x75, 0x74, 0x12, 0x3b,
+	0x0a, 0x0b, 0x73, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x0c, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52,
+	0x0a, 0x73, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x24, 0x0a, 0x04, 0x6c,
+	0x65, 0x67, 0x73, 0x18, 0x0d, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x62, 0x65, 0x74, 0x74,
+	0x69, 0x6e, 0x67, 0x2e, 0x42, 0x65, 0x74, 0x2e, 0x4c, 0x65, 0x67, 0x52, 0x04, 0x6c, 0x65, 0x67,
+	0x73, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x6f, 0x78, 0x65, 0x64, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x05, 0x62, 0x6f, 0x78, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x6c, 0x65, 0x78, 0x69,
+	0x18, 0x0f, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x66, 0x6c, 0x65, 0x78, 0x69, 0x12, 0x22, 0x0a,
+	0x0c, 0x63, 0x6f, 0x6d, 0x62, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x10, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0c, 0x63, 0x6f, 0x6d, 0x62, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x73, 0x74, 0x61, 0x6b, 0x65,
+	0x18, 0x11, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x53, 0x74, 0x61,
+	0x6b, 0x65, 0x1a, 0x2c, 0x0a, 0x03, 0x4c, 0x65, 0x67, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x75, 0x6e,
+	0x6e, 0x65, 0x72, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x03, 0x52, 0x0d, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x73,
+	0x22, 0x76, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x10, 0x54, 0x59, 0x50, 0x45,
+	0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x07,
+	0x0a, 0x03, 0x57, 0x49, 0x4e, 0x10, 0x01, 0x12, 0x09, 0x0a, 0x05, 0x50, 0x4c, 0x41, 0x43, 0x45,
+	0x10, 0x02, 0x12, 0x0c, 0x0a, 0x08, 0x45, 0x41, 0x43, 0x48, 0x5f, 0x57, 0x41, 0x59, 0x10, 0x03,
+	0x12, 0x0c, 0x0a, 0x08, 0x51, 0x55, 0x49, 0x4e, 0x45, 0x4c, 0x4c, 0x41, 0x10, 0x04, 0x12, 0x0a,
+	0x0a, 0x06, 0x45, 0x58, 0x41, 0x43, 0x54, 0x41, 0x10, 0x05, 0x12, 0x0c, 0x0a, 0x08, 0x54, 0x52,
+	0x49, 0x46, 0x45, 0x43, 0x54, 0x41, 0x10, 0x06, 0x12, 0x0e, 0x0a, 0x0a, 0x46, 0x49, 0x52, 0x53,
+	0x54, 0x5f, 0x46, 0x4f, 0x55, 0x52, 0x10, 0x07, 0x22, 0x5a, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x12, 0x16, 0x0a, 0x12, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x55, 0x4e, 0x53,
+	0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x50, 0x4c,
+	0x41, 0x43, 0x45, 0x44, 0x10, 0x01, 0x12, 0x07, 0x0a, 0x03, 0x57, 0x4f, 0x4e, 0x10, 0x02, 0x12,
+	0x08, 0x0a, 0x04, 0x4c, 0x4f, 0x53, 0x54, 0x10, 0x03, 0x12, 0x0c, 0x0a, 0x08, 0x52, 0x45, 0x46,
+	0x55, 0x4e, 0x44, 0x45, 0x44, 0x10, 0x04, 0x12, 0x0b, 0x0a, 0x07, 0x50, 0x45, 0x4e, 0x44, 0x49,
+	0x4e, 0x47, 0x10, 0x05, 0x22, 0x9f, 0x02, 0x0a, 0x0a, 0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d,
+	0x65, 0x6e, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x02, 0x69, 0x64, 0x12, 0x15, 0x0a, 0x06, 0x62, 0x65, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x05, 0x62, 0x65, 0x74, 0x49, 0x64, 0x12, 0x2c, 0x0a, 0x04, 0x74, 0x79,
+	0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x62, 0x65, 0x74, 0x74, 0x69,
+	0x6e, 0x67, 0x2e, 0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x2e, 0x54, 0x79,
+	0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x2b, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x62, 0x65, 0x74, 0x74, 0x69,
+	0x6e, 0x67, 0x2e, 0x42, 0x65, 0x74, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x3b, 0x0a,
+	0x0b, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0a,
+	0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x22, 0x3a, 0x0a, 0x04, 0x54, 0x79,
+	0x70, 0x65, 0x12, 0x14, 0x0a, 0x10, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45,
+	0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x53, 0x45, 0x54, 0x54,
+	0x4c, 0x45, 0x4d, 0x45, 0x4e, 0x54, 0x10, 0x01, 0x12, 0x0c, 0x0a, 0x08, 0x52, 0x45, 0x56, 0x45,
+	0x52, 0x53, 0x41, 0x4c, 0x10, 0x02, 0x32, 0x8c, 0x02, 0x0a, 0x07, 0x42, 0x65, 0x74, 0x74, 0x69,
+	0x6e, 0x67, 0x12, 0x34, 0x0a, 0x08, 0x50, 0x6c, 0x61, 0x63, 0x65, 0x42, 0x65, 0x74, 0x12, 0x18,
+	0x2e, 0x62, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x2e, 0x50, 0x6c, 0x61, 0x63, 0x65, 0x42, 0x65,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x62, 0x65, 0x74, 0x74, 0x69,
+	0x6e, 0x67, 0x2e, 0x42, 0x65, 0x74, 0x22, 0x00, 0x12, 0x30, 0x0a, 0x06, 0x47, 0x65, 0x74, 0x42,
+	0x65, 0x74, 0x12, 0x16, 0x2e, 0x62, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x2e, 0x47, 0x65, 0x74,
+	0x42, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x62, 0x65, 0x74,
+	0x74, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x65, 0x74, 0x22, 0x00, 0x12, 0x56, 0x0a, 0x0f, 0x4c, 0x69,
+	0x73, 0x74, 0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1f, 0x2e,
+	0x62, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x65, 0x74, 0x74,
+	0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20,
+	0x2e, 0x62, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x65, 0x74,
+	0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x22, 0x00, 0x12, 0x41, 0x0a, 0x08, 0x51, 0x75, 0x6f, 0x74, 0x65, 0x42, 0x65, 0x74, 0x12, 0x18,
+	0x2e, 0x62, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x2e, 0x51, 0x75, 0x6f, 0x74, 0x65, 0x42, 0x65,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x62, 0x65, 0x74, 0x74, 0x69,
+	0x6e, 0x67, 0x2e, 0x51, 0x75, 0x6f, 0x74, 0x65, 0x42, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x0a, 0x5a, 0x08, 0x2f, 0x62, 0x65, 0x74, 0x74, 0x69, 0x6e,
+	0x67, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_betting_betting_proto_rawDescOnce sync.Once
+	file_betting_betting_proto_rawDescData = file_betting_betting_proto_rawDesc
+)
+
+func file_betting_betting_proto_rawDescGZIP() []byte {
+	file_betting_betting_proto_rawDescOnce.Do(func() {
+		file_betting_betting_proto_rawDescData = protoimpl.X.CompressGZIP(file_betting_betting_proto_rawDescData)
+	})
+	return file_betting_betting_proto_rawDescData
+}
+
+var file_betting_betting_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_betting_betting_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_betting_betting_proto_goTypes = []interface{}{
+	(Bet_Type)(0),                   // 0: betting.Bet.Type
+	(Bet_Status)(0),                 // 1: betting.Bet.Status
+	(Settlement_Type)(0),            // 2: betting.Settlement.Type
+	(*PlaceBetRequest)(nil),         // 3: betting.PlaceBetRequest
+	(*GetBetRequest)(nil),           // 4: betting.GetBetRequest
+	(*ListSettlementsRequest)(nil),  // 5: betting.ListSettlementsRequest
+	(*ListSettlementsResponse)(nil), // 6: betting.ListSettlementsResponse
+	(*QuoteBetRequest)(nil),         // 7: betting.QuoteBetRequest
+	(*QuoteBetResponse)(nil),        // 8: betting.QuoteBetResponse
+	(*Bet)(nil),                     // 9: betting.Bet
+	(*Settlement)(nil),              // 10: betting.Settlement
+	(*Bet_Leg)(nil),                 // 11: betting.Bet.Leg
+	(*timestamp.Timestamp)(nil),     // 12: google.protobuf.Timestamp
+}
+var file_betting_betting_proto_depIdxs = []int32{
+	9,  // 0: betting.PlaceBetRequest.bet:type_name -> betting.Bet
+	10, // 1: betting.ListSettlementsResponse.settlements:type_name -> betting.Settlement
+	9,  // 2: betting.QuoteBetRequest.bet:type_name -> betting.Bet
+	0,  // 3: betting.Bet.type:type_name -> betting.Bet.Type
+	1,  // 4: betting.Bet.status:type_name -> betting.Bet.Status
+	12, // 5: betting.Bet.place_time:type_name -> google.protobuf.Timestamp
+	12, // 6: betting.Bet.settle_time:type_name -> google.protobuf.Timestamp
+	11, // 7: betting.Bet.legs:type_name -> betting.Bet.Leg
+	2,  // 8: betting.Settlement.type:type_name -> betting.Settlement.Type
+	1,  // 9: betting.Settlement.status:type_name -> betting.Bet.Status
+	12, // 10: betting.Settlement.create_time:type_name -> google.protobuf.Timestamp
+	3,  // 11: betting.Betting.PlaceBet:input_type -> betting.PlaceBetRequest
+	4,  // 12: betting.Betting.GetBet:input_type -> betting.GetBetRequest
+	5,  // 13: betting.Betting.ListSettlements:input_type -> betting.ListSettlementsRequest
+	7,  // 14: betting.Betting.QuoteBet:input_type -> betting.QuoteBetRequest
+	9,  // 15: betting.Betting.PlaceBet:output_type -> betting.Bet
+	9,  // 16: betting.Betting.GetBet:output_type -> betting.Bet
+	6,  // 17: betting.Betting.ListSettlements:output_type -> betting.ListSettlementsResponse
+	8,  // 18: betting.Betting.QuoteBet:output_type -> betting.QuoteBetResponse
+	15, // [15:19] is the sub-list for method output_type
+	11, // [11:15] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_betting_betting_proto_init() }
+func file_betting_betting_proto_init() {
+	if File_betting_betting_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_betting_betting_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PlaceBetRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_betting_betting_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetBetRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_betting_betting_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListSettlementsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_betting_betting_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListSettlementsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_betting_betting_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QuoteBetRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_betting_betting_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QuoteBetResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_betting_betting_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Bet); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_betting_betting_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Settlement); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_betting_betting_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Bet_Leg); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_betting_betting_proto_rawDesc,
+			NumEnums:      3,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_betting_betting_proto_goTypes,
+		DependencyIndexes: file_betting_betting_proto_depIdxs,
+		EnumInfos:         file_betting_betting_proto_enumTypes,
+		MessageInfos:      file_betting_betting_proto_msgTypes,
+	}.Build()
+	File_betting_betting_proto = out.File
+	file_betting_betting_proto_rawDesc = nil
+	file_betting_betting_proto_goTypes = nil
+	file_betting_betting_proto_depIdxs = nil
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// callerAccount returns the account a caller places a bet for: their own, identified by their
+// subject. The account a bet names, if any, must be the caller's.
+func callerAccount(ctx context.Context, accountID string) (string, error) {
+	id, ok := auth.FromContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "authentication is required to place bets")
+	}
+
+	if accountID != "" && accountID != id.Subject {
+		return "", status.Errorf(codes.PermissionDenied, "%q may only place bets for their own account", id.Subject)
+	}
+
+	return id.Subject, nil
+}
+
+// requireBetOwner returns an Unauthenticated error for anonymous callers, and a NotFound error
+// for callers other than the account a bet was placed for, so that the bets of other accounts are
+// not disclosed. Services may see any bet.
+func requireBetOwner(ctx context.Context, bet *betting.Bet, name string) error {
+	id, ok := auth.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "authentication is required to access bets")
+	}
+
+	if id.Subject != bet.AccountId && !id.HasRole(auth.RoleService) {
+		return status.Errorf(codes.NotFound, "bet %q not found", name)
+	}
+
+	return nil
+}
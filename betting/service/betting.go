@@ -0,0 +1,399 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"git.neds.sh/matty/entain/betting/db"
+	"git.neds.sh/matty/entain/betting/exotics"
+	"git.neds.sh/matty/entain/betting/funding"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Betting interface {
+	// PlaceBet will place a bet, reserving its total stake from the caller's account.
+	PlaceBet(ctx context.Context, in *betting.PlaceBetRequest) (*betting.Bet, error)
+
+	// GetBet will return a single bet.
+	GetBet(ctx context.Context, in *betting.GetBetRequest) (*betting.Bet, error)
+
+	// ListSettlements will return the settlement ledger of a bet.
+	ListSettlements(ctx context.Context, in *betting.ListSettlementsRequest) (*betting.ListSettlementsResponse, error)
+
+	// QuoteBet will check a bet could be placed, without placing it.
+	QuoteBet(ctx context.Context, in *betting.QuoteBetRequest) (*betting.QuoteBetResponse, error)
+}
+
+// bettingService implements the Betting interface.
+type bettingService struct {
+	betsRepo db.BetsRepo
+	racing   racing.RacingClient
+	funder   *funding.Funder
+}
+
+// NewBettingService instantiates and returns a new bettingService, validating bets against the
+// races served by the given racing client, and funding them from the accounts of the given
+// wallet client.
+func NewBettingService(betsRepo db.BetsRepo, racingClient racing.RacingClient, walletClient wallet.WalletClient) Betting {
+	return &bettingService{betsRepo, racingClient, funding.NewFunder(betsRepo, walletClient)}
+}
+
+func (s *bettingService) PlaceBet(ctx context.Context, in *betting.PlaceBetRequest) (*betting.Bet, error) {
+	if violations := validatePlaceBet(in); len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+
+	accountID, err := callerAccount(ctx, in.Bet.AccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	in.Bet.AccountId = accountID
+
+	// A retried request returns the bet it already placed, without checking the race again: the
+	// race may have closed since the bet was accepted. A bet left PENDING is funded again.
+	if existing, err := s.betsRepo.GetByRequestID(ctx, accountID, in.RequestId); err == nil {
+		if _, err := sameBet(in, existing); err != nil {
+			return nil, err
+		}
+
+		return s.fund(ctx, existing)
+	} else if !errors.Is(err, db.ErrNotFound) {
+		return nil, toStatusError(err)
+	}
+
+	bet, err := s.quote(ctx, in.Bet)
+	if err != nil {
+		return nil, err
+	}
+
+	// The bet is stored before its stake is reserved, so that a wallet failing to answer leaves a
+	// bet to retry rather than funds reserved for no bet.
+	bet.Status = betting.Bet_PENDING
+
+	placed, created, err := s.betsRepo.Place(ctx, in.RequestId, bet)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if !created {
+		// A concurrent retry of the request placed the bet first.
+		if _, err := sameBet(in, placed); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.fund(ctx, placed)
+}
+
+func (s *bettingService) QuoteBet(ctx context.Context, in *betting.QuoteBetRequest) (*betting.QuoteBetResponse, error) {
+	if violations := validateBet(in.Bet); len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+
+	bet, err := s.quote(ctx, in.Bet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &betting.QuoteBetResponse{
+		Combinations: bet.Combinations,
+		TotalStake:   bet.TotalStake,
+		FlexiPercent: exotics.FlexiPercent(bet, bet.Combinations),
+	}, nil
+}
+
+func (s *bettingService) GetBet(ctx context.Context, in *betting.GetBetRequest) (*betting.Bet, error) {
+	return s.getBet(ctx, in.Name)
+}
+
+func (s *bettingService) ListSettlements(ctx context.Context, in *betting.ListSettlementsRequest) (*betting.ListSettlementsResponse, error) {
+	bet, err := s.getBet(ctx, in.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	settlements, err := s.betsRepo.ListSettlements(ctx, bet.Id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "bet %q not found", in.Parent)
+		}
+
+		return nil, toStatusError(err)
+	}
+
+	return &betting.ListSettlementsResponse{Settlements: settlements}, nil
+}
+
+// getBet returns the named bet, provided the caller may see it.
+func (s *bettingService) getBet(ctx context.Context, name string) (*betting.Bet, error) {
+	id, err := parseBetName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	bet, err := s.betsRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "bet %q not found", name)
+		}
+
+		return nil, toStatusError(err)
+	}
+
+	if err := requireBetOwner(ctx, bet, name); err != nil {
+		return nil, err
+	}
+
+	return bet, nil
+}
+
+// quote confirms with the racing service that a validated bet may be placed, returning the bet
+// to place: at the current price of its runner, or with the combinations of its selection.
+func (s *bettingService) quote(ctx context.Context, in *betting.Bet) (*betting.Bet, error) {
+	race, err := s.openRace(ctx, in.RaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	bet := &betting.Bet{
+		AccountId:    in.AccountId,
+		RaceId:       in.RaceId,
+		RunnerId:     in.RunnerId,
+		Type:         in.Type,
+		Stake:        in.Stake,
+		Legs:         in.Legs,
+		Boxed:        in.Boxed,
+		Flexi:        in.Flexi,
+		Combinations: 1,
+		TotalStake:   in.Stake,
+		Status:       betting.Bet_PLACED,
+	}
+
+	if exotics.IsExotic(in.Type) {
+		if err := checkSelections(race, in); err != nil {
+			return nil, err
+		}
+
+		bet.Combinations = exotics.Count(in)
+
+		if bet.TotalStake, err = exotics.Cost(in, bet.Combinations); err != nil {
+			return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: "bet.stake", Description: "must total no more than the largest stake across all combinations"}})
+		}
+
+		return bet, nil
+	}
+
+	current, err := s.currentPrice(ctx, race, in)
+	if err != nil {
+		return nil, err
+	}
+
+	bet.Price = current.Win
+
+	switch in.Type {
+	case betting.Bet_PLACE:
+		bet.Price = current.Place
+	case betting.Bet_EACH_WAY:
+		bet.PlacePrice = current.Place
+		bet.TotalStake = 2 * in.Stake
+	}
+
+	if in.Price != 0 && in.Price != bet.Price {
+		return nil, status.Errorf(codes.FailedPrecondition, "the price of the runner has moved from %v to %v", in.Price, bet.Price)
+	}
+
+	if in.PlacePrice != 0 && in.PlacePrice != bet.PlacePrice {
+		return nil, status.Errorf(codes.FailedPrecondition, "the place price of the runner has moved from %v to %v", in.PlacePrice, bet.PlacePrice)
+	}
+
+	return bet, nil
+}
+
+// openRace fetches a race with its runners from the racing service, confirming it is open for
+// betting.
+func (s *bettingService) openRace(ctx context.Context, id int64) (*racing.Race, error) {
+	name := raceName(id)
+
+	race, err := s.racing.GetRace(ctx, &racing.GetRaceRequest{Name: name, IncludeRunners: true})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: "bet.race_id", Description: "race does not exist"}})
+		}
+
+		return nil, racingError(err)
+	}
+
+	if !race.Visible || race.Status != racing.Race_OPEN {
+		return nil, status.Errorf(codes.FailedPrecondition, "race %q is not open for betting", name)
+	}
+
+	return race, nil
+}
+
+// currentPrice returns the current price of the runner bet on by a single bet.
+func (s *bettingService) currentPrice(ctx context.Context, race *racing.Race, bet *betting.Bet) (*racing.Price, error) {
+	var runner *racing.Runner
+	for _, r := range race.Runners {
+		if r.Id == bet.RunnerId {
+			runner = r
+		}
+	}
+
+	if runner == nil {
+		return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: "bet.runner_id", Description: "runner is not entered in the race"}})
+	}
+
+	if runner.Scratched {
+		return nil, status.Errorf(codes.FailedPrecondition, "runner %d has been scratched", runner.Id)
+	}
+
+	prices, err := s.racing.ListPrices(ctx, &racing.ListPricesRequest{Parent: raceName(race.Id)})
+	if err != nil {
+		return nil, racingError(err)
+	}
+
+	for _, price := range prices.Prices {
+		if price.RunnerId != runner.Id {
+			continue
+		}
+
+		if bet.Type != betting.Bet_WIN && price.Place == 0 {
+			return nil, status.Errorf(codes.FailedPrecondition, "place betting is not offered on runner %d", runner.Id)
+		}
+
+		return price, nil
+	}
+
+	return nil, status.Errorf(codes.FailedPrecondition, "runner %d has not been priced", runner.Id)
+}
+
+// checkSelections confirms the runners selected by an exotic bet are entered in the race, and
+// have not been scratched.
+func checkSelections(race *racing.Race, bet *betting.Bet) error {
+	runners := make(map[int64]*racing.Runner, len(race.Runners))
+	for _, runner := range race.Runners {
+		runners[runner.SaddleNumber] = runner
+	}
+
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	for i, leg := range bet.Legs {
+		for _, number := range leg.RunnerNumbers {
+			if _, ok := runners[number]; !ok {
+				violations = append(violations, &errdetails.BadRequest_FieldViolation{
+					Field:       fmt.Sprintf("bet.legs[%d].runner_numbers", i),
+					Description: fmt.Sprintf("runner number %d is not entered in the race", number),
+				})
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return invalidArgument(violations)
+	}
+
+	for _, leg := range bet.Legs {
+		for _, number := range leg.RunnerNumbers {
+			if runners[number].Scratched {
+				return status.Errorf(codes.FailedPrecondition, "runner number %d has been scratched", number)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sameBet returns the bet already placed by a request, provided the request is a retry of the
+// one that placed it.
+func sameBet(in *betting.PlaceBetRequest, existing *betting.Bet) (*betting.Bet, error) {
+	if in.Bet.AccountId != existing.AccountId ||
+		in.Bet.RaceId != existing.RaceId ||
+		in.Bet.RunnerId != existing.RunnerId ||
+		in.Bet.Type != existing.Type ||
+		in.Bet.Stake != existing.Stake ||
+		in.Bet.Boxed != existing.Boxed ||
+		in.Bet.Flexi != existing.Flexi ||
+		!sameLegs(in.Bet.Legs, existing.Legs) {
+		return nil, status.Errorf(codes.AlreadyExists, "a different bet has already been placed with request_id %q", in.RequestId)
+	}
+
+	return existing, nil
+}
+
+// sameLegs reports whether two exotic bets select the same runners.
+func sameLegs(a, b []*betting.Bet_Leg) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if len(a[i].RunnerNumbers) != len(b[i].RunnerNumbers) {
+			return false
+		}
+
+		for j := range a[i].RunnerNumbers {
+			if a[i].RunnerNumbers[j] != b[i].RunnerNumbers[j] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// fund reserves the total stake of a bet left PENDING from its account, placing it, and maps
+// the errors doing so onto those returned to the caller.
+func (s *bettingService) fund(ctx context.Context, bet *betting.Bet) (*betting.Bet, error) {
+	funded, err := s.funder.Fund(ctx, bet)
+
+	var refused *funding.RefusedError
+	if errors.As(err, &refused) {
+		return nil, walletError(refused.Err, bet.AccountId)
+	}
+
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return funded, nil
+}
+
+// toStatusError maps repository errors onto the gRPC status returned to the caller. A busy
+// database is Unavailable, so that callers retry.
+func toStatusError(err error) error {
+	if db.IsBusy(err) {
+		return status.Error(codes.Unavailable, "the betting database is busy, retry the call")
+	}
+
+	return err
+}
+
+// walletError maps a refused reservation onto the error returned to the caller.
+func walletError(err error, accountID string) error {
+	switch status.Code(err) {
+	case codes.FailedPrecondition:
+		return status.Errorf(codes.FailedPrecondition, "funds could not be reserved for the bet: %s", status.Convert(err).Message())
+	case codes.NotFound:
+		return status.Errorf(codes.FailedPrecondition, "account %q must be opened before placing bets", accountID)
+	}
+
+	return status.Errorf(codes.Internal, "reserving funds with wallet service: %s", status.Convert(err).Message())
+}
+
+// racingError maps a failed call to the racing service onto the error returned to the caller.
+// Transient failures are passed through so that the caller may retry; anything else is internal.
+func racingError(err error) error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return err
+	}
+
+	return status.Errorf(codes.Internal, "checking race with racing service: %s", status.Convert(err).Message())
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/betting/db"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+)
+
+// newBetsRepo returns a bets repository over a fresh database.
+func newBetsRepo(t *testing.T) db.BetsRepo {
+	t.Helper()
+
+	bettingDB, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "betting.db")+"?_txlock=immediate&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bettingDB.Close() })
+
+	repo := db.NewBetsRepo(bettingDB)
+	if err := repo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+// callerContext returns a context for a call made by the subject with the roles.
+func callerContext(subject string, roles ...string) context.Context {
+	return auth.NewContext(context.Background(), &auth.Identity{Subject: subject, Roles: roles})
+}
+
+func TestGetBetOwnership(t *testing.T) {
+	ctx := context.Background()
+
+	repo := newBetsRepo(t)
+
+	if _, _, err := repo.Place(ctx, "1", &betting.Bet{AccountId: "alice", RaceId: 1, RunnerId: 1, Type: betting.Bet_WIN, Stake: 100, Price: 2.5, Status: betting.Bet_PLACED, Combinations: 1, TotalStake: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewBettingService(repo, nil, nil)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		bet  string
+		code codes.Code
+	}{
+		{name: "owner", ctx: callerContext("alice"), bet: "bets/1"},
+		{name: "service", ctx: callerContext("betting", auth.RoleService), bet: "bets/1"},
+		{name: "another account", ctx: callerContext("bob"), bet: "bets/1", code: codes.NotFound},
+		{name: "trader", ctx: callerContext("bob", auth.RoleTrader), bet: "bets/1", code: codes.NotFound},
+		{name: "anonymous", ctx: ctx, bet: "bets/1", code: codes.Unauthenticated},
+		{name: "unknown bet", ctx: callerContext("alice"), bet: "bets/2", code: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.GetBet(tt.ctx, &betting.GetBetRequest{Name: tt.bet}); status.Code(err) != tt.code {
+				t.Errorf("GetBet() error = %v, want %s", err, tt.code)
+			}
+
+			if _, err := s.ListSettlements(tt.ctx, &betting.ListSettlementsRequest{Parent: tt.bet}); status.Code(err) != tt.code {
+				t.Errorf("ListSettlements() error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
+
+// walletStub answers reservations with err, or reserves the funds should it be nil. Methods other
+// than those the service calls are left unimplemented.
+type walletStub struct {
+	wallet.WalletClient
+	err error
+}
+
+func (w *walletStub) ReserveFunds(ctx context.Context, in *wallet.ReserveFundsRequest, _ ...grpc.CallOption) (*wallet.Transaction, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	return &wallet.Transaction{AccountId: "alice", Type: wallet.Transaction_RESERVATION, Amount: in.Amount, Reference: in.Reference}, nil
+}
+
+func TestPlaceBetPending(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+		// status is that of the bet once the request is retried; deleted bets have none.
+		status betting.Bet_Status
+	}{
+		{name: "funded", status: betting.Bet_PLACED},
+		{name: "wallet unavailable", err: status.Error(codes.Unavailable, "down"), code: codes.Unavailable, status: betting.Bet_PENDING},
+		{name: "wallet timed out", err: status.Error(codes.DeadlineExceeded, "slow"), code: codes.DeadlineExceeded, status: betting.Bet_PENDING},
+		{name: "insufficient funds", err: status.Error(codes.FailedPrecondition, "insufficient funds"), code: codes.FailedPrecondition},
+		{name: "unknown account", err: status.Error(codes.NotFound, "no account"), code: codes.FailedPrecondition},
+		{name: "wallet failed", err: status.Error(codes.Internal, "broken"), code: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newBetsRepo(t)
+
+			// The bet was left pending by an earlier attempt of the request.
+			bet := &betting.Bet{AccountId: "alice", RaceId: 1, RunnerId: 1, Type: betting.Bet_WIN, Stake: 100, Price: 2.5, Status: betting.Bet_PENDING, Combinations: 1, TotalStake: 100}
+			if _, _, err := repo.Place(ctx, "1", bet); err != nil {
+				t.Fatal(err)
+			}
+
+			s := NewBettingService(repo, nil, &walletStub{err: tt.err})
+
+			placed, err := s.PlaceBet(callerContext("alice"), &betting.PlaceBetRequest{RequestId: "1", Bet: &betting.Bet{RaceId: 1, RunnerId: 1, Type: betting.Bet_WIN, Stake: 100}})
+			if status.Code(err) != tt.code {
+				t.Fatalf("PlaceBet() error = %v, want %s", err, tt.code)
+			}
+
+			if err == nil && placed.Status != tt.status {
+				t.Errorf("PlaceBet() status = %s, want %s", placed.Status, tt.status)
+			}
+
+			stored, err := repo.Get(ctx, 1)
+			switch {
+			case tt.status == betting.Bet_STATUS_UNSPECIFIED && !errors.Is(err, db.ErrNotFound):
+				t.Errorf("Get() error = %v, want the refused bet deleted", err)
+			case tt.status != betting.Bet_STATUS_UNSPECIFIED && (err != nil || stored.Status != tt.status):
+				t.Errorf("Get() = %v, %v; want the bet %s", stored, err, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// betsCollection is the collection ID of bet resource names.
+const betsCollection = "bets"
+
+// parseBetName extracts the bet ID from a resource name of the form bets/{id}.
+func parseBetName(name string) (int64, error) {
+	prefix := betsCollection + "/"
+
+	if !strings.HasPrefix(name, prefix) {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid name %q: must be of the form %s{id}", name, prefix)
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(name, prefix), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid name %q: id must be a positive integer", name)
+	}
+
+	return id, nil
+}
+
+// raceName builds the resource name of a race in the racing service.
+func raceName(id int64) string {
+	return "races/" + strconv.FormatInt(id, 10)
+}
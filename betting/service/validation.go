@@ -0,0 +1,212 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/betting/exotics"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+)
+
+const (
+	// maxRequestIDLength is the longest request_id accepted.
+	maxRequestIDLength = 128
+	// maxLegRunners is the most runners a leg of an exotic bet may select.
+	maxLegRunners = 24
+	// maxCombinations is the most combinations an exotic bet may be made up of.
+	maxCombinations = 10000
+	// minFlexiPercent is the smallest percentage of the dividend a flexi bet may be paid.
+	minFlexiPercent = 1
+	// maxStake is the largest total stake of a bet, in cents, matching the largest amount the
+	// wallet may reserve for it.
+	maxStake = 1000000000000
+)
+
+// validatePlaceBet checks the fields of a PlaceBet request, returning a violation for each
+// invalid field.
+func validatePlaceBet(in *betting.PlaceBetRequest) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	violate := func(field, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: description,
+		})
+	}
+
+	switch {
+	case strings.TrimSpace(in.RequestId) == "":
+		violate("request_id", "must be set, so that the placement can be safely retried")
+	case len(in.RequestId) > maxRequestIDLength:
+		violate("request_id", "must be no longer than 128 characters")
+	}
+
+	return append(violations, validateBet(in.Bet)...)
+}
+
+// validateBet checks the fields of a bet to place, returning a violation for each invalid field.
+func validateBet(bet *betting.Bet) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	violate := func(field, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: description,
+		})
+	}
+
+	if bet == nil {
+		violate("bet", "must be set")
+		return violations
+	}
+
+	if bet.RaceId <= 0 {
+		violate("bet.race_id", "must be a positive race id")
+	}
+
+	if bet.Stake <= 0 || bet.Stake > maxStake {
+		violate("bet.stake", fmt.Sprintf("must be a positive amount of cents, no more than %d", maxStake))
+	}
+
+	if _, ok := betting.Bet_Type_name[int32(bet.Type)]; !ok || bet.Type == betting.Bet_TYPE_UNSPECIFIED {
+		violate("bet.type", "must be WIN, PLACE, EACH_WAY, QUINELLA, EXACTA, TRIFECTA or FIRST_FOUR")
+		return violations
+	}
+
+	if !exotics.IsExotic(bet.Type) {
+		if bet.RunnerId <= 0 {
+			violate("bet.runner_id", "must be a positive runner id")
+		}
+
+		if bet.Price < 0 {
+			violate("bet.price", "must not be negative")
+		}
+
+		if bet.Type == betting.Bet_EACH_WAY && bet.Stake > maxStake/2 {
+			violate("bet.stake", fmt.Sprintf("must total no more than %d cents across the win and place bets", maxStake))
+		}
+
+		if bet.PlacePrice < 0 {
+			violate("bet.place_price", "must not be negative")
+		} else if bet.PlacePrice > 0 && bet.Type != betting.Bet_EACH_WAY {
+			violate("bet.place_price", "may only be set for EACH_WAY bets")
+		}
+
+		if len(bet.Legs) > 0 {
+			violate("bet.legs", "may only be set for exotic bets")
+		}
+
+		if bet.Boxed {
+			violate("bet.boxed", "may only be set for exotic bets")
+		}
+
+		if bet.Flexi {
+			violate("bet.flexi", "may only be set for exotic bets")
+		}
+
+		return violations
+	}
+
+	if bet.RunnerId != 0 {
+		violate("bet.runner_id", "must not be set for exotic bets, which select runners by their legs")
+	}
+
+	if bet.Price != 0 {
+		violate("bet.price", "must not be set for exotic bets, which pay tote dividends")
+	}
+
+	if bet.PlacePrice != 0 {
+		violate("bet.place_price", "may only be set for EACH_WAY bets")
+	}
+
+	return append(violations, validateLegs(bet)...)
+}
+
+// validateLegs checks the selection of an exotic bet, returning a violation for each invalid
+// field.
+func validateLegs(bet *betting.Bet) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	violate := func(field, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: description,
+		})
+	}
+
+	def := exotics.Definitions[bet.Type]
+
+	switch {
+	case bet.Boxed && len(bet.Legs) != 1:
+		violate("bet.legs", "must hold a single leg for boxed bets")
+	case bet.Boxed && len(bet.Legs[0].RunnerNumbers) < def.Positions:
+		violate("bet.legs[0].runner_numbers", fmt.Sprintf("must select at least %d runners for a boxed %s", def.Positions, bet.Type))
+	case !bet.Boxed && len(bet.Legs) != def.Positions:
+		violate("bet.legs", fmt.Sprintf("must hold %d legs for a %s, one for each position", def.Positions, bet.Type))
+	}
+
+	for i, leg := range bet.Legs {
+		field := fmt.Sprintf("bet.legs[%d].runner_numbers", i)
+
+		if len(leg.RunnerNumbers) == 0 {
+			violate(field, "must select at least one runner")
+		}
+
+		if len(leg.RunnerNumbers) > maxLegRunners {
+			violate(field, fmt.Sprintf("must select no more than %d runners", maxLegRunners))
+			continue
+		}
+
+		seen := make(map[int64]bool, len(leg.RunnerNumbers))
+		for _, number := range leg.RunnerNumbers {
+			if number <= 0 {
+				violate(field, "must hold positive saddle numbers")
+			} else if seen[number] {
+				violate(field, fmt.Sprintf("must not select runner number %d more than once", number))
+			}
+
+			seen[number] = true
+		}
+	}
+
+	if len(violations) > 0 {
+		return violations
+	}
+
+	// Combinations are counted rather than listed, as a bet may cover far too many to list.
+	combinations := exotics.Count(bet)
+
+	switch {
+	case combinations == 0:
+		violate("bet.legs", "must select distinct runners for each position")
+	case combinations > maxCombinations:
+		violate("bet.legs", fmt.Sprintf("must make up no more than %d combinations, not %d", maxCombinations, combinations))
+	case bet.Stake > 0 && !bet.Flexi && bet.Stake > maxStake/combinations:
+		violate("bet.stake", fmt.Sprintf("must total no more than %d cents across the %d combinations", maxStake, combinations))
+	case bet.Stake > 0 && bet.Flexi && exotics.FlexiPercent(bet, combinations) < minFlexiPercent:
+		violate("bet.stake", fmt.Sprintf("must be at least %d%% of a $1 unit for each of the %d combinations", minFlexiPercent, combinations))
+	}
+
+	return violations
+}
+
+// invalidArgument builds an InvalidArgument status carrying field-level details of the violations.
+func invalidArgument(violations []*errdetails.BadRequest_FieldViolation) error {
+	descriptions := make([]string, len(violations))
+	for i, v := range violations {
+		descriptions[i] = v.Field + " " + v.Description
+	}
+
+	st := status.New(codes.InvalidArgument, "invalid request: "+strings.Join(descriptions, "; "))
+
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
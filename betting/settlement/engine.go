@@ -0,0 +1,353 @@
+// Package settlement settles bets as the results of their races are declared.
+package settlement
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/betting/db"
+	"git.neds.sh/matty/entain/betting/funding"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	"git.neds.sh/matty/entain/logging"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+)
+
+const (
+	// minBackoff is how long the engine waits before reconnecting to the racing service after
+	// its watch first fails.
+	minBackoff = time.Second
+	// maxBackoff caps the wait between reconnections while the racing service is unavailable.
+	maxBackoff = 30 * time.Second
+)
+
+// Engine watches races in the racing service, and settles the bets placed on them as their
+// status changes:
+//
+//   - bets on FINAL races are settled on the race's result;
+//   - bets on ABANDONED races are refunded;
+//   - bets on races in any other status are unsettled, e.g. when a FINAL race is moved back to
+//     INTERIM following a protest, so that they settle again on the corrected result.
+//
+// Settling is idempotent: a bet is only settled again if its outcome has changed, in which case
+// its previous settlement is reversed in its ledger.
+//
+// The funds reserved for a bet in the wallet move with it: they are settled, paying its payout,
+// when it is WON or LOST, and released when it is REFUNDED. Should its outcome change, the
+// previous settlement or release is reversed first. A bet whose funds cannot be moved, e.g. as
+// its account no longer holds a payout to be taken back, keeps its previous settlement until they
+// can: the bets of races that fail to settle are retried from a fresh watch. Bets still PENDING
+// are funded before they settle, and deleted should the wallet refuse their stake.
+type Engine struct {
+	betsRepo db.BetsRepo
+	racing   racing.RacingClient
+	wallet   wallet.WalletClient
+	funder   *funding.Funder
+}
+
+// NewEngine creates a new settlement engine, settling the bets in the given repository on races
+// served by the given racing client, and their funds in the given wallet client.
+func NewEngine(betsRepo db.BetsRepo, racingClient racing.RacingClient, walletClient wallet.WalletClient) *Engine {
+	return &Engine{betsRepo, racingClient, walletClient, funding.NewFunder(betsRepo, walletClient)}
+}
+
+// Run settles bets until the context is done, reconnecting to the racing service whenever its
+// watch fails.
+func (e *Engine) Run(ctx context.Context) {
+	backoff := minBackoff
+
+	for {
+		synced, err := e.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if synced {
+			backoff = minBackoff
+		}
+
+		logging.FromContext(ctx).WithError(err).Warnf("settlement: watching races failed, retrying in %s", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// watch settles bets on the races streamed by a single watch, returning when the watch fails.
+// It reports whether the watch got as far as its initial snapshot.
+func (e *Engine) watch(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := e.racing.WatchRaces(ctx, &racing.WatchRacesRequest{
+		Filter: &racing.ListRacesRequestFilter{
+			Statuses: []racing.Race_Status{racing.Race_FINAL, racing.Race_ABANDONED},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		synced   bool
+		snapshot = make(map[int64]bool)
+		// failed is the first error settling the bets of a race in the snapshot, which does not
+		// hold up the races after it, but fails the watch once synced so that they are retried.
+		failed error
+	)
+
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return synced, status.Error(codes.Unavailable, "watch ended")
+		}
+
+		if err != nil {
+			return synced, err
+		}
+
+		switch event.Type {
+		case racing.WatchRacesResponse_RESYNC:
+			snapshot = make(map[int64]bool)
+
+		case racing.WatchRacesResponse_SYNCED:
+			// Races that left FINAL or ABANDONED while the engine was not watching are not part of
+			// the snapshot, yet may still have settled bets.
+			if err := e.reconcileMissing(ctx, snapshot); err != nil && failed == nil {
+				failed = err
+			}
+
+			if failed != nil {
+				return false, failed
+			}
+
+			synced = true
+
+		case racing.WatchRacesResponse_ADDED, racing.WatchRacesResponse_MODIFIED, racing.WatchRacesResponse_REMOVED:
+			if !synced {
+				snapshot[event.Race.Id] = true
+			}
+
+			if err := e.reconcile(ctx, event.Race); err != nil {
+				if synced {
+					return synced, err
+				}
+
+				if failed == nil {
+					failed = err
+				}
+			}
+		}
+	}
+}
+
+// reconcileMissing reconciles the races with settled bets that are not in a snapshot, returning
+// the first error reconciling any of them.
+func (e *Engine) reconcileMissing(ctx context.Context, snapshot map[int64]bool) error {
+	ids, err := e.betsRepo.ListSettledRaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed error
+
+	for _, id := range ids {
+		if snapshot[id] {
+			continue
+		}
+
+		race, err := e.racing.GetRace(ctx, &racing.GetRaceRequest{Name: raceName(id)})
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := e.reconcile(ctx, race); err != nil && failed == nil {
+			failed = err
+		}
+	}
+
+	return failed
+}
+
+// reconcile settles the bets on a race according to its current status. A bet that fails to
+// settle does not hold up the others; the first error is returned once all have been tried.
+func (e *Engine) reconcile(ctx context.Context, race *racing.Race) error {
+	// Deleted races are streamed with only their ID; their bets are left as they are.
+	if race.Status == racing.Race_STATUS_UNSPECIFIED {
+		return nil
+	}
+
+	bets, err := e.betsRepo.ListByRace(ctx, race.Id)
+	if err != nil || len(bets) == 0 {
+		return err
+	}
+
+	var outcome func(bet *betting.Bet) Outcome
+
+	switch race.Status {
+	case racing.Race_FINAL:
+		result, err := e.racing.GetRaceResult(ctx, &racing.GetRaceResultRequest{Name: raceName(race.Id) + "/result"})
+		if err != nil {
+			return err
+		}
+
+		withRunners, err := e.racing.GetRace(ctx, &racing.GetRaceRequest{Name: raceName(race.Id), IncludeRunners: true})
+		if err != nil {
+			return err
+		}
+
+		outcome = func(bet *betting.Bet) Outcome {
+			return Settle(bet, result, withRunners.Runners)
+		}
+
+	case racing.Race_ABANDONED:
+		outcome = Refund
+
+	default:
+		outcome = func(*betting.Bet) Outcome {
+			return Outcome{Status: betting.Bet_PLACED}
+		}
+	}
+
+	var failed error
+
+	for _, bet := range bets {
+		logger := logging.FromContext(ctx).WithFields(logrus.Fields{
+			"bet_id":  bet.Id,
+			"race_id": race.Id,
+		})
+
+		funded, err := e.funder.Fund(ctx, bet)
+
+		var refused *funding.RefusedError
+		if errors.As(err, &refused) {
+			logger.WithError(refused.Err).Info("settlement: pending bet refused by the wallet and deleted")
+			continue
+		}
+
+		if err != nil {
+			logger.WithError(err).Warn("settlement: funding pending bet failed")
+
+			if failed == nil {
+				failed = fmt.Errorf("funding bet %d of race %d: %w", bet.Id, race.Id, err)
+			}
+
+			continue
+		}
+
+		bet = funded
+
+		want := outcome(bet)
+		if bet.Status == want.Status && bet.Payout == want.Payout {
+			continue
+		}
+
+		logger = logger.WithFields(logrus.Fields{
+			"from":   bet.Status.String(),
+			"to":     want.Status.String(),
+			"payout": want.Payout,
+		})
+
+		if err := e.settle(ctx, bet, want); err != nil {
+			logger.WithError(err).Warn("settlement: settling bet failed")
+
+			if failed == nil {
+				failed = fmt.Errorf("settling bet %d of race %d: %w", bet.Id, race.Id, err)
+			}
+
+			continue
+		}
+
+		logger.Info("settlement: bet settled")
+	}
+
+	return failed
+}
+
+// settle moves the funds of a bet, then the bet itself, to its outcome. The funds are moved
+// first, so that a bet is settled again should moving them fail.
+func (e *Engine) settle(ctx context.Context, bet *betting.Bet, want Outcome) error {
+	if err := e.moveFunds(ctx, bet, want); err != nil {
+		return err
+	}
+
+	_, err := e.betsRepo.Settle(ctx, bet.Id, want.Status, want.Payout)
+
+	return err
+}
+
+// moveFunds moves the funds reserved for a bet from its current settlement to its outcome: the
+// release or settlement of its funds for the current settlement, if any, is reversed, then its
+// funds are settled or released for the outcome. The wallet transactions made for each settlement
+// of a bet have request IDs of their own, so that a retry returns those already made, while the
+// settlement of a corrected result makes new ones.
+func (e *Engine) moveFunds(ctx context.Context, bet *betting.Bet, want Outcome) error {
+	settlements, err := e.betsRepo.ListSettlements(ctx, bet.Id)
+	if err != nil {
+		return err
+	}
+
+	// Settlements are numbered from 1 in the order they were made; the outcome is the next.
+	var current int
+	for _, settlement := range settlements {
+		if settlement.Type == betting.Settlement_SETTLEMENT {
+			current++
+		}
+	}
+
+	var (
+		name    = betName(bet.Id)
+		account = "accounts/" + bet.AccountId
+	)
+
+	if bet.Status != betting.Bet_PLACED {
+		if _, err := e.wallet.ReverseFunds(ctx, &wallet.ReverseFundsRequest{Name: account, RequestId: settlementRequestID(bet.Id, current) + "/reversal", Reference: name}); err != nil {
+			return err
+		}
+	}
+
+	requestID := settlementRequestID(bet.Id, current+1)
+
+	switch want.Status {
+	case betting.Bet_WON, betting.Bet_LOST:
+		_, err = e.wallet.SettleFunds(ctx, &wallet.SettleFundsRequest{Name: account, RequestId: requestID, Reference: name, Payout: want.Payout})
+	case betting.Bet_REFUNDED:
+		_, err = e.wallet.ReleaseFunds(ctx, &wallet.ReleaseFundsRequest{Name: account, RequestId: requestID, Reference: name})
+	}
+
+	return err
+}
+
+// settlementRequestID identifies the wallet transactions made for a settlement of a bet.
+func settlementRequestID(id int64, settlement int) string {
+	return betName(id) + "/settlements/" + strconv.Itoa(settlement)
+}
+
+// betName builds the resource name of a bet, which references the funds reserved for it.
+func betName(id int64) string {
+	return "bets/" + strconv.FormatInt(id, 10)
+}
+
+// raceName builds the resource name of a race in the racing service.
+func raceName(id int64) string {
+	return "races/" + strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,265 @@
+package settlement
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/betting/db"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	walletdb "git.neds.sh/matty/entain/wallet/db"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+	walletservice "git.neds.sh/matty/entain/wallet/service"
+)
+
+// walletClient calls a wallet service in process, as the betting service. Methods other than
+// those the engine calls are left unimplemented.
+type walletClient struct {
+	wallet.WalletClient
+	service walletservice.Wallet
+}
+
+// serviceContext returns a context for a call made by the betting service.
+func serviceContext(ctx context.Context) context.Context {
+	return auth.NewContext(ctx, &auth.Identity{Subject: "betting", Roles: []string{auth.RoleService}})
+}
+
+func (c *walletClient) ReserveFunds(ctx context.Context, in *wallet.ReserveFundsRequest, _ ...grpc.CallOption) (*wallet.Transaction, error) {
+	return c.service.ReserveFunds(serviceContext(ctx), in)
+}
+
+func (c *walletClient) ReleaseFunds(ctx context.Context, in *wallet.ReleaseFundsRequest, _ ...grpc.CallOption) (*wallet.Transaction, error) {
+	return c.service.ReleaseFunds(serviceContext(ctx), in)
+}
+
+func (c *walletClient) SettleFunds(ctx context.Context, in *wallet.SettleFundsRequest, _ ...grpc.CallOption) (*wallet.Transaction, error) {
+	return c.service.SettleFunds(serviceContext(ctx), in)
+}
+
+func (c *walletClient) ReverseFunds(ctx context.Context, in *wallet.ReverseFundsRequest, _ ...grpc.CallOption) (*wallet.Transaction, error) {
+	return c.service.ReverseFunds(serviceContext(ctx), in)
+}
+
+// racingStub serves the result of race 1, won by the runner with ID winner, and its field of
+// eight runners. Methods other than those the engine calls are left unimplemented.
+type racingStub struct {
+	racing.RacingClient
+	winner int64
+}
+
+func (r *racingStub) GetRaceResult(ctx context.Context, in *racing.GetRaceResultRequest, _ ...grpc.CallOption) (*racing.Result, error) {
+	return &racing.Result{RaceId: 1, Placings: []*racing.Placing{{RunnerId: r.winner, Position: 1}}}, nil
+}
+
+func (r *racingStub) GetRace(ctx context.Context, in *racing.GetRaceRequest, _ ...grpc.CallOption) (*racing.Race, error) {
+	runners := make([]*racing.Runner, 8)
+	for i := range runners {
+		runners[i] = &racing.Runner{Id: int64(i+1) * 10, SaddleNumber: int64(i + 1)}
+	}
+
+	return &racing.Race{Id: 1, Status: racing.Race_FINAL, Runners: runners}, nil
+}
+
+// fixture is a settlement engine over fresh betting and wallet databases, holding a win bet of
+// 100 cents at $3.50 on runner 10 of race 1, placed by alice from the 1000 cents she deposited.
+type fixture struct {
+	engine   *Engine
+	racing   *racingStub
+	wallet   walletservice.Wallet
+	betsRepo db.BetsRepo
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	open := func(name string) *sql.DB {
+		conn, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), name)+"?_txlock=immediate&_busy_timeout=5000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		return conn
+	}
+
+	betsRepo := db.NewBetsRepo(open("betting.db"))
+	if err := betsRepo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	accountsRepo := walletdb.NewAccountsRepo(open("wallet.db"))
+	if err := accountsRepo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fixture{racing: &racingStub{}, wallet: walletservice.NewWalletService(accountsRepo), betsRepo: betsRepo}
+	f.engine = NewEngine(betsRepo, f.racing, &walletClient{service: f.wallet})
+
+	ctx := serviceContext(context.Background())
+
+	if _, err := accountsRepo.Create("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.wallet.Deposit(ctx, &wallet.DepositRequest{Name: "accounts/alice", RequestId: "deposit", Amount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	bet, _, err := betsRepo.Place(context.Background(), "1", &betting.Bet{AccountId: "alice", RaceId: 1, RunnerId: 10, Type: betting.Bet_WIN, Stake: 100, Price: 3.5, Status: betting.Bet_PLACED, Combinations: 1, TotalStake: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.wallet.ReserveFunds(ctx, &wallet.ReserveFundsRequest{Name: "accounts/alice", RequestId: betName(bet.Id), Reference: betName(bet.Id), Amount: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+// reconcile reconciles race 1 in a status, won by the runner with ID winner when FINAL.
+func (f *fixture) reconcile(status racing.Race_Status, winner int64) error {
+	f.racing.winner = winner
+
+	return f.engine.reconcile(context.Background(), &racing.Race{Id: 1, Status: status})
+}
+
+// check compares the bet and alice's balances with those wanted.
+func (f *fixture) check(t *testing.T, step int, want betting.Bet_Status, payout, available, reserved int64) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	bet, err := f.betsRepo.Get(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bet.Status != want || bet.Payout != payout {
+		t.Errorf("step %d: bet = %s paying %d, want %s paying %d", step, bet.Status, bet.Payout, want, payout)
+	}
+
+	account, err := f.wallet.GetAccount(serviceContext(ctx), &wallet.GetAccountRequest{Name: "accounts/alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if account.Available != available || account.Reserved != reserved {
+		t.Errorf("step %d: account holds %d available, %d reserved; want %d available, %d reserved", step, account.Available, account.Reserved, available, reserved)
+	}
+}
+
+func TestReconcileCorrections(t *testing.T) {
+	type step struct {
+		status racing.Race_Status
+		winner int64
+		// want is the bet once reconciled, and alice's balances.
+		want                        betting.Bet_Status
+		payout, available, reserved int64
+	}
+
+	var (
+		won      = step{status: racing.Race_FINAL, winner: 10, want: betting.Bet_WON, payout: 350, available: 1250}
+		lost     = step{status: racing.Race_FINAL, winner: 20, want: betting.Bet_LOST, available: 900}
+		refunded = step{status: racing.Race_ABANDONED, want: betting.Bet_REFUNDED, payout: 100, available: 1000}
+		interim  = step{status: racing.Race_INTERIM, want: betting.Bet_PLACED, available: 900, reserved: 100}
+	)
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{name: "won corrected to lost", steps: []step{won, lost}},
+		{name: "lost corrected to won", steps: []step{lost, won}},
+		{name: "corrected back and forth", steps: []step{won, lost, won, lost}},
+		{name: "won, protested, then lost", steps: []step{won, interim, lost}},
+		{name: "refunded then won", steps: []step{refunded, won}},
+		{name: "won then refunded", steps: []step{won, refunded}},
+		{name: "settled again unchanged", steps: []step{won, won}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+
+			for i, s := range tt.steps {
+				if err := f.reconcile(s.status, s.winner); err != nil {
+					t.Fatalf("step %d: reconcile() error = %v", i, err)
+				}
+
+				f.check(t, i, s.want, s.payout, s.available, s.reserved)
+			}
+		})
+	}
+}
+
+func TestReconcileCorrectionUnfunded(t *testing.T) {
+	f := newFixture(t)
+	ctx := serviceContext(context.Background())
+
+	if err := f.reconcile(racing.Race_FINAL, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Alice withdraws her winnings before the result is corrected, so they cannot be taken back.
+	if _, err := f.wallet.Withdraw(auth.NewContext(context.Background(), &auth.Identity{Subject: "alice"}), &wallet.WithdrawRequest{Name: "accounts/alice", RequestId: "withdraw", Amount: 1200}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.reconcile(racing.Race_FINAL, 20); err == nil {
+		t.Fatal("reconcile() error = nil, want the correction to fail")
+	}
+
+	f.check(t, 1, betting.Bet_WON, 350, 50, 0)
+
+	// The correction is retried once the account holds the funds.
+	if _, err := f.wallet.Deposit(ctx, &wallet.DepositRequest{Name: "accounts/alice", RequestId: "deposit again", Amount: 300}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.reconcile(racing.Race_FINAL, 20); err != nil {
+		t.Fatalf("reconcile() retried error = %v", err)
+	}
+
+	f.check(t, 2, betting.Bet_LOST, 0, 0, 0)
+}
+
+func TestReconcilePendingBets(t *testing.T) {
+	ctx := context.Background()
+
+	f := newFixture(t)
+
+	place := func(requestID string, stake int64) *betting.Bet {
+		bet, _, err := f.betsRepo.Place(ctx, requestID, &betting.Bet{AccountId: "alice", RaceId: 1, RunnerId: 10, Type: betting.Bet_WIN, Stake: stake, Price: 3.5, Status: betting.Bet_PENDING, Combinations: 1, TotalStake: stake})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return bet
+	}
+
+	var (
+		funded  = place("2", 200)
+		refused = place("3", 5000)
+	)
+
+	if err := f.reconcile(racing.Race_FINAL, 10); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+
+	if bet, err := f.betsRepo.Get(ctx, funded.Id); err != nil || bet.Status != betting.Bet_WON || bet.Payout != 700 {
+		t.Errorf("Get() pending bet = %v, %v; want it funded and WON paying 700", bet, err)
+	}
+
+	if _, err := f.betsRepo.Get(ctx, refused.Id); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Get() unfunded bet error = %v, want it deleted", err)
+	}
+
+	f.check(t, 0, betting.Bet_WON, 350, 1750, 0)
+}
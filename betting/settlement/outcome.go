@@ -0,0 +1,170 @@
+package settlement
+
+import (
+	"math"
+
+	"git.neds.sh/matty/entain/betting/exotics"
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/tote"
+)
+
+// Outcome is how a bet settles.
+type Outcome struct {
+	Status betting.Bet_Status
+	// Payout is the amount returned, in cents, including any refunded stake.
+	Payout int64
+}
+
+// Refund is the outcome of a bet whose stake is returned in full, e.g. as its race was abandoned.
+func Refund(bet *betting.Bet) Outcome {
+	return Outcome{Status: betting.Bet_REFUNDED, Payout: bet.TotalStake}
+}
+
+// Settle works out how a bet settles on the final result of its race, given the runners of the
+// race.
+//
+// A bet on a scratched runner is refunded. Win bets are paid on runners placed first, and place
+// bets on runners placed within the places paid, which depend on the number of starters. A bet
+// on a runner that dead heats is paid on its stake divided by the number of runners sharing the
+// position, reduced further where the dead heat spans the last place paid. Each-way bets settle
+// as a win bet and a place bet, each of the bet's stake. Exotic bets are paid the dividends
+// declared on the combinations they cover.
+func Settle(bet *betting.Bet, result *racing.Result, runners []*racing.Runner) Outcome {
+	if exotics.IsExotic(bet.Type) {
+		return settleExotic(bet, result, runners)
+	}
+
+	starters := 0
+	for _, runner := range runners {
+		if runner.Scratched {
+			if runner.Id == bet.RunnerId {
+				return Refund(bet)
+			}
+
+			continue
+		}
+
+		starters++
+	}
+
+	var (
+		position int64
+		tied     int64
+	)
+
+	for _, placing := range result.Placings {
+		if placing.RunnerId == bet.RunnerId {
+			position = placing.Position
+		}
+	}
+
+	if position > 0 {
+		for _, placing := range result.Placings {
+			if placing.Position == position {
+				tied++
+			}
+		}
+	}
+
+	var (
+		payout   float64
+		refunded bool
+	)
+
+	if bet.Type == betting.Bet_WIN || bet.Type == betting.Bet_EACH_WAY {
+		payout += float64(bet.Stake) * bet.Price * tote.PlaceShare(position, tied, 1)
+	}
+
+	if bet.Type == betting.Bet_PLACE || bet.Type == betting.Bet_EACH_WAY {
+		price := bet.Price
+		if bet.Type == betting.Bet_EACH_WAY {
+			price = bet.PlacePrice
+		}
+
+		if places := tote.PlacesPaid(starters); places == 0 {
+			// Without enough starters there is no place betting, so place stakes are refunded.
+			payout += float64(bet.Stake)
+			refunded = bet.Type == betting.Bet_PLACE
+		} else {
+			payout += float64(bet.Stake) * price * tote.PlaceShare(position, tied, int64(places))
+		}
+	}
+
+	outcome := Outcome{Status: betting.Bet_LOST, Payout: int64(math.Round(payout))}
+
+	switch {
+	case refunded:
+		outcome.Status = betting.Bet_REFUNDED
+	case outcome.Payout > 0:
+		outcome.Status = betting.Bet_WON
+	}
+
+	return outcome
+}
+
+// settleExotic works out how an exotic bet settles on the final result of its race.
+//
+// Each combination covered by the bet is paid every dividend declared on it, so that dead heats
+// declaring several dividends are each paid. Combinations including a scratched runner are
+// refunded, as is the whole bet should no dividend of its type be declared.
+func settleExotic(bet *betting.Bet, result *racing.Result, runners []*racing.Runner) Outcome {
+	dividends := exotics.Dividends(bet.Type, result, runners)
+	if len(dividends) == 0 {
+		return Refund(bet)
+	}
+
+	scratched := make(map[int64]bool)
+	for _, runner := range runners {
+		if runner.Scratched {
+			scratched[runner.SaddleNumber] = true
+		}
+	}
+
+	def := exotics.Definitions[bet.Type]
+	combinations := exotics.Combinations(bet)
+	unit := exotics.Unit(bet, int64(len(combinations)))
+
+	var (
+		payout   float64
+		refunded int
+	)
+
+	for _, combination := range combinations {
+		if includesAny(combination, scratched) {
+			payout += unit
+			refunded++
+
+			continue
+		}
+
+		for _, dividend := range dividends {
+			if exotics.Covers(def, combination, dividend.RunnerNumbers) {
+				// Dividends are paid per $1 unit, so a unit stake in cents returns its amount in cents.
+				payout += unit * dividend.Amount
+			}
+		}
+	}
+
+	if refunded == len(combinations) {
+		return Refund(bet)
+	}
+
+	outcome := Outcome{Status: betting.Bet_LOST, Payout: int64(math.Round(payout))}
+	if outcome.Payout > 0 {
+		outcome.Status = betting.Bet_WON
+	}
+
+	return outcome
+}
+
+// includesAny reports whether a combination includes any of the given runner numbers.
+func includesAny(combination []int64, numbers map[int64]bool) bool {
+	for _, number := range combination {
+		if numbers[number] {
+			return true
+		}
+	}
+
+	return false
+}
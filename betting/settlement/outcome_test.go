@@ -0,0 +1,212 @@
+package settlement_test
+
+import (
+	"testing"
+
+	"git.neds.sh/matty/entain/betting/proto/betting"
+	"git.neds.sh/matty/entain/betting/settlement"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// field returns a field of starters, each with an ID of ten times its saddle number, scratching
+// the given saddle numbers.
+func field(starters int, scratched ...int64) []*racing.Runner {
+	runners := make([]*racing.Runner, starters)
+	for i := range runners {
+		number := int64(i + 1)
+		runners[i] = &racing.Runner{Id: number * 10, SaddleNumber: number}
+
+		for _, s := range scratched {
+			if s == number {
+				runners[i].Scratched = true
+			}
+		}
+	}
+
+	return runners
+}
+
+// placings places runners, by ID, at positions.
+func placings(positions map[int64]int64) []*racing.Placing {
+	var placed []*racing.Placing
+	for id, position := range positions {
+		placed = append(placed, &racing.Placing{RunnerId: id, Position: position})
+	}
+
+	return placed
+}
+
+func TestSettle(t *testing.T) {
+	firstSecondThird := placings(map[int64]int64{10: 1, 20: 2, 30: 3})
+
+	tests := []struct {
+		name    string
+		bet     *betting.Bet
+		result  *racing.Result
+		runners []*racing.Runner
+		want    settlement.Outcome
+	}{
+		{
+			name:    "win bet wins",
+			bet:     &betting.Bet{Type: betting.Bet_WIN, RunnerId: 10, Stake: 100, TotalStake: 100, Price: 3.5},
+			result:  &racing.Result{Placings: firstSecondThird},
+			runners: field(8),
+			want:    settlement.Outcome{Status: betting.Bet_WON, Payout: 350},
+		},
+		{
+			name:    "win bet placed second loses",
+			bet:     &betting.Bet{Type: betting.Bet_WIN, RunnerId: 20, Stake: 100, TotalStake: 100, Price: 3.5},
+			result:  &racing.Result{Placings: firstSecondThird},
+			runners: field(8),
+			want:    settlement.Outcome{Status: betting.Bet_LOST},
+		},
+		{
+			name:    "win bet dead heating for first is paid half",
+			bet:     &betting.Bet{Type: betting.Bet_WIN, RunnerId: 20, Stake: 100, TotalStake: 100, Price: 4},
+			result:  &racing.Result{Placings: placings(map[int64]int64{10: 1, 20: 1, 30: 3})},
+			runners: field(8),
+			want:    settlement.Outcome{Status: betting.Bet_WON, Payout: 200},
+		},
+		{
+			name:    "place bet third of eight starters wins",
+			bet:     &betting.Bet{Type: betting.Bet_PLACE, RunnerId: 30, Stake: 100, TotalStake: 100, Price: 1.8},
+			result:  &racing.Result{Placings: firstSecondThird},
+			runners: field(8),
+			want:    settlement.Outcome{Status: betting.Bet_WON, Payout: 180},
+		},
+		{
+			name:    "place bet third of seven starters loses, as two places are paid",
+			bet:     &betting.Bet{Type: betting.Bet_PLACE, RunnerId: 30, Stake: 100, TotalStake: 100, Price: 1.8},
+			result:  &racing.Result{Placings: firstSecondThird},
+			runners: field(7),
+			want:    settlement.Outcome{Status: betting.Bet_LOST},
+		},
+		{
+			name:    "place bet counts only starters when paying places",
+			bet:     &betting.Bet{Type: betting.Bet_PLACE, RunnerId: 30, Stake: 100, TotalStake: 100, Price: 1.8},
+			result:  &racing.Result{Placings: firstSecondThird},
+			runners: field(8, 8),
+			want:    settlement.Outcome{Status: betting.Bet_LOST},
+		},
+		{
+			name:    "place bet dead heating over the last place paid is paid half",
+			bet:     &betting.Bet{Type: betting.Bet_PLACE, RunnerId: 40, Stake: 100, TotalStake: 100, Price: 2},
+			result:  &racing.Result{Placings: placings(map[int64]int64{10: 1, 20: 2, 30: 3, 40: 3})},
+			runners: field(8),
+			want:    settlement.Outcome{Status: betting.Bet_WON, Payout: 100},
+		},
+		{
+			name:    "place bet in a field of four is refunded",
+			bet:     &betting.Bet{Type: betting.Bet_PLACE, RunnerId: 10, Stake: 100, TotalStake: 100, Price: 1.2},
+			result:  &racing.Result{Placings: firstSecondThird},
+			runners: field(4),
+			want:    settlement.Outcome{Status: betting.Bet_REFUNDED, Payout: 100},
+		},
+		{
+			name:    "each way bet winning is paid on both parts",
+			bet:     &betting.Bet{Type: betting.Bet_EACH_WAY, RunnerId: 10, Stake: 100, TotalStake: 200, Price: 5, PlacePrice: 2},
+			result:  &racing.Result{Placings: firstSecondThird},
+			runners: field(8),
+			want:    settlement.Outcome{Status: betting.Bet_WON, Payout: 700},
+		},
+		{
+			name:    "each way bet placing is paid the place part",
+			bet:     &betting.Bet{Type: betting.Bet_EACH_WAY, RunnerId: 20, Stake: 100, TotalStake: 200, Price: 5, PlacePrice: 2},
+			result:  &racing.Result{Placings: firstSecondThird},
+			runners: field(8),
+			want:    settlement.Outcome{Status: betting.Bet_WON, Payout: 200},
+		},
+		{
+			name:    "each way bet in a small field refunds the place part",
+			bet:     &betting.Bet{Type: betting.Bet_EACH_WAY, RunnerId: 10, Stake: 100, TotalStake: 200, Price: 5, PlacePrice: 2},
+			result:  &racing.Result{Placings: firstSecondThird},
+			runners: field(4),
+			want:    settlement.Outcome{Status: betting.Bet_WON, Payout: 600},
+		},
+		{
+			name:    "bet on a scratched runner is refunded",
+			bet:     &betting.Bet{Type: betting.Bet_WIN, RunnerId: 50, Stake: 100, TotalStake: 100, Price: 3},
+			result:  &racing.Result{Placings: firstSecondThird},
+			runners: field(8, 5),
+			want:    settlement.Outcome{Status: betting.Bet_REFUNDED, Payout: 100},
+		},
+		{
+			name: "boxed quinella covering the winners is paid the dividend",
+			bet: &betting.Bet{
+				Type: betting.Bet_QUINELLA, Boxed: true, Stake: 100, TotalStake: 300,
+				Legs: []*betting.Bet_Leg{{RunnerNumbers: []int64{1, 2, 3}}},
+			},
+			result: &racing.Result{
+				Placings:  firstSecondThird,
+				Dividends: []*racing.Dividend{{BetType: racing.Dividend_QUINELLA, RunnerIds: []int64{10, 20}, Amount: 6.5}},
+			},
+			runners: field(8),
+			want:    settlement.Outcome{Status: betting.Bet_WON, Payout: 650},
+		},
+		{
+			name: "flexi exacta is paid its share of the dividend",
+			bet: &betting.Bet{
+				Type: betting.Bet_EXACTA, Flexi: true, Stake: 100, TotalStake: 100,
+				Legs: []*betting.Bet_Leg{{RunnerNumbers: []int64{1, 2}}, {RunnerNumbers: []int64{1, 2}}},
+			},
+			result: &racing.Result{
+				Placings:  firstSecondThird,
+				Dividends: []*racing.Dividend{{BetType: racing.Dividend_EXACTA, RunnerIds: []int64{10, 20}, Amount: 12}},
+			},
+			runners: field(8),
+			want:    settlement.Outcome{Status: betting.Bet_WON, Payout: 600},
+		},
+		{
+			name: "exacta in the wrong order loses",
+			bet: &betting.Bet{
+				Type: betting.Bet_EXACTA, Stake: 100, TotalStake: 100,
+				Legs: []*betting.Bet_Leg{{RunnerNumbers: []int64{2}}, {RunnerNumbers: []int64{1}}},
+			},
+			result: &racing.Result{
+				Placings:  firstSecondThird,
+				Dividends: []*racing.Dividend{{BetType: racing.Dividend_EXACTA, RunnerIds: []int64{10, 20}, Amount: 12}},
+			},
+			runners: field(8),
+			want:    settlement.Outcome{Status: betting.Bet_LOST},
+		},
+		{
+			name: "exotic combinations including a scratched runner are refunded",
+			bet: &betting.Bet{
+				Type: betting.Bet_QUINELLA, Boxed: true, Stake: 100, TotalStake: 300,
+				Legs: []*betting.Bet_Leg{{RunnerNumbers: []int64{1, 2, 5}}},
+			},
+			result: &racing.Result{
+				Placings:  firstSecondThird,
+				Dividends: []*racing.Dividend{{BetType: racing.Dividend_QUINELLA, RunnerIds: []int64{10, 20}, Amount: 6.5}},
+			},
+			runners: field(8, 5),
+			want:    settlement.Outcome{Status: betting.Bet_WON, Payout: 850},
+		},
+		{
+			name: "exotic without a dividend declared is refunded",
+			bet: &betting.Bet{
+				Type: betting.Bet_TRIFECTA, Stake: 100, TotalStake: 100,
+				Legs: []*betting.Bet_Leg{{RunnerNumbers: []int64{1}}, {RunnerNumbers: []int64{2}}, {RunnerNumbers: []int64{3}}},
+			},
+			result:  &racing.Result{Placings: firstSecondThird},
+			runners: field(8),
+			want:    settlement.Outcome{Status: betting.Bet_REFUNDED, Payout: 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := settlement.Settle(tt.bet, tt.result, tt.runners); got != tt.want {
+				t.Errorf("Settle() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefund(t *testing.T) {
+	got := settlement.Refund(&betting.Bet{Type: betting.Bet_EACH_WAY, Stake: 100, TotalStake: 200})
+
+	if want := (settlement.Outcome{Status: betting.Bet_REFUNDED, Payout: 200}); got != want {
+		t.Errorf("Refund() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,336 @@
+// Package config loads the typed configuration of a binary from a file, the environment and
+// flags.
+//
+// A configuration is a struct whose fields are tagged with the key of each setting, e.g.
+// `config:"grpc_endpoint"`, and optionally a description in a `usage` tag. Struct fields group
+// settings under their key, e.g. the cert_file setting of a field tagged `config:"tls"` has the
+// key tls.cert_file. Settings may be strings, bools, integers, floats, durations or lists of
+// strings.
+//
+// Settings start at the values held by the struct when it is loaded, its defaults, and are
+// overridden in turn by:
+//
+//  1. the config file, in YAML (.yaml, .yml) or TOML (.toml), named by the -config flag or the
+//     <NAME>_CONFIG environment variable, whose keys nest as the struct does;
+//  2. environment variables, named by the upper-cased name and key joined by underscores, e.g.
+//     RACING_TLS_CERT_FILE;
+//  3. flags, named by the key with underscores and dots replaced by dashes, e.g.
+//     -tls-cert-file.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is implemented by the configuration of a binary.
+type Config interface {
+	// Validate returns an error describing the first invalid setting, if any.
+	Validate() error
+}
+
+// durationType is the type of duration settings, which are held as integers.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting is a single setting of a configuration: a field of its struct that is not a group.
+type setting struct {
+	key   string
+	usage string
+	value reflect.Value
+}
+
+// Load fills cfg, a pointer to a struct holding its defaults, from the config file, environment
+// variables and flags in args, then validates it. The name of the binary prefixes its
+// environment variables.
+//
+// Passing -print-config prints the loaded configuration as YAML, which may be used as a config
+// file, and exits: successfully if it is valid, or reporting why not.
+func Load(name string, cfg Config, args []string) error {
+	settings, err := settingsOf(cfg)
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+
+	var (
+		file        = flags.String("config", os.Getenv(envName(name, "config")), "YAML or TOML config file")
+		printConfig = flags.Bool("print-config", false, "Print the loaded config as YAML, and exit")
+	)
+
+	// Flags are recorded as they are parsed, and applied once the file and environment have been.
+	explicit := make(map[string]string)
+	for _, s := range settings {
+		flags.Var(&flagValue{setting: s, explicit: explicit}, flagName(s.key), s.usage)
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *file != "" {
+		values, err := readFile(*file)
+		if err != nil {
+			return err
+		}
+
+		if err := apply(settings, values, func(key string) string { return fmt.Sprintf("%s in %s", key, *file) }); err != nil {
+			return err
+		}
+	}
+
+	environment := make(map[string]string)
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(envName(name, s.key)); ok {
+			environment[s.key] = v
+		}
+	}
+
+	if err := apply(settings, environment, func(key string) string { return envName(name, key) }); err != nil {
+		return err
+	}
+
+	if err := apply(settings, explicit, func(key string) string { return "-" + flagName(key) }); err != nil {
+		return err
+	}
+
+	if *printConfig {
+		if err := Print(os.Stdout, cfg); err != nil {
+			return err
+		}
+
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	return nil
+}
+
+// apply sets each setting with a value, keyed by setting. Values for unknown settings are
+// rejected. The source func describes where a value came from, for errors.
+func apply(settings []setting, values map[string]string, source func(key string) string) error {
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("unknown setting %s", source(key))
+		}
+
+		if err := s.set(values[key]); err != nil {
+			return fmt.Errorf("setting %s: %w", source(key), err)
+		}
+	}
+
+	return nil
+}
+
+// settingsOf lists the settings of a configuration, in the order its struct declares them.
+func settingsOf(cfg Config) ([]setting, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("config must be a pointer to a struct")
+	}
+
+	return collect(v.Elem(), "")
+}
+
+// collect lists the settings of a struct, prefixing their keys with the key of its group.
+func collect(v reflect.Value, prefix string) ([]setting, error) {
+	var settings []setting
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+
+		key, ok := field.Tag.Lookup("config")
+		if !ok {
+			continue
+		}
+
+		key = prefix + key
+
+		if field.Type.Kind() == reflect.Struct {
+			group, err := collect(v.Field(i), key+".")
+			if err != nil {
+				return nil, err
+			}
+
+			settings = append(settings, group...)
+			continue
+		}
+
+		s := setting{key: key, usage: field.Tag.Get("usage"), value: v.Field(i)}
+		if err := s.check(); err != nil {
+			return nil, err
+		}
+
+		settings = append(settings, s)
+	}
+
+	return settings, nil
+}
+
+// check confirms a setting is of a supported type.
+func (s setting) check() error {
+	switch s.value.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int32, reflect.Int64, reflect.Float64:
+		return nil
+	case reflect.Slice:
+		if s.value.Type().Elem().Kind() == reflect.String {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("setting %s is of unsupported type %s", s.key, s.value.Type())
+}
+
+// set parses a value into a setting.
+func (s setting) set(value string) error {
+	switch s.value.Kind() {
+	case reflect.String:
+		s.value.SetString(value)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+
+		s.value.SetBool(b)
+
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if s.value.Type() == durationType {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%q is not a duration, e.g. 30s", value)
+			}
+
+			s.value.SetInt(int64(d))
+			return nil
+		}
+
+		n, err := strconv.ParseInt(value, 10, s.value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+
+		s.value.SetInt(n)
+
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+
+		s.value.SetFloat(f)
+
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		s.value.Set(reflect.ValueOf(items))
+	}
+
+	return nil
+}
+
+// String formats a setting as it is parsed.
+func (s setting) String() string {
+	if s.value.Type() == durationType {
+		return time.Duration(s.value.Int()).String()
+	}
+
+	if s.value.Kind() == reflect.Slice {
+		return strings.Join(s.value.Interface().([]string), ",")
+	}
+
+	return fmt.Sprint(s.value.Interface())
+}
+
+// flagValue records a flag as it is parsed, to be applied after the file and environment.
+type flagValue struct {
+	setting  setting
+	explicit map[string]string
+}
+
+func (f *flagValue) String() string {
+	if f == nil || !f.setting.value.IsValid() {
+		return ""
+	}
+
+	return f.setting.String()
+}
+
+func (f *flagValue) Set(value string) error {
+	// The value is checked now, so that a bad flag is reported alongside the usage.
+	probe := setting{key: f.setting.key, value: reflect.New(f.setting.value.Type()).Elem()}
+	if err := probe.set(value); err != nil {
+		return err
+	}
+
+	f.explicit[f.setting.key] = value
+
+	return nil
+}
+
+// IsBoolFlag allows bool settings to be set by naming their flag alone.
+func (f *flagValue) IsBoolFlag() bool {
+	return f.setting.value.Kind() == reflect.Bool
+}
+
+// flagName returns the flag of a setting.
+func flagName(key string) string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(key)
+}
+
+// envName returns the environment variable of a setting of the named binary.
+func envName(name, key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(name + "_" + key))
+}
+
+// Print writes a configuration as YAML, in the order its struct declares its settings.
+func Print(w io.Writer, cfg Config) error {
+	settings, err := settingsOf(cfg)
+	if err != nil {
+		return err
+	}
+
+	out, err := marshalYAML(settings)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+
+	return err
+}
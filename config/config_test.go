@@ -0,0 +1,323 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testConfig is a configuration of each supported type, with a group.
+type testConfig struct {
+	Name    string        `config:"name" usage:"Name"`
+	Port    int           `config:"port" usage:"Port"`
+	Debug   bool          `config:"debug" usage:"Debug"`
+	Ratio   float64       `config:"ratio" usage:"Ratio"`
+	Timeout time.Duration `config:"timeout" usage:"Timeout"`
+	Tags    []string      `config:"tags" usage:"Tags"`
+	TLS     struct {
+		CertFile string `config:"cert_file" usage:"Certificate"`
+	} `config:"tls"`
+}
+
+// errInvalidName is returned when the name is "invalid".
+var errInvalidName = errors.New("name is invalid")
+
+func (c *testConfig) Validate() error {
+	if c.Name == "invalid" {
+		return errInvalidName
+	}
+
+	return nil
+}
+
+// defaults returns a configuration holding its defaults.
+func defaults() *testConfig {
+	return &testConfig{Name: "default", Port: 1, Timeout: time.Second}
+}
+
+// writeFile writes a config file, returning its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// setenv sets environment variables for the rest of a test.
+func setenv(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	for key, value := range env {
+		previous, ok := os.LookupEnv(key)
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatal(err)
+		}
+
+		key := key
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(key, previous)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := "name: file\nport: 2\ntls:\n  cert_file: file.pem\n"
+
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want func(c *testConfig)
+	}{
+		{name: "defaults", want: func(c *testConfig) {}},
+		{
+			name: "file over defaults",
+			file: file,
+			want: func(c *testConfig) { c.Name, c.Port, c.TLS.CertFile = "file", 2, "file.pem" },
+		},
+		{
+			name: "environment over file",
+			file: file,
+			env:  map[string]string{"TEST_NAME": "env", "TEST_TLS_CERT_FILE": "env.pem"},
+			want: func(c *testConfig) { c.Name, c.Port, c.TLS.CertFile = "env", 2, "env.pem" },
+		},
+		{
+			name: "flags over environment",
+			file: file,
+			env:  map[string]string{"TEST_NAME": "env", "TEST_PORT": "3"},
+			args: []string{"-name", "flag", "-tls-cert-file", "flag.pem"},
+			want: func(c *testConfig) { c.Name, c.Port, c.TLS.CertFile = "flag", 3, "flag.pem" },
+		},
+		{
+			name: "flags over defaults",
+			args: []string{"-debug", "-ratio", "0.5", "-timeout", "1m", "-tags", "a, b,,c"},
+			want: func(c *testConfig) {
+				c.Debug, c.Ratio, c.Timeout, c.Tags = true, 0.5, time.Minute, []string{"a", "b", "c"}
+			},
+		},
+		{
+			name: "file named by the environment",
+			file: file,
+			env:  map[string]string{"TEST_CONFIG": "<file>"},
+			want: func(c *testConfig) { c.Name, c.Port, c.TLS.CertFile = "file", 2, "file.pem" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+
+			env := make(map[string]string, len(tt.env))
+			for key, value := range tt.env {
+				env[key] = value
+			}
+
+			if tt.file != "" {
+				path := writeFile(t, "config.yaml", tt.file)
+
+				if env["TEST_CONFIG"] == "<file>" {
+					env["TEST_CONFIG"] = path
+				} else {
+					args = append([]string{"-config", path}, args...)
+				}
+			}
+
+			setenv(t, env)
+
+			got := defaults()
+			if err := Load("test", got, args); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			want := defaults()
+			tt.want(want)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Load() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadFormats(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+	}{
+		{file: "config.yaml", content: "name: file\ntimeout: 5s\ntags: [a, b]\ntls:\n  cert_file: file.pem\n"},
+		{file: "config.yml", content: "name: file\ntimeout: 5s\ntags:\n  - a\n  - b\ntls:\n  cert_file: file.pem\n"},
+		{file: "config.toml", content: "name = \"file\"\ntimeout = \"5s\"\ntags = [\"a\", \"b\"]\n[tls]\ncert_file = \"file.pem\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := defaults()
+			if err := Load("test", got, []string{"-config", writeFile(t, tt.file, tt.content)}); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			if got.Name != "file" || got.Timeout != 5*time.Second || !reflect.DeepEqual(got.Tags, []string{"a", "b"}) || got.TLS.CertFile != "file.pem" {
+				t.Errorf("Load() = %+v, want the settings of the file", got)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		// content is that of the file named file, if any.
+		content string
+		env     map[string]string
+		args    []string
+		// err is a substring of the error wanted.
+		err string
+	}{
+		{name: "unknown key", file: "config.yaml", content: "name: file\nnmae: typo\n", err: "unknown setting nmae in"},
+		{name: "unknown key in a group", file: "config.yaml", content: "tls:\n  key: x\n", err: "unknown setting tls.key in"},
+		{name: "unknown group", file: "config.toml", content: "[log]\nlevel = \"debug\"\n", err: "unknown setting log.level in"},
+		{name: "group set as a value", file: "config.yaml", content: "tls: x\n", err: "unknown setting tls in"},
+		{name: "empty value", file: "config.yaml", content: "name:\n", err: "name has no value"},
+		{name: "unknown format", file: "config.json", content: "{}", err: "unknown format"},
+		{name: "missing file", file: "missing.yaml", err: "no such file"},
+		{name: "bad value in file", file: "config.yaml", content: "port: many\n", err: `setting port in`},
+		{name: "bad value in environment", env: map[string]string{"TEST_TIMEOUT": "soon"}, err: `setting TEST_TIMEOUT: "soon" is not a duration`},
+		{name: "invalid", args: []string{"-name", "invalid"}, err: "invalid config: name is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), tt.file)
+				if tt.content != "" {
+					path = writeFile(t, tt.file, tt.content)
+				}
+
+				args = append([]string{"-config", path}, args...)
+			}
+
+			setenv(t, tt.env)
+
+			if err := Load("test", defaults(), args); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Load() error = %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	tree := map[string]interface{}{
+		"name": "racing",
+		"port": 9000,
+		"tags": []interface{}{"a", 1, true},
+		// YAML decodes groups with interface keys; TOML with string keys.
+		"tls": map[interface{}]interface{}{"cert_file": "cert.pem", "client": map[string]interface{}{"ca_file": "ca.pem"}},
+	}
+
+	values := make(map[string]string)
+	if err := flatten(tree, "", values); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"name":               "racing",
+		"port":               "9000",
+		"tags":               "a,1,true",
+		"tls.cert_file":      "cert.pem",
+		"tls.client.ca_file": "ca.pem",
+	}
+
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("flatten() = %v, want %v", values, want)
+	}
+
+	if err := flatten(map[string]interface{}{"tls": map[string]interface{}{"cert_file": nil}}, "", values); err == nil || !strings.Contains(err.Error(), "tls.cert_file has no value") {
+		t.Errorf("flatten() error = %v, want tls.cert_file to have no value", err)
+	}
+}
+
+// TestPrintConfigHelper loads a configuration from the arguments following "--" when run by
+// TestPrintConfig, which Load prints before exiting.
+func TestPrintConfigHelper(t *testing.T) {
+	if os.Getenv("CONFIG_TEST_HELPER") != "1" {
+		t.Skip("run by TestPrintConfig")
+	}
+
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+
+	if err := Load("test", defaults(), args); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Fatal("Load() returned, want it to exit")
+}
+
+func TestPrintConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		ok     bool
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "valid",
+			args:   []string{"-print-config", "-name", "printed", "-tags", "a,b", "-tls-cert-file", "cert.pem"},
+			ok:     true,
+			stdout: "name: printed\nport: 1\ndebug: false\nratio: 0\ntimeout: 1s\ntags:\n- a\n- b\ntls:\n  cert_file: cert.pem\n",
+		},
+		{
+			name:   "invalid",
+			args:   []string{"-print-config", "-name", "invalid"},
+			stdout: "name: invalid\nport: 1\ndebug: false\nratio: 0\ntimeout: 1s\ntags: []\ntls:\n  cert_file: \"\"\n",
+			stderr: "invalid config: name is invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestPrintConfigHelper$", "--"}, tt.args...)...)
+			cmd.Env = append(os.Environ(), "CONFIG_TEST_HELPER=1")
+
+			var stdout, stderr strings.Builder
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+			err := cmd.Run()
+			if ok := err == nil; ok != tt.ok {
+				t.Fatalf("exited with %v, want success %t; stderr: %s", err, tt.ok, stderr.String())
+			}
+
+			if stdout.String() != tt.stdout {
+				t.Errorf("printed %q, want %q", stdout.String(), tt.stdout)
+			}
+
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.stderr)
+			}
+		})
+	}
+}
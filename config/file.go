@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// readFile reads the settings held by a YAML or TOML config file, keyed by setting.
+func readFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]interface{}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("reading config from %s: unknown format %q, must be .yaml, .yml or .toml", path, ext)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading config from %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten(tree, "", values); err != nil {
+		return nil, fmt.Errorf("reading config from %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// flatten adds the settings in a tree of groups to values, keyed by setting.
+func flatten(tree map[string]interface{}, prefix string, values map[string]string) error {
+	for name, value := range tree {
+		key := prefix + name
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flatten(v, key+".", values); err != nil {
+				return err
+			}
+
+		case map[interface{}]interface{}:
+			group := make(map[string]interface{}, len(v))
+			for k, item := range v {
+				group[fmt.Sprint(k)] = item
+			}
+
+			if err := flatten(group, key+".", values); err != nil {
+				return err
+			}
+
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+
+			values[key] = strings.Join(items, ",")
+
+		case nil:
+			return fmt.Errorf("%s has no value", key)
+
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+
+	return nil
+}
+
+// marshalYAML formats settings as YAML, nesting grouped settings.
+func marshalYAML(settings []setting) ([]byte, error) {
+	var root yaml.MapSlice
+
+	for _, s := range settings {
+		path := strings.Split(s.key, ".")
+
+		group := &root
+		for _, name := range path[:len(path)-1] {
+			group = subgroup(group, name)
+		}
+
+		*group = append(*group, yaml.MapItem{Key: path[len(path)-1], Value: printable(s)})
+	}
+
+	return yaml.Marshal(root)
+}
+
+// subgroup returns the named group within a group, adding it if it has yet to be.
+func subgroup(group *yaml.MapSlice, name string) *yaml.MapSlice {
+	for i := range *group {
+		if (*group)[i].Key == name {
+			return (*group)[i].Value.(*yaml.MapSlice)
+		}
+	}
+
+	sub := &yaml.MapSlice{}
+	*group = append(*group, yaml.MapItem{Key: name, Value: sub})
+
+	return sub
+}
+
+// printable returns the value of a setting as it is written to a config file.
+func printable(s setting) interface{} {
+	switch {
+	case s.value.Type() == durationType:
+		return time.Duration(s.value.Int()).String()
+	case s.value.Kind() == reflect.Slice && s.value.Len() == 0:
+		return []string{}
+	}
+
+	return s.value.Interface()
+}
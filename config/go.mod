@@ -0,0 +1,8 @@
+module git.neds.sh/matty/entain/config
+
+go 1.16
+
+require (
+	github.com/BurntSushi/toml v0.3.1
+	gopkg.in/yaml.v2 v2.3.0
+)
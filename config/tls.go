@@ -0,0 +1,146 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// ServerTLS configures the TLS a server is served with. Servers without a certificate are served
+// in the clear.
+type ServerTLS struct {
+	CertFile     string `config:"cert_file" usage:"PEM certificate file to serve TLS with; plaintext when empty"`
+	KeyFile      string `config:"key_file" usage:"PEM private key file of the TLS certificate"`
+	ClientCAFile string `config:"client_ca_file" usage:"PEM CA bundle to verify client certificates with; client certificates are required when set"`
+}
+
+// Enabled reports whether the server is served with TLS.
+func (t ServerTLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// Validate checks the certificate is configured along with its key, and the files exist.
+func (t ServerTLS) Validate(key string) error {
+	switch {
+	case t.CertFile == "" && t.KeyFile != "":
+		return fmt.Errorf("%s.key_file is set without %s.cert_file", key, key)
+	case t.CertFile != "" && t.KeyFile == "":
+		return fmt.Errorf("%s.cert_file is set without %s.key_file", key, key)
+	case t.CertFile == "" && t.ClientCAFile != "":
+		return fmt.Errorf("%s.client_ca_file requires %s.cert_file to be set", key, key)
+	}
+
+	return checkFiles(map[string]string{
+		key + ".cert_file":      t.CertFile,
+		key + ".key_file":       t.KeyFile,
+		key + ".client_ca_file": t.ClientCAFile,
+	})
+}
+
+// Config builds the TLS config of the server. It must be Enabled.
+func (t ServerTLS) Config() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+
+	if t.ClientCAFile != "" {
+		if cfg.ClientCAs, err = certPool(t.ClientCAFile); err != nil {
+			return nil, err
+		}
+
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// ClientTLS configures the TLS a client connects to servers with.
+type ClientTLS struct {
+	Enabled    bool   `config:"enabled" usage:"Connect with TLS; plaintext when false"`
+	CAFile     string `config:"ca_file" usage:"PEM CA bundle to verify servers with; the system roots when empty"`
+	ServerName string `config:"server_name" usage:"Name to verify server certificates against; the host connected to when empty"`
+	CertFile   string `config:"cert_file" usage:"PEM client certificate file, for servers requiring one"`
+	KeyFile    string `config:"key_file" usage:"PEM private key file of the client certificate"`
+}
+
+// Validate checks any client certificate is configured along with its key, and the files exist.
+func (t ClientTLS) Validate(key string) error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("%s.cert_file and %s.key_file must be set together", key, key)
+	}
+
+	if !t.Enabled && (t.CAFile != "" || t.ServerName != "" || t.CertFile != "") {
+		return fmt.Errorf("%s is configured but not enabled", key)
+	}
+
+	return checkFiles(map[string]string{
+		key + ".ca_file":   t.CAFile,
+		key + ".cert_file": t.CertFile,
+		key + ".key_file":  t.KeyFile,
+	})
+}
+
+// Config builds the TLS config of the client. It must be Enabled.
+func (t ClientTLS) Config() (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: t.ServerName, MinVersion: tls.VersionTLS12}
+
+	if t.CAFile != "" {
+		pool, err := certPool(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// certPool reads a PEM CA bundle.
+func certPool(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// checkFiles confirms each named file that is set exists, keyed by setting.
+func checkFiles(files map[string]string) error {
+	for key, path := range files {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+
+		if info.IsDir() {
+			return fmt.Errorf("%s: %s is a directory", key, path)
+		}
+	}
+
+	return nil
+}
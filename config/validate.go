@@ -0,0 +1,50 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errEmpty is wrapped by errors reporting a required setting is empty.
+var errEmpty = errors.New("must be set")
+
+// ValidateAddress checks a setting holds a host:port address to listen on or dial. The host may
+// be empty, e.g. ":9000", to listen on every interface.
+func ValidateAddress(key, address string) error {
+	if address == "" {
+		return fmt.Errorf("%s %w", key, errEmpty)
+	}
+
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%s: %q must be of the form host:port", key, address)
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("%s: %q has an invalid port", key, address)
+	}
+
+	return nil
+}
+
+// ValidateRequired checks a setting is not empty.
+func ValidateRequired(key, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("%s %w", key, errEmpty)
+	}
+
+	return nil
+}
+
+// ValidateTimeout checks a timeout setting is not negative. Zero disables the timeout.
+func ValidateTimeout(key string, timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("%s must not be negative", key)
+	}
+
+	return nil
+}
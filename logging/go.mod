@@ -0,0 +1,9 @@
+module git.neds.sh/matty/entain/logging
+
+go 1.16
+
+require (
+	github.com/sirupsen/logrus v1.8.1
+	go.opentelemetry.io/otel/trace v1.0.1
+	google.golang.org/grpc v1.36.0
+)
@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor identifies each unary call by the request ID in its metadata, or a
+// generated ID, and logs the call once it completes.
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx = withIncomingRequestID(ctx)
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+
+	return resp, err
+}
+
+// StreamServerInterceptor identifies each streaming call by the request ID in its metadata, or a
+// generated ID, and logs the call once it ends.
+func StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := withIncomingRequestID(ss.Context())
+	start := time.Now()
+
+	err := handler(srv, &loggedStream{ServerStream: ss, ctx: ctx})
+	logCall(ctx, info.FullMethod, start, err)
+
+	return err
+}
+
+// UnaryClientInterceptor forwards the request ID of each unary call made in its metadata.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(withOutgoingRequestID(ctx), method, req, reply, cc, opts...)
+}
+
+// StreamClientInterceptor forwards the request ID of each streaming call made in its metadata.
+func StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(withOutgoingRequestID(ctx), desc, cc, method, opts...)
+}
+
+func withIncomingRequestID(ctx context.Context) context.Context {
+	var id string
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadata); len(values) > 0 {
+			id = values[0]
+		}
+	}
+
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+
+	return WithRequestID(ctx, id)
+}
+
+func withOutgoingRequestID(ctx context.Context) context.Context {
+	id := RequestID(ctx)
+	if id == "" {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, requestIDMetadata, id)
+}
+
+// logCall logs a completed call at a level reflecting its status: errors for failures of the
+// server, such as an error returned by a repository, warnings for calls that could not be
+// completed as made, and info otherwise.
+func logCall(ctx context.Context, fullMethod string, start time.Time, err error) {
+	s := status.Convert(err)
+
+	entry := FromContext(ctx).WithFields(logrus.Fields{
+		"grpc_method": fullMethod,
+		"grpc_code":   s.Code().String(),
+		"duration_ms": durationMillis(time.Since(start)),
+	})
+
+	if p, ok := peer.FromContext(ctx); ok {
+		entry = entry.WithField("peer", p.Addr.String())
+	}
+
+	if err != nil {
+		entry = entry.WithField("error", s.Message())
+	}
+
+	switch s.Code() {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.Unauthenticated:
+		entry.Info("call completed")
+	case codes.Unknown, codes.Unimplemented, codes.Internal, codes.DataLoss:
+		entry.Error("call failed")
+	default:
+		entry.Warn("call completed")
+	}
+}
+
+// loggedStream is a stream whose context holds its request ID.
+type loggedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *loggedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,91 @@
+// Package logging writes structured logs, as JSON by default, and identifies the requests they
+// are written for.
+//
+// Each request is given an ID at the gateway, taken from its X-Request-ID header or generated,
+// which is forwarded to services in the x-request-id metadata of the calls made for it. The
+// logger returned by FromContext adds the ID, along with the trace the request is part of, to
+// every line logged for the request.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config configures the logs of a binary.
+type Config struct {
+	Level  string `config:"level" usage:"Minimum level logged: debug, info, warn or error"`
+	Format string `config:"format" usage:"Format of log lines: json, or text for reading in a terminal"`
+}
+
+// DefaultConfig logs info and above as JSON.
+var DefaultConfig = Config{Level: "info", Format: "json"}
+
+// Validate checks the logging configured under key.
+func (c Config) Validate(key string) error {
+	switch strings.ToLower(c.Level) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		return fmt.Errorf("%s.level must be one of debug, info, warn or error", key)
+	}
+
+	if c.Format != "json" && c.Format != "text" {
+		return fmt.Errorf("%s.format must be json or text", key)
+	}
+
+	return nil
+}
+
+// base is the logger of the binary, before any request is known.
+var base = logrus.NewEntry(logrus.StandardLogger())
+
+// Setup configures the logger of the named service. Lines written by the standard log package,
+// e.g. by dependencies, are logged at info.
+func Setup(service string, cfg Config) error {
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	logger := logrus.StandardLogger()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(level)
+
+	if cfg.Format == "text" {
+		logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	} else {
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	base = logrus.NewEntry(logger).WithField("service", service)
+
+	log.SetFlags(0)
+	log.SetOutput(base.WriterLevel(logrus.InfoLevel))
+
+	return nil
+}
+
+// FromContext returns the logger for a context, adding the ID of its request and its trace to
+// each line, when known.
+func FromContext(ctx context.Context) *logrus.Entry {
+	entry := base
+
+	if id := RequestID(ctx); id != "" {
+		entry = entry.WithField("request_id", id)
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		entry = entry.WithFields(logrus.Fields{
+			"trace_id": span.TraceID().String(),
+			"span_id":  span.SpanID().String(),
+		})
+	}
+
+	return entry
+}
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+const (
+	// RequestIDHeader is the HTTP header identifying a request.
+	RequestIDHeader = "X-Request-ID"
+	// requestIDMetadata is the gRPC metadata identifying the request a call is made for.
+	requestIDMetadata = "x-request-id"
+	// maxRequestIDLength is the longest request ID accepted from a caller.
+	maxRequestIDLength = 128
+)
+
+// requestIDKey keys the request ID in a context.
+type requestIDKey struct{}
+
+// WithRequestID returns a context for the request with the given ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request of a context, or "" if it has none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// validRequestID reports whether a request ID supplied by a caller may be used. IDs are logged,
+// so are limited in length to letters, digits and a little punctuation.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID generates a random request ID, in the form of a version 4 UUID.
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("reading random bytes: %s", err))
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,92 @@
+package migrate_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"git.neds.sh/matty/entain/migrate"
+)
+
+// tables returns the names of the tables in the database, other than schema_migrations.
+func tables(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name != 'schema_migrations' ORDER BY name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var names []string
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func TestApply(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "migrate.db")+"?_txlock=immediate&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations := []string{
+		`CREATE TABLE a (id INTEGER PRIMARY KEY)`,
+		`CREATE TABLE b (id INTEGER PRIMARY KEY)`,
+	}
+
+	steps := []struct {
+		name       string
+		migrations []string
+		fails      bool
+		tables     []string
+	}{
+		{name: "fresh database", migrations: migrations, tables: []string{"a", "b"}},
+		// Applied migrations are not applied again, which would fail as their tables exist.
+		{name: "already applied", migrations: migrations, tables: []string{"a", "b"}},
+		{name: "appended migration", migrations: append(migrations, `CREATE TABLE c (id INTEGER PRIMARY KEY)`), tables: []string{"a", "b", "c"}},
+		// A failed migration is rolled back, along with the statements before it, and not recorded.
+		{name: "failed migration", migrations: append(migrations, `CREATE TABLE c (id INTEGER PRIMARY KEY)`, `CREATE TABLE d (id INTEGER PRIMARY KEY); CREATE TABLE a (id INTEGER)`), fails: true, tables: []string{"a", "b", "c"}},
+		{name: "fixed migration", migrations: append(migrations, `CREATE TABLE c (id INTEGER PRIMARY KEY)`, `CREATE TABLE d (id INTEGER PRIMARY KEY)`), tables: []string{"a", "b", "c", "d"}},
+	}
+
+	for i, s := range steps {
+		if err := migrate.Apply(db, s.migrations); (err != nil) != s.fails {
+			t.Fatalf("step %d (%s): Apply() error = %v, want failure %t", i, s.name, err, s.fails)
+		}
+
+		if got := tables(t, db); !equal(got, s.tables) {
+			t.Errorf("step %d (%s): tables = %v, want %v", i, s.name, got, s.tables)
+		}
+	}
+
+	var version int
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil || version != 4 {
+		t.Errorf("schema version = %d, %v; want 4", version, err)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
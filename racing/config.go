@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"git.neds.sh/matty/entain/config"
+	"git.neds.sh/matty/entain/logging"
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/tracing"
+)
+
+// Config is the configuration of the racing service.
+type Config struct {
+	GRPCEndpoint    string           `config:"grpc_endpoint" usage:"gRPC server endpoint"`
+	MetricsEndpoint string           `config:"metrics_endpoint" usage:"HTTP endpoint serving Prometheus metrics on /metrics; disabled when empty"`
+	TLS             config.ServerTLS `config:"tls"`
+	DB              DBConfig         `config:"db"`
+	ToteCommissions string           `config:"tote_commissions" usage:"JSON file of tote commission rates per jurisdiction, replacing the defaults"`
+	PageTokenKey    string           `config:"page_token_key" usage:"Secret of at least 32 bytes signing page tokens, shared by every instance so that tokens outlive restarts; a random key is generated when empty"`
+	Seed            SeedConfig       `config:"seed"`
+	Timeouts        TimeoutsConfig   `config:"timeouts"`
+	Tracing         tracing.Config   `config:"tracing"`
+	Log             logging.Config   `config:"log"`
+}
+
+// DBConfig configures the racing database.
+type DBConfig struct {
+	DSN string `config:"dsn" usage:"SQLite data source name of the racing database; transactions should begin immediately, with a busy timeout, so that concurrent writes wait for each other"`
+}
+
+// SeedConfig configures the dummy data seeded into the racing database.
+type SeedConfig struct {
+	Enabled         bool `config:"enabled" usage:"Seed the database with dummy meetings, races and runners"`
+	Meetings        int  `config:"meetings" usage:"Number of meetings seeded"`
+	RacesPerMeeting int  `config:"races_per_meeting" usage:"Number of races seeded for each meeting"`
+}
+
+// minPageTokenKeyLength is the shortest page token key accepted, the size of the SHA-256 digests
+// tokens are signed with.
+const minPageTokenKeyLength = 32
+
+// TimeoutsConfig configures the timeouts of the gRPC server.
+type TimeoutsConfig struct {
+	Connection        time.Duration `config:"connection" usage:"Time allowed for a new connection to complete its handshake"`
+	MaxConnectionIdle time.Duration `config:"max_connection_idle" usage:"Time after which an idle connection is closed; 0 keeps idle connections open"`
+	Shutdown          time.Duration `config:"shutdown" usage:"Time allowed for in-flight calls to complete on SIGTERM before they are cancelled; 0 waits for them"`
+}
+
+// defaultConfig returns the configuration used for any setting that is not configured.
+func defaultConfig() *Config {
+	return &Config{
+		GRPCEndpoint:    "localhost:9000",
+		MetricsEndpoint: "localhost:9010",
+		DB:              DBConfig{DSN: "file:./db/racing.db?_txlock=immediate&_busy_timeout=5000"},
+		Seed: SeedConfig{
+			Enabled:         db.DefaultSeedOptions.Enabled,
+			Meetings:        db.DefaultSeedOptions.Meetings,
+			RacesPerMeeting: db.DefaultSeedOptions.RacesPerMeeting,
+		},
+		Timeouts: TimeoutsConfig{Connection: 120 * time.Second, Shutdown: 30 * time.Second},
+		Tracing:  tracing.DefaultConfig,
+		Log:      logging.DefaultConfig,
+	}
+}
+
+// Validate checks every setting of the configuration.
+func (c *Config) Validate() error {
+	if err := config.ValidateAddress("grpc_endpoint", c.GRPCEndpoint); err != nil {
+		return err
+	}
+
+	if c.MetricsEndpoint != "" {
+		if err := config.ValidateAddress("metrics_endpoint", c.MetricsEndpoint); err != nil {
+			return err
+		}
+	}
+
+	if err := c.TLS.Validate("tls"); err != nil {
+		return err
+	}
+
+	if err := config.ValidateRequired("db.dsn", c.DB.DSN); err != nil {
+		return err
+	}
+
+	if c.PageTokenKey != "" && len(c.PageTokenKey) < minPageTokenKeyLength {
+		return fmt.Errorf("page_token_key must be at least %d bytes", minPageTokenKeyLength)
+	}
+
+	if c.Seed.Enabled && (c.Seed.Meetings < 1 || c.Seed.RacesPerMeeting < 1) {
+		return fmt.Errorf("seed.meetings and seed.races_per_meeting must be positive when seeding")
+	}
+
+	if err := config.ValidateTimeout("timeouts.connection", c.Timeouts.Connection); err != nil {
+		return err
+	}
+
+	if err := config.ValidateTimeout("timeouts.max_connection_idle", c.Timeouts.MaxConnectionIdle); err != nil {
+		return err
+	}
+
+	if err := config.ValidateTimeout("timeouts.shutdown", c.Timeouts.Shutdown); err != nil {
+		return err
+	}
+
+	if err := c.Tracing.Validate("tracing"); err != nil {
+		return err
+	}
+
+	return c.Log.Validate("log")
+}
+
+// seedOptions returns the seeding configured for the races repository.
+func (c *Config) seedOptions() db.SeedOptions {
+	return db.SeedOptions{
+		Enabled:         c.Seed.Enabled,
+		Meetings:        c.Seed.Meetings,
+		RacesPerMeeting: c.Seed.RacesPerMeeting,
+	}
+}
+
+// pageTokenKey returns the key page tokens are signed with, or a random key, valid only for the
+// lifetime of the process, when none is configured.
+func (c *Config) pageTokenKey() ([]byte, error) {
+	if c.PageTokenKey != "" {
+		return []byte(c.PageTokenKey), nil
+	}
+
+	key := make([]byte, minPageTokenKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating page token key: %w", err)
+	}
+
+	return key, nil
+}
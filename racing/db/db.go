@@ -1,30 +1,289 @@
 package db
 
 import (
+	"database/sql"
+	"math"
+	"math/rand"
+	"strings"
 	"time"
 
 	"syreclabs.com/go/faker"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+const (
+	// seedRaceInterval is the time between the seeded races of a meeting.
+	seedRaceInterval = 35 * time.Minute
+	// seedMinRunners and seedMaxRunners bound the size of the field seeded for each race.
+	seedMinRunners = 6
+	seedMaxRunners = 12
+	// seedMaxGreyhounds is the size of the largest field seeded for greyhound races, which start
+	// from eight boxes.
+	seedMaxGreyhounds = 8
+	// seedMinPool and seedMaxPool bound the amount invested in each tote pool seeded, in cents.
+	seedMinPool = 500000
+	seedMaxPool = 5000000
+	// seedExoticInvestments is the number of QUINELLA and TRIFECTA investments seeded per race,
+	// each on runners drawn at random.
+	seedExoticInvestments = 40
+	// seedExoticRunners is the number of runners drawn for each exotic investment seeded.
+	seedExoticRunners = 3
 )
 
+// SeedOptions configures the dummy meetings and races seeded by the races repository.
+type SeedOptions struct {
+	// Enabled is whether anything is seeded.
+	Enabled bool
+	// Meetings is the number of meetings seeded.
+	Meetings int
+	// RacesPerMeeting is the number of races seeded for each meeting.
+	RacesPerMeeting int
+}
+
+// DefaultSeedOptions seed ten meetings of ten races each.
+var DefaultSeedOptions = SeedOptions{Enabled: true, Meetings: 10, RacesPerMeeting: 10}
+
+// seedJurisdictions are the jurisdictions seeded meetings are held in.
+var seedJurisdictions = []string{"NSW", "VIC", "QLD", "SA", "WA"}
+
+// seedRaceTypes are the race types seeded meetings are drawn from.
+var seedRaceTypes = []racing.Meeting_RaceType{
+	racing.Meeting_THOROUGHBRED,
+	racing.Meeting_HARNESS,
+	racing.Meeting_GREYHOUND,
+}
+
+// seeder holds the statements seeding the database, prepared once within its transaction.
+type seeder struct {
+	meeting    *sql.Stmt
+	race       *sql.Stmt
+	runner     *sql.Stmt
+	price      *sql.Stmt
+	investment *sql.Stmt
+}
+
+// prepareSeeder prepares the statements seeding the database within a transaction.
+func prepareSeeder(tx *sql.Tx) (*seeder, error) {
+	s := &seeder{}
+
+	for _, stmt := range []struct {
+		dest  **sql.Stmt
+		query string
+	}{
+		{&s.meeting, `INSERT OR IGNORE INTO meetings(id, name, venue, date, race_type, jurisdiction) VALUES (?,?,?,?,?,?)`},
+		{&s.race, `INSERT OR IGNORE INTO races(id, meeting_id, name, number, visible, advertised_start_time) VALUES (?,?,?,?,?,?)`},
+		{&s.runner, `INSERT INTO runners(race_id, name, saddle_number, barrier, jockey, trainer, weight) VALUES (?,?,?,?,?,?,?)`},
+		{&s.price, `INSERT INTO runner_prices(race_id, runner_id, win, place, offered_at) VALUES (?,?,?,?,?)`},
+		{&s.investment, `INSERT INTO pool_investments(race_id, bet_type, runner_ids, amount, invested_at) VALUES (?,?,?,?,?)`},
+	} {
+		prepared, err := tx.Prepare(stmt.query)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		*stmt.dest = prepared
+	}
+
+	return s, nil
+}
+
+// Close closes the statements of the seeder.
+func (s *seeder) Close() {
+	for _, stmt := range []*sql.Stmt{s.meeting, s.race, s.runner, s.price, s.investment} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+}
+
+// seededPrice is the opening price seeded for a runner.
+type seededPrice struct {
+	runnerID   int64
+	win, place float64
+}
+
+// seed populates the database with meetings, each with a card of races numbered in the order
+// they start, and a field of runners with opening prices for each race. Meetings and races are
+// seeded with fixed IDs, so that seeding an existing database leaves the races it already holds,
+// and their runners, prices and pools, as they are.
 func (r *racesRepo) seed() error {
-	statement, err := r.db.Prepare(`CREATE TABLE IF NOT EXISTS races (id INTEGER PRIMARY KEY, meeting_id INTEGER, name TEXT, number INTEGER, visible INTEGER, advertised_start_time DATETIME)`)
-	if err == nil {
-		_, err = statement.Exec()
+	if !r.seedOptions.Enabled {
+		return nil
 	}
 
-	for i := 1; i <= 100; i++ {
-		statement, err = r.db.Prepare(`INSERT OR IGNORE INTO races(id, meeting_id, name, number, visible, advertised_start_time) VALUES (?,?,?,?,?,?)`)
-		if err == nil {
-			_, err = statement.Exec(
-				i,
-				faker.Number().Between(1, 10),
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	s, err := prepareSeeder(tx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	for m := 1; m <= r.seedOptions.Meetings; m++ {
+		// Meetings start on the hour, between yesterday and the day after tomorrow.
+		firstRace := faker.Time().Between(time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 2)).Truncate(time.Hour)
+		venue := faker.Address().City()
+		raceType := seedRaceTypes[rand.Intn(len(seedRaceTypes))]
+
+		if _, err := s.meeting.Exec(
+			m,
+			venue+" Races",
+			venue,
+			firstRace.Format(meetingDateLayout),
+			raceType.String(),
+			seedJurisdictions[rand.Intn(len(seedJurisdictions))],
+		); err != nil {
+			return err
+		}
+
+		for n := 1; n <= r.seedOptions.RacesPerMeeting; n++ {
+			raceID := int64((m-1)*r.seedOptions.RacesPerMeeting + n)
+
+			res, err := s.race.Exec(
+				raceID,
+				m,
 				faker.Team().Name(),
-				faker.Number().Between(1, 12),
+				n,
 				faker.Number().Between(0, 1),
-				faker.Time().Between(time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 2)).Format(time.RFC3339),
+				firstRace.Add(time.Duration(n-1)*seedRaceInterval).Format(time.RFC3339),
 			)
+			if err != nil {
+				return err
+			}
+
+			inserted, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			// The race was seeded before, or created since, so already has its field.
+			if inserted == 0 {
+				continue
+			}
+
+			prices, err := s.seedRunners(raceID, raceType)
+			if err != nil {
+				return err
+			}
+
+			if err := s.seedPools(raceID, prices); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// seedRunners populates a race with a field of runners, drawn into random barriers, each offered
+// an opening fixed-odds price with a place price paying a quarter of the win odds.
+func (s *seeder) seedRunners(raceID int64, raceType racing.Meeting_RaceType) ([]seededPrice, error) {
+	maxRunners := seedMaxRunners
+	if raceType == racing.Meeting_GREYHOUND {
+		maxRunners = seedMaxGreyhounds
+	}
+
+	field := seedMinRunners + rand.Intn(maxRunners-seedMinRunners+1)
+	barriers := rand.Perm(field)
+	prices := make([]seededPrice, 0, field)
+
+	for saddle := 1; saddle <= field; saddle++ {
+		var (
+			jockey string
+			weight float64
+		)
+
+		if raceType != racing.Meeting_GREYHOUND {
+			jockey = faker.Name().Name()
+		}
+
+		// Thoroughbreds carry between 54 and 60kg, in half kilogram steps.
+		if raceType == racing.Meeting_THOROUGHBRED {
+			weight = 54 + float64(rand.Intn(13))/2
+		}
+
+		res, err := s.runner.Exec(
+			raceID,
+			strings.Title(faker.Commerce().Color()+" "+faker.Team().Creature()),
+			saddle,
+			barriers[saddle-1]+1,
+			jockey,
+			faker.Name().Name(),
+			weight,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		runnerID, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+
+		win := math.Round((1.5+rand.Float64()*30)*10) / 10
+		price := seededPrice{runnerID: runnerID, win: win, place: math.Round((1+(win-1)/4)*100) / 100}
+
+		if _, err := s.price.Exec(raceID, runnerID, price.win, price.place, time.Now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return nil, err
+		}
+
+		prices = append(prices, price)
+	}
+
+	return prices, nil
+}
+
+// seedPools invests in the tote pools of a race, in proportion to the opening prices of its
+// runners.
+func (s *seeder) seedPools(raceID int64, prices []seededPrice) error {
+	if len(prices) < seedExoticRunners {
+		return nil
+	}
+
+	investedAt := time.Now().UTC().Format(time.RFC3339Nano)
+
+	invest := func(betType racing.Dividend_BetType, amount float64, ids ...int64) error {
+		_, err := s.investment.Exec(raceID, betType.String(), joinIDs(ids), int64(amount), investedAt)
+		return err
+	}
+
+	// The pools hold between $5,000 and $50,000 each, spread according to the prices.
+	pool := float64(seedMinPool + rand.Intn(seedMaxPool-seedMinPool+1))
+
+	for _, price := range prices {
+		if err := invest(racing.Dividend_WIN, pool/price.win, price.runnerID); err != nil {
+			return err
+		}
+
+		if price.place > 0 {
+			if err := invest(racing.Dividend_PLACE, pool/price.place/3, price.runnerID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := 0; i < seedExoticInvestments; i++ {
+		picked := rand.Perm(len(prices))[:seedExoticRunners]
+
+		first, second, third := prices[picked[0]].runnerID, prices[picked[1]].runnerID, prices[picked[2]].runnerID
+		if first > second {
+			first, second = second, first
+		}
+
+		if err := invest(racing.Dividend_QUINELLA, pool/float64(seedExoticInvestments)*rand.Float64(), first, second); err != nil {
+			return err
+		}
+
+		if err := invest(racing.Dividend_TRIFECTA, pool/float64(seedExoticInvestments)*rand.Float64(), prices[picked[0]].runnerID, prices[picked[1]].runnerID, third); err != nil {
+			return err
 		}
 	}
 
-	return err
+	return nil
 }
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrNotFound is returned, wrapped, when a requested race, meeting, runner or result does not exist.
+var ErrNotFound = errors.New("not found")
+
+// InvalidArgumentError is returned when a caller supplied value cannot be used by the repository.
+type InvalidArgumentError struct {
+	// Field is the request field that holds the offending value.
+	Field string
+	// Reason describes why the value was rejected.
+	Reason string
+}
+
+func (e *InvalidArgumentError) Error() string {
+	return "invalid " + e.Field + ": " + e.Reason
+}
+
+// IsBusy reports whether an error is SQLite failing to lock the database within its busy timeout,
+// as it may while concurrent calls write to it.
+func IsBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
+
+func raceNotFound(id int64) error {
+	return fmt.Errorf("race %d %w", id, ErrNotFound)
+}
+
+func meetingNotFound(id int64) error {
+	return fmt.Errorf("meeting %d %w", id, ErrNotFound)
+}
+
+func runnerNotFound(raceID, id int64) error {
+	return fmt.Errorf("runner %d of race %d %w", id, raceID, ErrNotFound)
+}
+
+func resultNotFound(raceID int64) error {
+	return fmt.Errorf("result of race %d %w", raceID, ErrNotFound)
+}
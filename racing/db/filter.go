@@ -0,0 +1,34 @@
+package db
+
+import (
+	"time"
+
+	"git.neds.sh/matty/entain/racing/filter"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// raceFilterColumns maps the Race fields that may be used in filter expressions onto SQL.
+var raceFilterColumns = map[string]filter.Column{
+	"id":         {Expr: "id"},
+	"meeting_id": {Expr: "meeting_id"},
+	"name":       {Expr: "name"},
+	"number":     {Expr: "number"},
+	"visible":    {Expr: "visible"},
+	"advertised_start_time": {
+		Expr: raceOrderFields["advertised_start_time"].expr,
+		Arg: func(v interface{}) interface{} {
+			return v.(time.Time).UTC().Format(timeSortLayout)
+		},
+	},
+	"status": {Expr: raceStatusExpr},
+}
+
+// compileExpression translates an AIP-160 filter expression over races into an SQL condition.
+func compileExpression(expression string) (string, []interface{}, error) {
+	clause, args, err := filter.Compile(expression, (&racing.Race{}).ProtoReflect().Descriptor(), raceFilterColumns)
+	if err != nil {
+		return "", nil, &InvalidArgumentError{Field: "filter.expression", Reason: err.Error()}
+	}
+
+	return clause, args, nil
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"git.neds.sh/matty/entain/logging"
+	"git.neds.sh/matty/entain/racing/metrics"
+	"git.neds.sh/matty/entain/racing/watch"
+)
+
+const (
+	// lapsesBufferSize is the number of race changes buffered while waiting for the next race to
+	// start. Changes only prompt the next start time to be re-read, so dropping them is harmless.
+	lapsesBufferSize = 16
+	// lapsesRetryDelay is how long to wait before retrying after failing to read start times.
+	lapsesRetryDelay = time.Second
+)
+
+// startExpr normalises the start time of a race so that it compares correctly as text with
+// times formatted with timeSortLayout.
+var startExpr = raceOrderFields["advertised_start_time"].expr
+
+func (r *racesRepo) PublishLapses(ctx context.Context) {
+	// Changes may create races, or move their start times, so wake the publisher to re-read the
+	// next start time.
+	sub := r.changes.Subscribe(lapsesBufferSize)
+	defer sub.Close()
+
+	since := time.Now()
+
+	for {
+		next, err := r.nextLapse(ctx, since)
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Warnf("race lapses: reading the next start time failed, retrying in %s", lapsesRetryDelay)
+			next = time.Now().Add(lapsesRetryDelay)
+		}
+
+		var (
+			timer *time.Timer
+			fired <-chan time.Time
+		)
+
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			fired = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-sub.Overflow():
+			sub.Drain()
+		case <-sub.Changes():
+		case <-fired:
+			if err != nil {
+				break
+			}
+
+			now := time.Now()
+			if err := r.publishLapses(ctx, since, now); err != nil {
+				logging.FromContext(ctx).WithError(err).Warnf("race lapses: reading the races that have started failed, retrying in %s", lapsesRetryDelay)
+
+				select {
+				case <-ctx.Done():
+				case <-time.After(lapsesRetryDelay):
+				}
+
+				break
+			}
+
+			since = now
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// nextLapse returns the earliest start time after since of a race whose status is derived from
+// it, or the zero time if there is none.
+func (r *racesRepo) nextLapse(ctx context.Context, since time.Time) (time.Time, error) {
+	defer metrics.ObserveQuery("races", "next_lapse", time.Now())
+
+	query := `SELECT MIN(` + startExpr + `) FROM races WHERE status IS NULL AND ` + startExpr + ` > ?`
+
+	var next sql.NullString
+
+	queryCtx, end := traceQuery(ctx, "SELECT", "races", query)
+	err := r.db.QueryRowContext(queryCtx, query, since.UTC().Format(timeSortLayout)).Scan(&next)
+	end(err)
+
+	if err != nil || !next.Valid {
+		return time.Time{}, err
+	}
+
+	return time.Parse(timeSortLayout, next.String)
+}
+
+// publishLapses publishes an Updated change for each race whose derived status closed between
+// since and now.
+func (r *racesRepo) publishLapses(ctx context.Context, since, now time.Time) error {
+	defer metrics.ObserveQuery("races", "lapses", time.Now())
+
+	query := `SELECT id FROM races WHERE status IS NULL AND ` + startExpr + ` > ? AND ` + startExpr + ` <= ?`
+
+	queryCtx, end := traceQuery(ctx, "SELECT", "races", query)
+	ids, err := r.lapsedIDs(queryCtx, query, since.UTC().Format(timeSortLayout), now.UTC().Format(timeSortLayout))
+	end(err)
+
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		r.changes.Publish(watch.Change{Type: watch.Updated, ID: id})
+	}
+
+	return nil
+}
+
+func (r *racesRepo) lapsedIDs(ctx context.Context, query string, args ...interface{}) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
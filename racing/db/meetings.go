@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/date"
+
+	"git.neds.sh/matty/entain/migrate"
+	"git.neds.sh/matty/entain/racing/metrics"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+const (
+	// meetingDateLayout is the layout meeting dates are stored in.
+	meetingDateLayout = "2006-01-02"
+	// meetingsPageQuery identifies the page tokens issued for meetings, which are always listed
+	// in the same order.
+	meetingsPageQuery = "meetings"
+)
+
+// MeetingsRepo provides repository access to meetings.
+type MeetingsRepo interface {
+	// Init will initialise our meetings repository.
+	Init() error
+
+	// List will return a page of meetings, ordered by ID.
+	List(ctx context.Context, opts ListOptions) ([]*racing.Meeting, string, error)
+
+	// Get will return a single meeting by its ID, or ErrNotFound if it does not exist.
+	Get(ctx context.Context, id int64) (*racing.Meeting, error)
+
+	// GetMany will return the meetings with the given IDs, keyed by ID. Unknown IDs are omitted.
+	GetMany(ctx context.Context, ids []int64) (map[int64]*racing.Meeting, error)
+}
+
+type meetingsRepo struct {
+	db         *sql.DB
+	init       sync.Once
+	pageTokens *pageTokenCodec
+}
+
+// NewMeetingsRepo creates a new meetings repository, signing page tokens with pageTokenKey.
+func NewMeetingsRepo(db *sql.DB, pageTokenKey []byte) MeetingsRepo {
+	return &meetingsRepo{db: db, pageTokens: newPageTokenCodec(pageTokenKey)}
+}
+
+// Init migrates the meetings repository schema. Meetings are seeded alongside their races.
+func (r *meetingsRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		err = migrate.Apply(r.db, migrations)
+	})
+
+	return err
+}
+
+func (r *meetingsRepo) List(ctx context.Context, opts ListOptions) ([]*racing.Meeting, string, error) {
+	defer metrics.ObserveQuery("meetings", "list", time.Now())
+
+	limit, err := pageSize(opts.PageSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var (
+		clauses []string
+		args    []interface{}
+	)
+
+	if opts.PageToken != "" {
+		cursor, err := r.pageTokens.decode(opts.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if cursor.Query != meetingsPageQuery || len(cursor.Keys) != 1 {
+			return nil, "", &InvalidArgumentError{Field: "page_token", Reason: "token was not issued for meetings"}
+		}
+
+		clauses = append(clauses, "id > ?")
+		args = append(args, cursorArg(cursor.Keys[0]))
+	}
+
+	meetings, err := r.queryMeetings(ctx, getMeetingQueries()[meetingsList]+where(clauses)+" ORDER BY id LIMIT ?", append(args, limit+1)...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(meetings) <= limit {
+		return meetings, "", nil
+	}
+
+	meetings = meetings[:limit]
+
+	token, err := r.pageTokens.encode(pageCursor{Query: meetingsPageQuery, Keys: []interface{}{meetings[limit-1].Id}})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return meetings, token, nil
+}
+
+func (r *meetingsRepo) Get(ctx context.Context, id int64) (*racing.Meeting, error) {
+	defer metrics.ObserveQuery("meetings", "get", time.Now())
+
+	meetings, err := r.GetMany(ctx, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+
+	meeting, ok := meetings[id]
+	if !ok {
+		return nil, meetingNotFound(id)
+	}
+
+	return meeting, nil
+}
+
+func (r *meetingsRepo) GetMany(ctx context.Context, ids []int64) (map[int64]*racing.Meeting, error) {
+	defer metrics.ObserveQuery("meetings", "get_many", time.Now())
+
+	byID := make(map[int64]*racing.Meeting, len(ids))
+
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	meetings, err := r.queryMeetings(ctx, getMeetingQueries()[meetingsList]+" WHERE id IN ("+strings.Repeat("?,", len(ids)-1)+"?)", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, meeting := range meetings {
+		byID[meeting.Id] = meeting
+	}
+
+	return byID, nil
+}
+
+// queryMeetings reads the meetings selected by a query, in a span around the query.
+func (r *meetingsRepo) queryMeetings(ctx context.Context, query string, args ...interface{}) (meetings []*racing.Meeting, err error) {
+	err = queryTraced(ctx, r.db, "meetings", query, args, func(rows *sql.Rows) error {
+		meetings, err = scanMeetings(rows)
+		return err
+	})
+
+	return meetings, err
+}
+
+// scanMeetings reads meetings from rows selected by the meetingsList query.
+func scanMeetings(rows *sql.Rows) ([]*racing.Meeting, error) {
+	var meetings []*racing.Meeting
+
+	for rows.Next() {
+		var (
+			meeting  racing.Meeting
+			day      string
+			raceType string
+		)
+
+		if err := rows.Scan(&meeting.Id, &meeting.Name, &meeting.Venue, &day, &raceType, &meeting.Jurisdiction); err != nil {
+			return nil, err
+		}
+
+		t, err := time.Parse(meetingDateLayout, day)
+		if err != nil {
+			return nil, err
+		}
+
+		meeting.Date = &date.Date{Year: int32(t.Year()), Month: int32(t.Month()), Day: int32(t.Day())}
+		meeting.RaceType = racing.Meeting_RaceType(racing.Meeting_RaceType_value[raceType])
+
+		meetings = append(meetings, &meeting)
+	}
+
+	return meetings, rows.Err()
+}
@@ -0,0 +1,96 @@
+package db
+
+// migrations bring the racing schema up to date. Each is applied once, in order, and recorded in
+// the schema_migrations table. Released migrations must never be edited; append a new one instead.
+var migrations = []string{
+	// 1: races.
+	`CREATE TABLE IF NOT EXISTS races (id INTEGER PRIMARY KEY, meeting_id INTEGER, name TEXT, number INTEGER, visible INTEGER, advertised_start_time DATETIME)`,
+
+	// 2: explicit race statuses, and the history of transitions between them.
+	`ALTER TABLE races ADD COLUMN status TEXT;
+	CREATE TABLE race_status_transitions (
+		id INTEGER PRIMARY KEY,
+		race_id INTEGER NOT NULL REFERENCES races(id),
+		from_status TEXT NOT NULL,
+		to_status TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		transitioned_at DATETIME NOT NULL
+	);
+	CREATE INDEX race_status_transitions_race_id ON race_status_transitions(race_id);`,
+
+	// 3: meetings.
+	`CREATE TABLE meetings (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		venue TEXT NOT NULL,
+		date TEXT NOT NULL,
+		race_type TEXT NOT NULL
+	);
+	CREATE INDEX races_meeting_id ON races(meeting_id);`,
+
+	// 4: runners, and their scratchings.
+	`CREATE TABLE runners (
+		id INTEGER PRIMARY KEY,
+		race_id INTEGER NOT NULL REFERENCES races(id),
+		name TEXT NOT NULL,
+		saddle_number INTEGER NOT NULL,
+		barrier INTEGER NOT NULL,
+		jockey TEXT NOT NULL DEFAULT '',
+		trainer TEXT NOT NULL DEFAULT '',
+		weight REAL NOT NULL DEFAULT 0,
+		scratched_at DATETIME
+	);
+	CREATE UNIQUE INDEX runners_race_id_saddle_number ON runners(race_id, saddle_number);`,
+
+	// 5: results, with their placings and dividends.
+	`CREATE TABLE race_results (
+		race_id INTEGER PRIMARY KEY REFERENCES races(id),
+		published_at DATETIME NOT NULL
+	);
+	CREATE TABLE result_placings (
+		race_id INTEGER NOT NULL REFERENCES races(id),
+		runner_id INTEGER NOT NULL REFERENCES runners(id),
+		position INTEGER NOT NULL,
+		margin REAL NOT NULL DEFAULT 0,
+		PRIMARY KEY (race_id, runner_id)
+	);
+	CREATE TABLE result_dividends (
+		id INTEGER PRIMARY KEY,
+		race_id INTEGER NOT NULL REFERENCES races(id),
+		bet_type TEXT NOT NULL,
+		runner_ids TEXT NOT NULL,
+		amount REAL NOT NULL
+	);
+	CREATE INDEX result_dividends_race_id ON result_dividends(race_id);`,
+
+	// 6: fixed-odds prices, one row per price offered.
+	`CREATE TABLE runner_prices (
+		id INTEGER PRIMARY KEY,
+		race_id INTEGER NOT NULL REFERENCES races(id),
+		runner_id INTEGER NOT NULL REFERENCES runners(id),
+		win REAL NOT NULL,
+		place REAL NOT NULL DEFAULT 0,
+		offered_at DATETIME NOT NULL
+	);
+	CREATE INDEX runner_prices_race_id_runner_id ON runner_prices(race_id, runner_id);`,
+
+	// 7: meeting jurisdictions, and tote pool investments, one row per investment. Meetings seeded
+	// before jurisdictions were recorded are assigned one in turn.
+	`ALTER TABLE meetings ADD COLUMN jurisdiction TEXT NOT NULL DEFAULT '';
+	UPDATE meetings SET jurisdiction = CASE id % 5
+		WHEN 0 THEN 'NSW'
+		WHEN 1 THEN 'VIC'
+		WHEN 2 THEN 'QLD'
+		WHEN 3 THEN 'SA'
+		ELSE 'WA'
+	END;
+	CREATE TABLE pool_investments (
+		id INTEGER PRIMARY KEY,
+		race_id INTEGER NOT NULL REFERENCES races(id),
+		bet_type TEXT NOT NULL,
+		runner_ids TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		invested_at DATETIME NOT NULL
+	);
+	CREATE INDEX pool_investments_race_id ON pool_investments(race_id);`,
+}
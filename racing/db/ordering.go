@@ -0,0 +1,216 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// defaultOrderBy is applied when the caller does not specify an order.
+const defaultOrderBy = "advertised_start_time asc"
+
+// timeSortLayout normalises start times so that they compare correctly as text, regardless of
+// the offset they were stored with. It mirrors the strftime format used in raceOrderFields.
+const timeSortLayout = "2006-01-02T15:04:05.000Z"
+
+// orderField describes a Race field that callers may order by.
+type orderField struct {
+	// expr is the SQL expression the field sorts by.
+	expr string
+	// value extracts the sort key of the field from a race, for use in page cursors.
+	value func(race *racing.Race) interface{}
+}
+
+// raceOrderFields is the allow-list of fields races may be ordered by, keyed by proto field name.
+var raceOrderFields = map[string]orderField{
+	"id": {
+		expr:  "id",
+		value: func(race *racing.Race) interface{} { return race.Id },
+	},
+	"meeting_id": {
+		expr:  "meeting_id",
+		value: func(race *racing.Race) interface{} { return race.MeetingId },
+	},
+	"name": {
+		expr:  "name",
+		value: func(race *racing.Race) interface{} { return race.Name },
+	},
+	"number": {
+		expr:  "number",
+		value: func(race *racing.Race) interface{} { return race.Number },
+	},
+	"visible": {
+		expr:  "visible",
+		value: func(race *racing.Race) interface{} { return race.Visible },
+	},
+	"advertised_start_time": {
+		expr: "strftime('%Y-%m-%dT%H:%M:%fZ', advertised_start_time)",
+		value: func(race *racing.Race) interface{} {
+			ts, err := ptypes.Timestamp(race.AdvertisedStartTime)
+			if err != nil {
+				return ""
+			}
+
+			return ts.UTC().Format(timeSortLayout)
+		},
+	},
+}
+
+// orderTerm is a single field of an ORDER BY clause.
+type orderTerm struct {
+	field string
+	desc  bool
+}
+
+// ordering is a parsed order_by value.
+type ordering []orderTerm
+
+// parseOrderBy parses an AIP-132 order_by value, such as "advertised_start_time desc, number".
+// The race ID is always appended as a final tie-breaker, so that the order is total and may be
+// used to position page cursors.
+func parseOrderBy(orderBy string) (ordering, error) {
+	if strings.TrimSpace(orderBy) == "" {
+		orderBy = defaultOrderBy
+	}
+
+	var (
+		terms ordering
+		seen  = make(map[string]bool)
+	)
+
+	for _, part := range strings.Split(orderBy, ",") {
+		words := strings.Fields(part)
+		if len(words) == 0 || len(words) > 2 {
+			return nil, &InvalidArgumentError{Field: "order_by", Reason: fmt.Sprintf("malformed term %q, expected \"<field> [asc|desc]\"", strings.TrimSpace(part))}
+		}
+
+		term := orderTerm{field: words[0]}
+
+		if _, ok := raceOrderFields[term.field]; !ok {
+			return nil, &InvalidArgumentError{Field: "order_by", Reason: fmt.Sprintf("unknown field %q, must be one of %s", term.field, orderFieldNames())}
+		}
+
+		if seen[term.field] {
+			return nil, &InvalidArgumentError{Field: "order_by", Reason: fmt.Sprintf("field %q is specified more than once", term.field)}
+		}
+		seen[term.field] = true
+
+		if len(words) == 2 {
+			switch strings.ToLower(words[1]) {
+			case "asc":
+			case "desc":
+				term.desc = true
+			default:
+				return nil, &InvalidArgumentError{Field: "order_by", Reason: fmt.Sprintf("unknown direction %q for field %q, must be asc or desc", words[1], term.field)}
+			}
+		}
+
+		terms = append(terms, term)
+	}
+
+	if !seen["id"] {
+		terms = append(terms, orderTerm{field: "id"})
+	}
+
+	return terms, nil
+}
+
+// String returns the canonical form of the ordering.
+func (o ordering) String() string {
+	parts := make([]string, len(o))
+	for i, term := range o {
+		parts[i] = term.field + " asc"
+		if term.desc {
+			parts[i] = term.field + " desc"
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// orderByClause renders the ordering as an SQL ORDER BY clause.
+func (o ordering) orderByClause() string {
+	parts := make([]string, len(o))
+	for i, term := range o {
+		parts[i] = raceOrderFields[term.field].expr + " ASC"
+		if term.desc {
+			parts[i] = raceOrderFields[term.field].expr + " DESC"
+		}
+	}
+
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// keys returns the sort keys of a race under this ordering.
+func (o ordering) keys(race *racing.Race) []interface{} {
+	keys := make([]interface{}, len(o))
+	for i, term := range o {
+		keys[i] = raceOrderFields[term.field].value(race)
+	}
+
+	return keys
+}
+
+// after builds a condition selecting the races that sort after the given keys, expanded as
+// (a > ?) OR (a = ? AND b > ?) OR ... so that mixed directions are supported.
+func (o ordering) after(keys []interface{}) (string, []interface{}, error) {
+	if len(keys) != len(o) {
+		return "", nil, &InvalidArgumentError{Field: "page_token", Reason: "token does not match the requested order"}
+	}
+
+	var (
+		disjuncts []string
+		args      []interface{}
+	)
+
+	for i, term := range o {
+		var conjuncts []string
+
+		for j := 0; j < i; j++ {
+			conjuncts = append(conjuncts, raceOrderFields[o[j].field].expr+" = ?")
+			args = append(args, cursorArg(keys[j]))
+		}
+
+		op := " > ?"
+		if term.desc {
+			op = " < ?"
+		}
+
+		conjuncts = append(conjuncts, raceOrderFields[term.field].expr+op)
+		args = append(args, cursorArg(keys[i]))
+
+		disjuncts = append(disjuncts, "("+strings.Join(conjuncts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(disjuncts, " OR ") + ")", args, nil
+}
+
+// cursorArg converts a key decoded from a page cursor back into a query argument.
+func cursorArg(key interface{}) interface{} {
+	if n, ok := key.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+
+		return n.String()
+	}
+
+	return key
+}
+
+// orderFieldNames lists the fields races may be ordered by, for use in error messages.
+func orderFieldNames() string {
+	names := make([]string, 0, len(raceOrderFields))
+	for name := range raceOrderFields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
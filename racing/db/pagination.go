@@ -0,0 +1,143 @@
+package db
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+const (
+	// defaultPageSize is used when the caller does not specify a page size.
+	defaultPageSize = 100
+	// maxPageSize caps the page size a caller may request.
+	maxPageSize = 1000
+)
+
+// ListOptions controls the order and page of results a List call returns.
+type ListOptions struct {
+	// PageSize is the maximum number of races to return. Zero selects the default.
+	PageSize int32
+	// PageToken is the NextPageToken of a previous List call.
+	PageToken string
+	// OrderBy is an AIP-132 ordering such as "advertised_start_time desc, number".
+	OrderBy string
+}
+
+// ListResult is a single page of races.
+type ListResult struct {
+	// Races holds the races on this page.
+	Races []*racing.Race
+	// NextPageToken fetches the following page, and is empty on the last page.
+	NextPageToken string
+	// TotalSize is the number of races matching the filter across all pages.
+	TotalSize int32
+}
+
+// pageCursor is the keyset position encoded within a page token.
+type pageCursor struct {
+	// Query is a digest of the request parameters the token was issued for.
+	Query string `json:"q"`
+	// Keys are the sort keys of the last race on the previous page.
+	Keys []interface{} `json:"k"`
+}
+
+// pageTokenCodec signs and verifies page tokens, so that callers cannot forge or alter their position.
+type pageTokenCodec struct {
+	key []byte
+}
+
+// newPageTokenCodec creates a codec signing tokens with the key. Tokens are valid for any codec
+// sharing the key, e.g. those of other instances of the service, or of the same one once restarted.
+func newPageTokenCodec(key []byte) *pageTokenCodec {
+	return &pageTokenCodec{key: key}
+}
+
+func (c *pageTokenCodec) encode(cursor pageCursor) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload)), nil
+}
+
+func (c *pageTokenCodec) decode(token string) (*pageCursor, error) {
+	invalid := &InvalidArgumentError{Field: "page_token", Reason: "token is malformed or has expired"}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, invalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, invalid
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return nil, invalid
+	}
+
+	var cursor pageCursor
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+
+	if err := dec.Decode(&cursor); err != nil {
+		return nil, invalid
+	}
+
+	return &cursor, nil
+}
+
+func (c *pageTokenCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
+
+// queryDigest fingerprints the parameters of a List call, so that a page token cannot be
+// replayed against a different query.
+func queryDigest(filter RaceFilter, order ordering) (string, error) {
+	request := filter.Request
+	if request == nil {
+		request = &racing.ListRacesRequestFilter{}
+	}
+
+	b, err := proto.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	b = append(b, order.String()...)
+	if filter.VisibleOnly {
+		b = append(b, " visible only"...)
+	}
+
+	sum := sha256.Sum256(b)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:16]), nil
+}
+
+// pageSize validates and normalises a requested page size.
+func pageSize(requested int32) (int, error) {
+	switch {
+	case requested < 0:
+		return 0, &InvalidArgumentError{Field: "page_size", Reason: "must not be negative"}
+	case requested == 0:
+		return defaultPageSize, nil
+	case requested > maxPageSize:
+		return maxPageSize, nil
+	}
+
+	return int(requested), nil
+}
@@ -0,0 +1,241 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/watch"
+)
+
+// testPageTokenKey signs the page tokens of repositories under test.
+var testPageTokenKey = []byte("0123456789abcdef0123456789abcdef")
+
+// openDB opens a fresh database.
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	racingDB, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "racing.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { racingDB.Close() })
+
+	return racingDB
+}
+
+// newRacesRepo returns a races repository over a fresh database, seeded as given.
+func newRacesRepo(t *testing.T, seed SeedOptions) RacesRepo {
+	t.Helper()
+
+	return newRacesRepoWithKey(t, seed, testPageTokenKey)
+}
+
+// newRacesRepoWithKey returns a races repository over a fresh database, seeded as given and
+// signing page tokens with the key.
+func newRacesRepoWithKey(t *testing.T, seed SeedOptions, pageTokenKey []byte) RacesRepo {
+	t.Helper()
+
+	repo := NewRacesRepo(openDB(t), watch.NewBroker(), seed, pageTokenKey)
+	if err := repo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+// raceIDs returns the IDs of races, in order.
+func raceIDs(races []*racing.Race) []int64 {
+	ids := make([]int64, len(races))
+	for i, race := range races {
+		ids[i] = race.Id
+	}
+
+	return ids
+}
+
+func TestListPages(t *testing.T) {
+	repo := newRacesRepo(t, SeedOptions{Enabled: true, Meetings: 5, RacesPerMeeting: 5})
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		filter   *racing.ListRacesRequestFilter
+		pageSize int32
+		orderBy  string
+	}{
+		{name: "one race a page", pageSize: 1},
+		{name: "last page partly full", pageSize: 7},
+		{name: "last page exactly full", pageSize: 5},
+		{name: "single page", pageSize: 30},
+		{name: "ties on the sort key", pageSize: 4, orderBy: "visible desc, number"},
+		{name: "descending", pageSize: 6, orderBy: "advertised_start_time desc"},
+		{name: "filtered", pageSize: 2, filter: &racing.ListRacesRequestFilter{MeetingIds: []int64{2, 4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all, err := repo.List(ctx, RaceFilter{Request: tt.filter}, ListOptions{PageSize: maxPageSize, OrderBy: tt.orderBy})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var (
+				paged []*racing.Race
+				opts  = ListOptions{PageSize: tt.pageSize, OrderBy: tt.orderBy}
+			)
+
+			for {
+				page, err := repo.List(ctx, RaceFilter{Request: tt.filter}, opts)
+				if err != nil {
+					t.Fatalf("List() page %d error = %v", len(paged)/int(tt.pageSize)+1, err)
+				}
+
+				if page.TotalSize != int32(len(all.Races)) || len(page.Races) > int(tt.pageSize) {
+					t.Fatalf("List() = %d races of %d, want at most %d of %d", len(page.Races), page.TotalSize, tt.pageSize, len(all.Races))
+				}
+
+				paged = append(paged, page.Races...)
+
+				if page.NextPageToken == "" {
+					break
+				}
+
+				opts.PageToken = page.NextPageToken
+			}
+
+			if got, want := raceIDs(paged), raceIDs(all.Races); !reflect.DeepEqual(got, want) {
+				t.Errorf("List() pages = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestListPageTokenReplay(t *testing.T) {
+	repo := newRacesRepo(t, SeedOptions{Enabled: true, Meetings: 2, RacesPerMeeting: 5})
+	ctx := context.Background()
+
+	first, err := repo.List(ctx, RaceFilter{}, ListOptions{PageSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Repositories sharing the key accept each other's tokens, e.g. once the service restarts.
+	if _, err := newRacesRepo(t, SeedOptions{Enabled: true, Meetings: 2, RacesPerMeeting: 5}).List(ctx, RaceFilter{}, ListOptions{PageSize: 3, PageToken: first.NextPageToken}); err != nil {
+		t.Errorf("List() with a token of a repository sharing the key error = %v", err)
+	}
+
+	other, err := newRacesRepoWithKey(t, SeedOptions{Enabled: true, Meetings: 2, RacesPerMeeting: 5}, []byte("another key of at least 32 bytes")).List(ctx, RaceFilter{}, ListOptions{PageSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter RaceFilter
+		opts   ListOptions
+	}{
+		{name: "different filter", filter: RaceFilter{Request: &racing.ListRacesRequestFilter{MeetingIds: []int64{1}}}, opts: ListOptions{PageSize: 3, PageToken: first.NextPageToken}},
+		{name: "visible only", filter: RaceFilter{VisibleOnly: true}, opts: ListOptions{PageSize: 3, PageToken: first.NextPageToken}},
+		{name: "different order", opts: ListOptions{PageSize: 3, PageToken: first.NextPageToken, OrderBy: "number"}},
+		{name: "signed with another key", opts: ListOptions{PageSize: 3, PageToken: other.NextPageToken}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var invalid *InvalidArgumentError
+			if _, err := repo.List(ctx, tt.filter, tt.opts); !errors.As(err, &invalid) || invalid.Field != "page_token" {
+				t.Errorf("List() error = %v, want an invalid page_token", err)
+			}
+		})
+	}
+
+	// A different page size continues from the same position.
+	if _, err := repo.List(ctx, RaceFilter{}, ListOptions{PageSize: 5, PageToken: first.NextPageToken}); err != nil {
+		t.Errorf("List() with another page size error = %v", err)
+	}
+}
+
+func TestPageTokenCodec(t *testing.T) {
+	codec := newPageTokenCodec(testPageTokenKey)
+	cursor := pageCursor{Query: "digest", Keys: []interface{}{json.Number("3"), "2026-10-18T10:00:00Z"}}
+
+	token, err := codec.encode(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(token, ".")
+	payload, sig := parts[0], parts[1]
+
+	forged, err := json.Marshal(pageCursor{Query: "digest", Keys: []interface{}{json.Number("300")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		codec *pageTokenCodec
+		token string
+		want  *pageCursor
+	}{
+		{name: "issued token", codec: codec, token: token, want: &cursor},
+		{name: "issued by a codec sharing the key", codec: newPageTokenCodec(testPageTokenKey), token: token, want: &cursor},
+		{name: "signed with another key", codec: newPageTokenCodec([]byte("another key of at least 32 bytes")), token: token},
+		{name: "altered payload", codec: codec, token: base64.RawURLEncoding.EncodeToString(forged) + "." + sig},
+		{name: "altered signature", codec: codec, token: payload + "." + base64.RawURLEncoding.EncodeToString([]byte("forged"))},
+		{name: "no signature", codec: codec, token: payload},
+		{name: "extra part", codec: codec, token: token + ".x"},
+		{name: "not base64", codec: codec, token: "!." + sig},
+		{name: "empty", codec: codec, token: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.codec.decode(tt.token)
+
+			if tt.want != nil {
+				if err != nil || !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("decode() = %+v, %v; want %+v", got, err, tt.want)
+				}
+
+				return
+			}
+
+			var invalid *InvalidArgumentError
+			if !errors.As(err, &invalid) || invalid.Field != "page_token" {
+				t.Errorf("decode() error = %v, want an invalid page_token", err)
+			}
+		})
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	tests := []struct {
+		requested int32
+		want      int
+		invalid   bool
+	}{
+		{requested: 0, want: defaultPageSize},
+		{requested: 1, want: 1},
+		{requested: maxPageSize, want: maxPageSize},
+		{requested: maxPageSize + 1, want: maxPageSize},
+		{requested: -1, invalid: true},
+	}
+
+	for _, tt := range tests {
+		got, err := pageSize(tt.requested)
+
+		var invalid *InvalidArgumentError
+		if tt.invalid != errors.As(err, &invalid) || got != tt.want {
+			t.Errorf("pageSize(%d) = %d, %v; want %d, invalid %v", tt.requested, got, err, tt.want, tt.invalid)
+		}
+	}
+}
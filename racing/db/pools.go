@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"git.neds.sh/matty/entain/migrate"
+	"git.neds.sh/matty/entain/racing/metrics"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// PoolsRepo provides repository access to the investments in the tote pools of races.
+type PoolsRepo interface {
+	// Init will initialise our pools repository.
+	Init() error
+
+	// List will return the total invested on each selection in the pools of a race, or
+	// ErrNotFound if the race does not exist.
+	List(ctx context.Context, raceID int64) ([]*racing.PoolInvestment, error)
+
+	// Invest will add investments to the pools of a race. The allow func is called with the
+	// current status and runners of the race within the same transaction, and may veto the
+	// investments by returning an error.
+	Invest(ctx context.Context, raceID int64, investments []*racing.PoolInvestment, allow func(status racing.Race_Status, runners []*racing.Runner) error) error
+}
+
+type poolsRepo struct {
+	db   *sql.DB
+	init sync.Once
+}
+
+// NewPoolsRepo creates a new pools repository.
+func NewPoolsRepo(db *sql.DB) PoolsRepo {
+	return &poolsRepo{db: db}
+}
+
+// Init migrates the pools repository schema. Opening investments are seeded alongside runners.
+func (r *poolsRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		err = migrate.Apply(r.db, migrations)
+	})
+
+	return err
+}
+
+func (r *poolsRepo) List(ctx context.Context, raceID int64) (investments []*racing.PoolInvestment, err error) {
+	defer metrics.ObserveQuery("pools", "list", time.Now())
+
+	if err := checkRaceExists(ctx, r.db, raceID); err != nil {
+		return nil, err
+	}
+
+	err = queryTraced(ctx, r.db, "pool_investments", getPoolQueries()[poolsInvestments], []interface{}{raceID}, func(rows *sql.Rows) error {
+		for rows.Next() {
+			var (
+				investment racing.PoolInvestment
+				betType    string
+				ids        string
+			)
+
+			if err := rows.Scan(&betType, &ids, &investment.Amount); err != nil {
+				return err
+			}
+
+			investment.BetType = racing.Dividend_BetType(racing.Dividend_BetType_value[betType])
+
+			var err error
+			if investment.RunnerIds, err = splitIDs(ids); err != nil {
+				return err
+			}
+
+			investments = append(investments, &investment)
+		}
+
+		return rows.Err()
+	})
+
+	return investments, err
+}
+
+func (r *poolsRepo) Invest(ctx context.Context, raceID int64, investments []*racing.PoolInvestment, allow func(status racing.Race_Status, runners []*racing.Runner) error) error {
+	defer metrics.ObserveQuery("pools", "invest", time.Now())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := raceStatus(ctx, tx, raceID)
+	if err != nil {
+		return err
+	}
+
+	runners, err := raceRunners(ctx, tx, raceID)
+	if err != nil {
+		return err
+	}
+
+	if err := allow(current, runners); err != nil {
+		return err
+	}
+
+	investedAt := time.Now().UTC().Format(time.RFC3339Nano)
+
+	for _, investment := range investments {
+		if _, err := execTraced(ctx, tx, "INSERT", "pool_investments",
+			`INSERT INTO pool_investments (race_id, bet_type, runner_ids, amount, invested_at) VALUES (?,?,?,?,?)`,
+			raceID, investment.BetType.String(), joinIDs(investment.RunnerIds), investment.Amount, investedAt,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"git.neds.sh/matty/entain/migrate"
+	"git.neds.sh/matty/entain/racing/metrics"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/watch"
+)
+
+// PricesRepo provides repository access to the fixed-odds prices of runners.
+type PricesRepo interface {
+	// Init will initialise our prices repository.
+	Init() error
+
+	// List will return the current price of each runner in a race, ordered by runner, or every
+	// price offered in the race, oldest first, when history is set. It returns ErrNotFound if
+	// the race does not exist.
+	List(ctx context.Context, raceID int64, history bool) ([]*racing.Price, error)
+
+	// ListSince will return the prices offered in a race after the price with the given ID,
+	// oldest first.
+	ListSince(ctx context.Context, raceID, afterID int64) ([]*racing.Price, error)
+
+	// Update will offer new prices for runners in a race. The allow func is called with the
+	// current status and runners of the race within the same transaction, and may veto the
+	// update by returning an error.
+	Update(ctx context.Context, raceID int64, prices []*racing.Price, allow func(status racing.Race_Status, runners []*racing.Runner) error) ([]*racing.Price, error)
+
+	// Watch will subscribe to price updates, buffering up to buffer changes. Each change holds
+	// the ID of the race whose prices were updated.
+	Watch(buffer int) *watch.Subscription
+}
+
+type pricesRepo struct {
+	db      *sql.DB
+	init    sync.Once
+	changes *watch.Broker
+}
+
+// NewPricesRepo creates a new prices repository, publishing price updates to the given broker.
+func NewPricesRepo(db *sql.DB, changes *watch.Broker) PricesRepo {
+	return &pricesRepo{db: db, changes: changes}
+}
+
+// Init migrates the prices repository schema. Opening prices are seeded alongside runners.
+func (r *pricesRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		err = migrate.Apply(r.db, migrations)
+	})
+
+	return err
+}
+
+func (r *pricesRepo) List(ctx context.Context, raceID int64, history bool) ([]*racing.Price, error) {
+	defer metrics.ObserveQuery("prices", "list", time.Now())
+
+	if err := checkRaceExists(ctx, r.db, raceID); err != nil {
+		return nil, err
+	}
+
+	if history {
+		return queryPrices(ctx, r.db, getPriceQueries()[pricesList]+" WHERE race_id = ? ORDER BY id", raceID)
+	}
+
+	return queryPrices(ctx, r.db, getPriceQueries()[pricesCurrent], raceID)
+}
+
+func (r *pricesRepo) ListSince(ctx context.Context, raceID, afterID int64) ([]*racing.Price, error) {
+	defer metrics.ObserveQuery("prices", "list_since", time.Now())
+
+	return queryPrices(ctx, r.db, getPriceQueries()[pricesList]+" WHERE race_id = ? AND id > ? ORDER BY id", raceID, afterID)
+}
+
+func (r *pricesRepo) Update(ctx context.Context, raceID int64, prices []*racing.Price, allow func(status racing.Race_Status, runners []*racing.Runner) error) ([]*racing.Price, error) {
+	defer metrics.ObserveQuery("prices", "update", time.Now())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := raceStatus(ctx, tx, raceID)
+	if err != nil {
+		return nil, err
+	}
+
+	runners, err := raceRunners(ctx, tx, raceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := allow(current, runners); err != nil {
+		return nil, err
+	}
+
+	offeredAt := time.Now().UTC()
+
+	ts, err := ptypes.TimestampProto(offeredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := make([]*racing.Price, len(prices))
+
+	for i, price := range prices {
+		res, err := execTraced(ctx, tx, "INSERT", "runner_prices",
+			`INSERT INTO runner_prices (race_id, runner_id, win, place, offered_at) VALUES (?,?,?,?,?)`,
+			raceID, price.RunnerId, price.Win, price.Place, offeredAt.Format(time.RFC3339Nano),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+
+		updated[i] = &racing.Price{
+			Id:         id,
+			RaceId:     raceID,
+			RunnerId:   price.RunnerId,
+			Win:        price.Win,
+			Place:      price.Place,
+			UpdateTime: ts,
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	r.changes.Publish(watch.Change{Type: watch.Updated, ID: raceID})
+
+	return updated, nil
+}
+
+func (r *pricesRepo) Watch(buffer int) *watch.Subscription {
+	return r.changes.Subscribe(buffer)
+}
+
+// queryPrices reads the prices selected by a query of the pricesList columns, in a span around
+// the query.
+func queryPrices(ctx context.Context, q querier, query string, args ...interface{}) (prices []*racing.Price, err error) {
+	err = queryTraced(ctx, q, "runner_prices", query, args, func(rows *sql.Rows) error {
+		for rows.Next() {
+			var (
+				price     racing.Price
+				offeredAt time.Time
+			)
+
+			if err := rows.Scan(&price.Id, &price.RaceId, &price.RunnerId, &price.Win, &price.Place, &offeredAt); err != nil {
+				return err
+			}
+
+			ts, err := ptypes.TimestampProto(offeredAt)
+			if err != nil {
+				return err
+			}
+
+			price.UpdateTime = ts
+
+			prices = append(prices, &price)
+		}
+
+		return rows.Err()
+	})
+
+	return prices, err
+}
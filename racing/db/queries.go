@@ -1,9 +1,37 @@
 package db
 
+import (
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
 const (
-	racesList = "list"
+	racesList        = "list"
+	racesCount       = "count"
+	racesStatus      = "status"
+	racesTransitions = "transitions"
+
+	meetingsList = "list"
+
+	runnersList = "list"
+
+	resultsGet       = "get"
+	resultsPlacings  = "placings"
+	resultsDividends = "dividends"
+
+	pricesList    = "list"
+	pricesCurrent = "current"
+
+	poolsInvestments = "investments"
 )
 
+// raceStatusExpr derives the status of a race: its explicit status if one has been set, or else
+// OPEN or CLOSED depending on whether the race has reached its advertised start time.
+const raceStatusExpr = `COALESCE(status, CASE WHEN strftime('%Y-%m-%dT%H:%M:%fZ', advertised_start_time) <= strftime('%Y-%m-%dT%H:%M:%fZ', 'now') THEN 'CLOSED' ELSE 'OPEN' END)`
+
 func getRaceQueries() map[string]string {
 	return map[string]string{
 		racesList: `
@@ -13,8 +41,146 @@ func getRaceQueries() map[string]string {
 				name, 
 				number, 
 				visible, 
-				advertised_start_time 
+				advertised_start_time,
+				` + raceStatusExpr + `
+			FROM races
+		`,
+		racesCount: `
+			SELECT COUNT(*)
 			FROM races
 		`,
+		racesStatus: `
+			SELECT ` + raceStatusExpr + `
+			FROM races
+			WHERE id = ?
+		`,
+		racesTransitions: `
+			SELECT
+				from_status,
+				to_status,
+				reason,
+				transitioned_at
+			FROM race_status_transitions
+			WHERE race_id = ?
+			ORDER BY id
+		`,
+	}
+}
+
+func getMeetingQueries() map[string]string {
+	return map[string]string{
+		meetingsList: `
+			SELECT
+				id,
+				name,
+				venue,
+				date,
+				race_type,
+				jurisdiction
+			FROM meetings
+		`,
+	}
+}
+
+func getRunnerQueries() map[string]string {
+	return map[string]string{
+		runnersList: `
+			SELECT
+				id,
+				race_id,
+				name,
+				saddle_number,
+				barrier,
+				jockey,
+				trainer,
+				weight,
+				scratched_at
+			FROM runners
+		`,
+	}
+}
+
+func getResultQueries() map[string]string {
+	return map[string]string{
+		resultsGet: `
+			SELECT
+				race_results.published_at,
+				` + raceStatusExpr + `
+			FROM race_results
+			JOIN races ON races.id = race_results.race_id
+			WHERE race_results.race_id = ?
+		`,
+		resultsPlacings: `
+			SELECT
+				runner_id,
+				position,
+				margin
+			FROM result_placings
+			WHERE race_id = ?
+			ORDER BY position, runner_id
+		`,
+		resultsDividends: `
+			SELECT
+				bet_type,
+				runner_ids,
+				amount
+			FROM result_dividends
+			WHERE race_id = ?
+			ORDER BY id
+		`,
+	}
+}
+
+func getPriceQueries() map[string]string {
+	const columns = `
+			SELECT
+				id,
+				race_id,
+				runner_id,
+				win,
+				place,
+				offered_at
+			FROM runner_prices
+		`
+
+	return map[string]string{
+		pricesList: columns,
+		// The current price of a runner is the last it was offered at.
+		pricesCurrent: columns + `
+			WHERE id IN (SELECT MAX(id) FROM runner_prices WHERE race_id = ? GROUP BY runner_id)
+			ORDER BY runner_id
+		`,
+	}
+}
+
+// raceUpdateColumns maps the Race fields that may be updated onto functions extracting the value
+// to store in their column.
+var raceUpdateColumns = map[string]func(race *racing.Race) (interface{}, error){
+	"meeting_id": func(race *racing.Race) (interface{}, error) { return race.MeetingId, nil },
+	"name":       func(race *racing.Race) (interface{}, error) { return race.Name, nil },
+	"number":     func(race *racing.Race) (interface{}, error) { return race.Number, nil },
+	"visible":    func(race *racing.Race) (interface{}, error) { return race.Visible, nil },
+	"advertised_start_time": func(race *racing.Race) (interface{}, error) {
+		ts, err := ptypes.Timestamp(race.AdvertisedStartTime)
+		if err != nil {
+			return nil, err
+		}
+
+		return ts.UTC().Format(time.RFC3339), nil
+	},
+}
+
+func getPoolQueries() map[string]string {
+	return map[string]string{
+		poolsInvestments: `
+			SELECT
+				bet_type,
+				runner_ids,
+				SUM(amount)
+			FROM pool_investments
+			WHERE race_id = ?
+			GROUP BY bet_type, runner_ids
+			ORDER BY bet_type, runner_ids
+		`,
 	}
 }
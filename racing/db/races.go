@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"github.com/golang/protobuf/ptypes"
 	_ "github.com/mattn/go-sqlite3"
@@ -8,33 +9,85 @@ import (
 	"sync"
 	"time"
 
+	"git.neds.sh/matty/entain/migrate"
+	"git.neds.sh/matty/entain/racing/metrics"
 	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/watch"
 )
 
+// RaceFilter selects races: those matching the filter of a request, if any, and only visible
+// races should VisibleOnly be set. Visibility is a condition of its own rather than a term of the
+// request's expression, so that restricting a filter never makes it invalid, e.g. too long.
+type RaceFilter struct {
+	Request     *racing.ListRacesRequestFilter
+	VisibleOnly bool
+}
+
 // RacesRepo provides repository access to races.
 type RacesRepo interface {
 	// Init will initialise our races repository.
 	Init() error
 
-	// List will return a list of races.
-	List(filter *racing.ListRacesRequestFilter) ([]*racing.Race, error)
+	// List will return a page of races matching the filter.
+	List(ctx context.Context, filter RaceFilter, opts ListOptions) (*ListResult, error)
+
+	// Get will return a single race by its ID, or ErrNotFound if it does not exist.
+	Get(ctx context.Context, id int64) (*racing.Race, error)
+
+	// Create will insert a new race, returning it with its assigned ID.
+	Create(ctx context.Context, race *racing.Race) (*racing.Race, error)
+
+	// Update will set the fields of a race named by paths to their values in race.
+	Update(ctx context.Context, race *racing.Race, paths []string) (*racing.Race, error)
+
+	// Delete will remove a race with its runners, prices, result and history, or return
+	// ErrNotFound if it does not exist.
+	Delete(ctx context.Context, id int64) error
+
+	// TransitionStatus will move a race into a new status and record the transition. The allow
+	// func is called with the current status of the race within the same transaction, and may
+	// veto the transition by returning an error.
+	TransitionStatus(ctx context.Context, id int64, to racing.Race_Status, reason string, allow func(from racing.Race_Status) error) (*racing.Race, error)
+
+	// ListStatusTransitions will return the status history of a race, oldest first.
+	ListStatusTransitions(ctx context.Context, id int64) ([]*racing.RaceStatusTransition, error)
+
+	// PublishLapses will publish an Updated change for each race as it reaches its advertised
+	// start time, until the context is cancelled. The derived status of a race without an explicit
+	// status closes then without a write, so without a change being published otherwise.
+	PublishLapses(ctx context.Context)
+
+	// GetMatching will return a single race by its ID if it matches the filter, or ErrNotFound.
+	GetMatching(ctx context.Context, id int64, filter RaceFilter) (*racing.Race, error)
+
+	// Watch will subscribe to changes made to races through the repository, buffering up to
+	// buffer changes.
+	Watch(buffer int) *watch.Subscription
 }
 
 type racesRepo struct {
-	db   *sql.DB
-	init sync.Once
+	db          *sql.DB
+	init        sync.Once
+	pageTokens  *pageTokenCodec
+	changes     *watch.Broker
+	seedOptions SeedOptions
 }
 
-// NewRacesRepo creates a new races repository.
-func NewRacesRepo(db *sql.DB) RacesRepo {
-	return &racesRepo{db: db}
+// NewRacesRepo creates a new races repository, publishing changes to races to the given broker
+// and seeding dummy races as configured. Page tokens are signed with pageTokenKey.
+func NewRacesRepo(db *sql.DB, changes *watch.Broker, seed SeedOptions, pageTokenKey []byte) RacesRepo {
+	return &racesRepo{db: db, pageTokens: newPageTokenCodec(pageTokenKey), changes: changes, seedOptions: seed}
 }
 
-// Init prepares the race repository dummy data.
+// Init migrates the race repository schema and prepares its dummy data.
 func (r *racesRepo) Init() error {
 	var err error
 
 	r.init.Do(func() {
+		if err = migrate.Apply(r.db, migrations); err != nil {
+			return
+		}
+
 		// For test/example purposes, we seed the DB with some dummy races.
 		err = r.seed()
 	})
@@ -42,33 +95,375 @@ func (r *racesRepo) Init() error {
 	return err
 }
 
-func (r *racesRepo) List(filter *racing.ListRacesRequestFilter) ([]*racing.Race, error) {
+func (r *racesRepo) List(ctx context.Context, filter RaceFilter, opts ListOptions) (*ListResult, error) {
+	defer metrics.ObserveQuery("races", "list", time.Now())
+
+	limit, err := pageSize(opts.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := parseOrderBy(opts.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := queryDigest(filter, order)
+	if err != nil {
+		return nil, err
+	}
+
+	clauses, args, err := r.filterClauses(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int32
+
+	countQuery := getRaceQueries()[racesCount] + where(clauses)
+
+	countCtx, end := traceQuery(ctx, "SELECT", "races", countQuery)
+	err = r.db.QueryRowContext(countCtx, countQuery, args...).Scan(&total)
+	end(err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Pages are positioned by keyset rather than offset, so that races inserted while a
+	// caller is paging do not shift or repeat results.
+	if opts.PageToken != "" {
+		cursor, err := r.pageTokens.decode(opts.PageToken)
+		if err != nil {
+			return nil, err
+		}
+
+		if cursor.Query != digest {
+			return nil, &InvalidArgumentError{Field: "page_token", Reason: "token was issued for a different filter or order"}
+		}
+
+		clause, keyArgs, err := order.after(cursor.Keys)
+		if err != nil {
+			return nil, err
+		}
+
+		clauses = append(clauses, clause)
+		args = append(args, keyArgs...)
+	}
+
+	// Fetch one more race than requested to learn whether another page follows.
+	query := getRaceQueries()[racesList] + where(clauses) + order.orderByClause() + " LIMIT ?"
+	args = append(args, limit+1)
+
+	races, err := r.queryRaces(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ListResult{Races: races, TotalSize: total}
+
+	if len(races) > limit {
+		result.Races = races[:limit]
+
+		result.NextPageToken, err = r.pageTokens.encode(pageCursor{Query: digest, Keys: order.keys(races[limit-1])})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (r *racesRepo) Get(ctx context.Context, id int64) (*racing.Race, error) {
+	defer metrics.ObserveQuery("races", "get", time.Now())
+
+	races, err := r.queryRaces(ctx, getRaceQueries()[racesList]+" WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(races) == 0 {
+		return nil, raceNotFound(id)
+	}
+
+	return races[0], nil
+}
+
+func (r *racesRepo) Create(ctx context.Context, race *racing.Race) (*racing.Race, error) {
+	defer metrics.ObserveQuery("races", "create", time.Now())
+
+	advertisedStart, err := ptypes.Timestamp(race.AdvertisedStartTime)
+	if err != nil {
+		return nil, &InvalidArgumentError{Field: "race.advertised_start_time", Reason: err.Error()}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := execTraced(ctx, tx, "INSERT", "races",
+		`INSERT INTO races (meeting_id, name, number, visible, advertised_start_time) VALUES (?,?,?,?,?)`,
+		race.MeetingId, race.Name, race.Number, race.Visible, advertisedStart.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	r.changes.Publish(watch.Change{Type: watch.Created, ID: id})
+
+	return r.Get(ctx, id)
+}
+
+func (r *racesRepo) Update(ctx context.Context, race *racing.Race, paths []string) (*racing.Race, error) {
+	defer metrics.ObserveQuery("races", "update", time.Now())
+
 	var (
-		err   error
-		query string
-		args  []interface{}
+		sets []string
+		args []interface{}
 	)
 
-	query = getRaceQueries()[racesList]
+	for _, path := range paths {
+		value, ok := raceUpdateColumns[path]
+		if !ok {
+			return nil, &InvalidArgumentError{Field: "update_mask", Reason: "field " + path + " cannot be updated"}
+		}
 
-	query, args = r.applyFilter(query, filter)
+		arg, err := value(race)
+		if err != nil {
+			return nil, &InvalidArgumentError{Field: "race." + path, Reason: err.Error()}
+		}
 
-	rows, err := r.db.Query(query, args...)
+		sets = append(sets, path+" = ?")
+		args = append(args, arg)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	return r.scanRaces(rows)
+	if err := checkRaceExists(ctx, tx, race.Id); err != nil {
+		return nil, err
+	}
+
+	if len(sets) > 0 {
+		if _, err := execTraced(ctx, tx, "UPDATE", "races", `UPDATE races SET `+strings.Join(sets, ", ")+` WHERE id = ?`, append(args, race.Id)...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	r.changes.Publish(watch.Change{Type: watch.Updated, ID: race.Id})
+
+	return r.Get(ctx, race.Id)
+}
+
+func (r *racesRepo) Delete(ctx context.Context, id int64) error {
+	defer metrics.ObserveQuery("races", "delete", time.Now())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkRaceExists(ctx, tx, id); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"race_status_transitions", "pool_investments", "runner_prices", "result_dividends", "result_placings", "race_results", "runners"} {
+		if _, err := execTraced(ctx, tx, "DELETE", table, `DELETE FROM `+table+` WHERE race_id = ?`, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := execTraced(ctx, tx, "DELETE", "races", `DELETE FROM races WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	r.changes.Publish(watch.Change{Type: watch.Deleted, ID: id})
+
+	return nil
+}
+
+// checkRaceExists returns ErrNotFound if there is no race with the given ID.
+func checkRaceExists(ctx context.Context, q querier, id int64) error {
+	var exists bool
+
+	if err := scanRowTraced(ctx, q, "races", `SELECT EXISTS (SELECT 1 FROM races WHERE id = ?)`, []interface{}{id}, &exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return raceNotFound(id)
+	}
+
+	return nil
+}
+
+// raceStatus returns the current status of a race, or ErrNotFound if it does not exist.
+func raceStatus(ctx context.Context, q querier, id int64) (racing.Race_Status, error) {
+	var current string
+
+	if err := scanRowTraced(ctx, q, "races", getRaceQueries()[racesStatus], []interface{}{id}, &current); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, raceNotFound(id)
+		}
+
+		return 0, err
+	}
+
+	return racing.Race_Status(racing.Race_Status_value[current]), nil
+}
+
+func (r *racesRepo) TransitionStatus(ctx context.Context, id int64, to racing.Race_Status, reason string, allow func(from racing.Race_Status) error) (*racing.Race, error) {
+	defer metrics.ObserveQuery("races", "transition_status", time.Now())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	from, err := raceStatus(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := allow(from); err != nil {
+		return nil, err
+	}
+
+	if err := recordTransition(ctx, tx, id, from, to, reason); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	r.changes.Publish(watch.Change{Type: watch.Updated, ID: id})
+
+	return r.Get(ctx, id)
+}
+
+// recordTransition moves a race into a new status and records the transition in its history.
+func recordTransition(ctx context.Context, tx *sql.Tx, id int64, from, to racing.Race_Status, reason string) error {
+	if _, err := execTraced(ctx, tx, "UPDATE", "races", `UPDATE races SET status = ? WHERE id = ?`, to.String(), id); err != nil {
+		return err
+	}
+
+	_, err := execTraced(ctx, tx, "INSERT", "race_status_transitions",
+		`INSERT INTO race_status_transitions (race_id, from_status, to_status, reason, transitioned_at) VALUES (?,?,?,?,?)`,
+		id, from.String(), to.String(), reason, time.Now().UTC().Format(time.RFC3339Nano),
+	)
+
+	return err
+}
+
+func (r *racesRepo) ListStatusTransitions(ctx context.Context, id int64) (transitions []*racing.RaceStatusTransition, err error) {
+	defer metrics.ObserveQuery("races", "list_status_transitions", time.Now())
+
+	if _, err := r.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	ctx, end := traceQuery(ctx, "SELECT", "race_status_transitions", getRaceQueries()[racesTransitions])
+	defer func() { end(err) }()
+
+	rows, err := r.db.QueryContext(ctx, getRaceQueries()[racesTransitions], id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			transition     racing.RaceStatusTransition
+			from, to       string
+			transitionedAt time.Time
+		)
+
+		if err := rows.Scan(&from, &to, &transition.Reason, &transitionedAt); err != nil {
+			return nil, err
+		}
+
+		ts, err := ptypes.TimestampProto(transitionedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		transition.FromStatus = racing.Race_Status(racing.Race_Status_value[from])
+		transition.ToStatus = racing.Race_Status(racing.Race_Status_value[to])
+		transition.TransitionTime = ts
+
+		transitions = append(transitions, &transition)
+	}
+
+	return transitions, rows.Err()
+}
+
+func (r *racesRepo) GetMatching(ctx context.Context, id int64, filter RaceFilter) (*racing.Race, error) {
+	defer metrics.ObserveQuery("races", "get_matching", time.Now())
+
+	clauses, args, err := r.filterClauses(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	clauses = append(clauses, "id = ?")
+	args = append(args, id)
+
+	races, err := r.queryRaces(ctx, getRaceQueries()[racesList]+where(clauses), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(races) == 0 {
+		return nil, raceNotFound(id)
+	}
+
+	return races[0], nil
+}
+
+func (r *racesRepo) Watch(buffer int) *watch.Subscription {
+	return r.changes.Subscribe(buffer)
 }
 
-func (r *racesRepo) applyFilter(query string, filter *racing.ListRacesRequestFilter) (string, []interface{}) {
+// filterClauses translates a filter into SQL conditions and their arguments.
+func (r *racesRepo) filterClauses(raceFilter RaceFilter) ([]string, []interface{}, error) {
 	var (
 		clauses []string
 		args    []interface{}
 	)
 
+	if raceFilter.VisibleOnly {
+		clauses = append(clauses, "visible = ?")
+		args = append(args, true)
+	}
+
+	filter := raceFilter.Request
 	if filter == nil {
-		return query, args
+		return clauses, args, nil
 	}
 
 	if len(filter.MeetingIds) > 0 {
@@ -79,11 +474,50 @@ func (r *racesRepo) applyFilter(query string, filter *racing.ListRacesRequestFil
 		}
 	}
 
-	if len(clauses) != 0 {
-		query += " WHERE " + strings.Join(clauses, " AND ")
+	if len(filter.Statuses) > 0 {
+		clauses = append(clauses, raceStatusExpr+" IN ("+strings.Repeat("?,", len(filter.Statuses)-1)+"?)")
+
+		for _, status := range filter.Statuses {
+			args = append(args, status.String())
+		}
 	}
 
-	return query, args
+	if filter.Expression != "" {
+		clause, exprArgs, err := compileExpression(filter.Expression)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if clause != "" {
+			clauses = append(clauses, clause)
+			args = append(args, exprArgs...)
+		}
+	}
+
+	return clauses, args, nil
+}
+
+// where joins conditions into a WHERE clause, or returns an empty string when there are none.
+func where(clauses []string) string {
+	if len(clauses) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(clauses, " AND ")
+}
+
+// queryRaces reads the races selected by a query, in a span around the query.
+func (r *racesRepo) queryRaces(ctx context.Context, query string, args ...interface{}) (races []*racing.Race, err error) {
+	ctx, end := traceQuery(ctx, "SELECT", "races", query)
+	defer func() { end(err) }()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanRaces(rows)
 }
 
 func (m *racesRepo) scanRaces(
@@ -94,8 +528,9 @@ func (m *racesRepo) scanRaces(
 	for rows.Next() {
 		var race racing.Race
 		var advertisedStart time.Time
+		var status string
 
-		if err := rows.Scan(&race.Id, &race.MeetingId, &race.Name, &race.Number, &race.Visible, &advertisedStart); err != nil {
+		if err := rows.Scan(&race.Id, &race.MeetingId, &race.Name, &race.Number, &race.Visible, &advertisedStart, &status); err != nil {
 			if err == sql.ErrNoRows {
 				return nil, nil
 			}
@@ -109,6 +544,7 @@ func (m *racesRepo) scanRaces(
 		}
 
 		race.AdvertisedStartTime = ts
+		race.Status = racing.Race_Status(racing.Race_Status_value[status])
 
 		races = append(races, &race)
 	}
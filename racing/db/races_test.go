@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"git.neds.sh/matty/entain/racing/filter"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/watch"
+)
+
+var errRefused = errors.New("refused")
+
+func TestTransitionStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		// start is the advertised start time of the race, relative to now.
+		start time.Duration
+		// to are the statuses the race is moved into in turn; the last is refused when refuse is set.
+		to     []racing.Race_Status
+		refuse bool
+		// from are the statuses the race is moved from, as seen by each allow func.
+		from    []racing.Race_Status
+		want    racing.Race_Status
+		history int
+	}{
+		{
+			name:    "upcoming race is open",
+			start:   time.Hour,
+			to:      []racing.Race_Status{racing.Race_CLOSED},
+			from:    []racing.Race_Status{racing.Race_OPEN},
+			want:    racing.Race_CLOSED,
+			history: 1,
+		},
+		{
+			name:    "race past its start is closed",
+			start:   -time.Hour,
+			to:      []racing.Race_Status{racing.Race_INTERIM},
+			from:    []racing.Race_Status{racing.Race_CLOSED},
+			want:    racing.Race_INTERIM,
+			history: 1,
+		},
+		{
+			name:    "explicit status outlasts the start",
+			start:   -time.Hour,
+			to:      []racing.Race_Status{racing.Race_OPEN, racing.Race_POSTPONED},
+			from:    []racing.Race_Status{racing.Race_CLOSED, racing.Race_OPEN},
+			want:    racing.Race_POSTPONED,
+			history: 2,
+		},
+		{
+			name:    "refused transition leaves the race as it was",
+			start:   time.Hour,
+			to:      []racing.Race_Status{racing.Race_CLOSED, racing.Race_FINAL},
+			refuse:  true,
+			from:    []racing.Race_Status{racing.Race_OPEN, racing.Race_CLOSED},
+			want:    racing.Race_CLOSED,
+			history: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			racingDB := openDB(t)
+			ctx := context.Background()
+
+			repo := NewRacesRepo(racingDB, watch.NewBroker(), SeedOptions{Enabled: true, Meetings: 1, RacesPerMeeting: 1}, testPageTokenKey)
+			if err := repo.Init(); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := racingDB.Exec(`UPDATE races SET advertised_start_time = ? WHERE id = 1`, time.Now().Add(tt.start).UTC().Format(time.RFC3339)); err != nil {
+				t.Fatal(err)
+			}
+
+			var from []racing.Race_Status
+
+			for i, to := range tt.to {
+				refuse := tt.refuse && i == len(tt.to)-1
+
+				_, err := repo.TransitionStatus(ctx, 1, to, "test", func(status racing.Race_Status) error {
+					from = append(from, status)
+
+					if refuse {
+						return errRefused
+					}
+
+					return nil
+				})
+
+				switch {
+				case refuse && !errors.Is(err, errRefused):
+					t.Fatalf("TransitionStatus(%s) error = %v, want %v", to, err, errRefused)
+				case !refuse && err != nil:
+					t.Fatalf("TransitionStatus(%s) error = %v", to, err)
+				}
+			}
+
+			if !reflect.DeepEqual(from, tt.from) {
+				t.Errorf("TransitionStatus() moved from %v, want %v", from, tt.from)
+			}
+
+			got, err := repo.Get(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Status != tt.want {
+				t.Errorf("Get() status = %s, want %s", got.Status, tt.want)
+			}
+
+			history, err := repo.ListStatusTransitions(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(history) != tt.history {
+				t.Fatalf("ListStatusTransitions() = %d transitions, want %d", len(history), tt.history)
+			}
+
+			for i, transition := range history {
+				if transition.FromStatus != tt.from[i] || transition.ToStatus != tt.to[i] || transition.Reason != "test" || transition.TransitionTime == nil {
+					t.Errorf("ListStatusTransitions()[%d] = %v, want %s to %s", i, transition, tt.from[i], tt.to[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTransitionStatusNotFound(t *testing.T) {
+	repo := newRacesRepo(t, SeedOptions{})
+
+	_, err := repo.TransitionStatus(context.Background(), 1, racing.Race_CLOSED, "test", func(racing.Race_Status) error {
+		t.Error("TransitionStatus() called allow for a race that does not exist")
+		return nil
+	})
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("TransitionStatus() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestListVisibleOnly(t *testing.T) {
+	repo := newRacesRepo(t, SeedOptions{})
+	ctx := context.Background()
+
+	start, err := ptypes.TimestampProto(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, visible := range []bool{true, false, true} {
+		if _, err := repo.Create(ctx, &racing.Race{MeetingId: 1, Name: "Race", Number: 1, Visible: visible, AdvertisedStartTime: start}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The longest expression allowed stays valid once restricted to visible races.
+	longest := "id > 0" + strings.Repeat(" ", filter.MaxLength-6)
+
+	tests := []struct {
+		name   string
+		filter RaceFilter
+		want   []int64
+	}{
+		{name: "all races", want: []int64{1, 2, 3}},
+		{name: "visible only", filter: RaceFilter{VisibleOnly: true}, want: []int64{1, 3}},
+		{name: "filtered", filter: RaceFilter{Request: &racing.ListRacesRequestFilter{Expression: "id < 3"}}, want: []int64{1, 2}},
+		{name: "filtered, visible only", filter: RaceFilter{Request: &racing.ListRacesRequestFilter{Expression: "id < 3"}, VisibleOnly: true}, want: []int64{1}},
+		{name: "longest expression, visible only", filter: RaceFilter{Request: &racing.ListRacesRequestFilter{Expression: longest}, VisibleOnly: true}, want: []int64{1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.List(ctx, tt.filter, ListOptions{OrderBy: "id"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := raceIDs(result.Races); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+
+			for _, id := range []int64{1, 2, 3} {
+				_, err := repo.GetMatching(ctx, id, tt.filter)
+				if got, want := err == nil, contains(tt.want, id); got != want {
+					t.Errorf("GetMatching(%d) error = %v, want a match %t", id, err, want)
+				}
+			}
+		})
+	}
+}
+
+func contains(ids []int64, id int64) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+
+	return false
+}
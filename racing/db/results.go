@@ -0,0 +1,256 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"git.neds.sh/matty/entain/migrate"
+	"git.neds.sh/matty/entain/racing/metrics"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/watch"
+)
+
+// ResultsRepo provides repository access to the results of races.
+type ResultsRepo interface {
+	// Init will initialise our results repository.
+	Init() error
+
+	// Get will return the result of a race, or ErrNotFound if none has been published.
+	Get(ctx context.Context, raceID int64) (*racing.Result, error)
+
+	// Publish will store the result of a race, replacing any previously published. The plan func
+	// is called with the current status and runners of the race within the same transaction. It
+	// may veto publishing by returning an error, or else returns the statuses the race moves
+	// through as the result is published, in order.
+	Publish(ctx context.Context, raceID int64, result *racing.Result, plan func(from racing.Race_Status, runners []*racing.Runner) ([]racing.Race_Status, error)) (*racing.Result, error)
+}
+
+type resultsRepo struct {
+	db      *sql.DB
+	init    sync.Once
+	changes *watch.Broker
+}
+
+// NewResultsRepo creates a new results repository. Status changes made when publishing a result
+// are published to the given broker as a change to its race.
+func NewResultsRepo(db *sql.DB, changes *watch.Broker) ResultsRepo {
+	return &resultsRepo{db: db, changes: changes}
+}
+
+// Init migrates the results repository schema.
+func (r *resultsRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		err = migrate.Apply(r.db, migrations)
+	})
+
+	return err
+}
+
+func (r *resultsRepo) Get(ctx context.Context, raceID int64) (*racing.Result, error) {
+	defer metrics.ObserveQuery("results", "get", time.Now())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return getResult(ctx, tx, raceID)
+}
+
+func (r *resultsRepo) Publish(ctx context.Context, raceID int64, result *racing.Result, plan func(from racing.Race_Status, runners []*racing.Runner) ([]racing.Race_Status, error)) (*racing.Result, error) {
+	defer metrics.ObserveQuery("results", "publish", time.Now())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	from, err := raceStatus(ctx, tx, raceID)
+	if err != nil {
+		return nil, err
+	}
+
+	runners, err := raceRunners(ctx, tx, raceID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := plan(from, runners)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range []string{"result_dividends", "result_placings"} {
+		if _, err := execTraced(ctx, tx, "DELETE", table, `DELETE FROM `+table+` WHERE race_id = ?`, raceID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := execTraced(ctx, tx, "INSERT", "race_results",
+		`INSERT OR REPLACE INTO race_results (race_id, published_at) VALUES (?,?)`,
+		raceID, time.Now().UTC().Format(time.RFC3339Nano),
+	); err != nil {
+		return nil, err
+	}
+
+	for _, placing := range result.Placings {
+		if _, err := execTraced(ctx, tx, "INSERT", "result_placings",
+			`INSERT INTO result_placings (race_id, runner_id, position, margin) VALUES (?,?,?,?)`,
+			raceID, placing.RunnerId, placing.Position, placing.Margin,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dividend := range result.Dividends {
+		if _, err := execTraced(ctx, tx, "INSERT", "result_dividends",
+			`INSERT INTO result_dividends (race_id, bet_type, runner_ids, amount) VALUES (?,?,?,?)`,
+			raceID, dividend.BetType.String(), joinIDs(dividend.RunnerIds), dividend.Amount,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, to := range statuses {
+		reason := "result published"
+		if to == racing.Race_FINAL {
+			reason = "result declared final"
+		}
+
+		if err := recordTransition(ctx, tx, raceID, from, to, reason); err != nil {
+			return nil, err
+		}
+
+		from = to
+	}
+
+	published, err := getResult(ctx, tx, raceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if len(statuses) > 0 {
+		r.changes.Publish(watch.Change{Type: watch.Updated, ID: raceID})
+	}
+
+	return published, nil
+}
+
+// getResult reads the result of a race within a transaction.
+func getResult(ctx context.Context, tx *sql.Tx, raceID int64) (*racing.Result, error) {
+	var (
+		publishedAt time.Time
+		status      string
+	)
+
+	if err := scanRowTraced(ctx, tx, "race_results", getResultQueries()[resultsGet], []interface{}{raceID}, &publishedAt, &status); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, resultNotFound(raceID)
+		}
+
+		return nil, err
+	}
+
+	ts, err := ptypes.TimestampProto(publishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &racing.Result{
+		RaceId:      raceID,
+		Final:       status == racing.Race_FINAL.String(),
+		PublishTime: ts,
+	}
+
+	err = queryTraced(ctx, tx, "result_placings", getResultQueries()[resultsPlacings], []interface{}{raceID}, func(rows *sql.Rows) error {
+		for rows.Next() {
+			var placing racing.Placing
+
+			if err := rows.Scan(&placing.RunnerId, &placing.Position, &placing.Margin); err != nil {
+				return err
+			}
+
+			result.Placings = append(result.Placings, &placing)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryTraced(ctx, tx, "result_dividends", getResultQueries()[resultsDividends], []interface{}{raceID}, func(rows *sql.Rows) error {
+		for rows.Next() {
+			var (
+				dividend racing.Dividend
+				betType  string
+				ids      string
+			)
+
+			if err := rows.Scan(&betType, &ids, &dividend.Amount); err != nil {
+				return err
+			}
+
+			dividend.BetType = racing.Dividend_BetType(racing.Dividend_BetType_value[betType])
+
+			var err error
+			if dividend.RunnerIds, err = splitIDs(ids); err != nil {
+				return err
+			}
+
+			result.Dividends = append(result.Dividends, &dividend)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// joinIDs stores a list of IDs in a single column, separated by commas.
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// splitIDs reads a list of IDs stored by joinIDs.
+func splitIDs(s string) ([]int64, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	ids := make([]int64, len(parts))
+
+	for i, part := range parts {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		ids[i] = id
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"git.neds.sh/matty/entain/migrate"
+	"git.neds.sh/matty/entain/racing/metrics"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/watch"
+)
+
+// RunnersRepo provides repository access to the runners of races.
+type RunnersRepo interface {
+	// Init will initialise our runners repository.
+	Init() error
+
+	// List will return the runners of a race ordered by saddle number, or ErrNotFound if the
+	// race does not exist.
+	List(ctx context.Context, raceID int64) ([]*racing.Runner, error)
+
+	// ListForRaces will return the runners of the given races, keyed by race ID.
+	ListForRaces(ctx context.Context, raceIDs []int64) (map[int64][]*racing.Runner, error)
+
+	// Scratch will withdraw a runner from its race, recording the time it was scratched. The
+	// allow func is called with the runner and the current status of its race within the same
+	// transaction, and may veto the scratching by returning an error.
+	Scratch(ctx context.Context, raceID, id int64, allow func(runner *racing.Runner, status racing.Race_Status) error) (*racing.Runner, error)
+}
+
+type runnersRepo struct {
+	db      *sql.DB
+	init    sync.Once
+	changes *watch.Broker
+}
+
+// NewRunnersRepo creates a new runners repository. Scratching a runner is published to the
+// given broker as a change to its race.
+func NewRunnersRepo(db *sql.DB, changes *watch.Broker) RunnersRepo {
+	return &runnersRepo{db: db, changes: changes}
+}
+
+// Init migrates the runners repository schema. Runners are seeded alongside their races.
+func (r *runnersRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		err = migrate.Apply(r.db, migrations)
+	})
+
+	return err
+}
+
+func (r *runnersRepo) List(ctx context.Context, raceID int64) ([]*racing.Runner, error) {
+	defer metrics.ObserveQuery("runners", "list", time.Now())
+
+	if err := checkRaceExists(ctx, r.db, raceID); err != nil {
+		return nil, err
+	}
+
+	return raceRunners(ctx, r.db, raceID)
+}
+
+func (r *runnersRepo) ListForRaces(ctx context.Context, raceIDs []int64) (map[int64][]*racing.Runner, error) {
+	defer metrics.ObserveQuery("runners", "list_for_races", time.Now())
+
+	byRace := make(map[int64][]*racing.Runner, len(raceIDs))
+
+	if len(raceIDs) == 0 {
+		return byRace, nil
+	}
+
+	args := make([]interface{}, len(raceIDs))
+	for i, id := range raceIDs {
+		args[i] = id
+	}
+
+	query := getRunnerQueries()[runnersList] +
+		" WHERE race_id IN (" + strings.Repeat("?,", len(raceIDs)-1) + "?) ORDER BY race_id, saddle_number"
+
+	runners, err := queryRunners(ctx, r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, runner := range runners {
+		byRace[runner.RaceId] = append(byRace[runner.RaceId], runner)
+	}
+
+	return byRace, nil
+}
+
+func (r *runnersRepo) Scratch(ctx context.Context, raceID, id int64, allow func(runner *racing.Runner, status racing.Race_Status) error) (*racing.Runner, error) {
+	defer metrics.ObserveQuery("runners", "scratch", time.Now())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := raceStatus(ctx, tx, raceID)
+	if err != nil {
+		return nil, err
+	}
+
+	runners, err := queryRunners(ctx, tx, getRunnerQueries()[runnersList]+" WHERE id = ? AND race_id = ?", id, raceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(runners) == 0 {
+		return nil, runnerNotFound(raceID, id)
+	}
+
+	runner := runners[0]
+
+	if err := allow(runner, current); err != nil {
+		return nil, err
+	}
+
+	scratchedAt := time.Now().UTC()
+
+	if _, err := execTraced(ctx, tx, "UPDATE", "runners", `UPDATE runners SET scratched_at = ? WHERE id = ?`, scratchedAt.Format(time.RFC3339Nano), id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	r.changes.Publish(watch.Change{Type: watch.Updated, ID: raceID})
+
+	ts, err := ptypes.TimestampProto(scratchedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	runner.Scratched = true
+	runner.ScratchTime = ts
+
+	return runner, nil
+}
+
+// raceRunners returns the runners of a race, ordered by saddle number.
+func raceRunners(ctx context.Context, q querier, raceID int64) ([]*racing.Runner, error) {
+	return queryRunners(ctx, q, getRunnerQueries()[runnersList]+" WHERE race_id = ? ORDER BY saddle_number", raceID)
+}
+
+// queryRunners reads the runners selected by a query, in a span around the query.
+func queryRunners(ctx context.Context, q querier, query string, args ...interface{}) (runners []*racing.Runner, err error) {
+	err = queryTraced(ctx, q, "runners", query, args, func(rows *sql.Rows) error {
+		runners, err = scanRunners(rows)
+		return err
+	})
+
+	return runners, err
+}
+
+// scanRunners reads runners from rows selected by the runnersList query.
+func scanRunners(rows *sql.Rows) ([]*racing.Runner, error) {
+	defer rows.Close()
+
+	var runners []*racing.Runner
+
+	for rows.Next() {
+		var (
+			runner      racing.Runner
+			scratchedAt sql.NullTime
+		)
+
+		if err := rows.Scan(
+			&runner.Id,
+			&runner.RaceId,
+			&runner.Name,
+			&runner.SaddleNumber,
+			&runner.Barrier,
+			&runner.Jockey,
+			&runner.Trainer,
+			&runner.Weight,
+			&scratchedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if scratchedAt.Valid {
+			ts, err := ptypes.TimestampProto(scratchedAt.Time)
+			if err != nil {
+				return nil, err
+			}
+
+			runner.Scratched = true
+			runner.ScratchTime = ts
+		}
+
+		runners = append(runners, &runner)
+	}
+
+	return runners, rows.Err()
+}
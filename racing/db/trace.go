@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer traces the SQL statements executed by the repositories.
+var tracer = otel.Tracer("git.neds.sh/matty/entain/racing/db")
+
+// traceQuery starts a span around an SQL statement, named for its operation and the table it
+// operates on, e.g. SELECT races. The returned func ends the span, recording the error the
+// statement returned, if any.
+func traceQuery(ctx context.Context, operation, table, statement string) (context.Context, func(error)) {
+	ctx, span := tracer.Start(ctx, operation+" "+table,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemSqlite,
+			semconv.DBOperationKey.String(operation),
+			semconv.DBSQLTableKey.String(table),
+			semconv.DBStatementKey.String(statement),
+		),
+	)
+
+	return ctx, func(err error) {
+		// A query finding no rows has not failed.
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}
+}
+
+// execTraced executes an SQL statement within a transaction, in a span around the statement.
+func execTraced(ctx context.Context, tx *sql.Tx, operation, table, statement string, args ...interface{}) (sql.Result, error) {
+	ctx, end := traceQuery(ctx, operation, table, statement)
+
+	res, err := tx.ExecContext(ctx, statement, args...)
+	end(err)
+
+	return res, err
+}
+
+// querier runs queries against the database, or within a transaction.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// queryTraced runs a query, passing the rows it selects to scan, in a span around the query and
+// the scan.
+func queryTraced(ctx context.Context, q querier, table, statement string, args []interface{}, scan func(rows *sql.Rows) error) (err error) {
+	ctx, end := traceQuery(ctx, "SELECT", table, statement)
+	defer func() { end(err) }()
+
+	rows, err := q.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return scan(rows)
+}
+
+// scanRowTraced scans the single row selected by a query into dest, in a span around the query.
+func scanRowTraced(ctx context.Context, q querier, table, statement string, args []interface{}, dest ...interface{}) error {
+	ctx, end := traceQuery(ctx, "SELECT", table, statement)
+
+	err := q.QueryRowContext(ctx, statement, args...).Scan(dest...)
+	end(err)
+
+	return err
+}
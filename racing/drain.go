@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"git.neds.sh/matty/entain/logging"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// drainer ends the streams being served once the server starts draining. Streams such as
+// WatchRaces last until the client goes away, so would otherwise hold up a graceful stop until
+// its deadline; instead they end cleanly, and clients reconnect to another instance.
+type drainer struct {
+	draining chan struct{}
+	once     sync.Once
+}
+
+func newDrainer() *drainer {
+	return &drainer{draining: make(chan struct{})}
+}
+
+// StreamInterceptor cancels the context of each stream once draining starts.
+func (d *drainer) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, cancel := context.WithCancel(ss.Context())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-d.draining:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return handler(srv, &drainingStream{ServerStream: ss, ctx: ctx})
+}
+
+// drain stops the server once in-flight calls complete, ending streams, and stops it forcibly,
+// cancelling the calls, should they not complete within the deadline. A zero deadline waits for
+// them however long they take.
+func (d *drainer) drain(server *grpc.Server, deadline time.Duration) {
+	d.once.Do(func() { close(d.draining) })
+
+	stopped := make(chan struct{})
+
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	var expired <-chan time.Time
+	if deadline > 0 {
+		expired = time.After(deadline)
+	}
+
+	select {
+	case <-stopped:
+	case <-expired:
+		logging.FromContext(context.Background()).Warnf("shutdown deadline of %s passed, cancelling in-flight calls", deadline)
+		server.Stop()
+	}
+}
+
+// drainingStream is a stream whose context is cancelled once draining starts.
+type drainingStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *drainingStream) Context() context.Context {
+	return s.ctx
+}
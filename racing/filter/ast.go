@@ -0,0 +1,81 @@
+// Package filter implements the AIP-160 filtering language (https://google.aip.dev/160).
+//
+// Expressions are parsed into an AST, type-checked against a protobuf message and compiled into
+// a parameterised SQL condition, e.g.
+//
+//	visible = true AND advertised_start_time > "2026-10-18T00:00:00Z" AND meeting_id:(1,2)
+//
+// Following AIP-160, OR binds more tightly than AND, and juxtaposed restrictions are combined
+// with an implicit AND.
+package filter
+
+import "fmt"
+
+// Expr is a node of a parsed filter expression.
+type Expr interface {
+	// Pos is the offset of the expression within the source.
+	Pos() int
+}
+
+// And is satisfied when both of its operands are.
+type And struct {
+	Left, Right Expr
+}
+
+// Or is satisfied when either of its operands is.
+type Or struct {
+	Left, Right Expr
+}
+
+// Not is satisfied when its operand is not.
+type Not struct {
+	Expr Expr
+	pos  int
+}
+
+// Restriction compares a field against one or more values, e.g. meeting_id:(1,2).
+type Restriction struct {
+	// Field is the proto name of the field being restricted.
+	Field string
+	// Comparator is one of =, !=, <, <=, >, >= or :.
+	Comparator string
+	// Values are the literals the field is compared against. Only the : comparator accepts
+	// more than one value.
+	Values []Value
+
+	// args holds the values converted to the field's type by Check.
+	args []interface{}
+	// kind is the type of the field, resolved by Check.
+	kind fieldKind
+	pos  int
+}
+
+// Value is a literal within a restriction.
+type Value struct {
+	// Text is the literal, without quotes.
+	Text string
+	// Quoted reports whether the literal was a quoted string.
+	Quoted bool
+	pos    int
+}
+
+func (e *And) Pos() int         { return e.Left.Pos() }
+func (e *Or) Pos() int          { return e.Left.Pos() }
+func (e *Not) Pos() int         { return e.pos }
+func (e *Restriction) Pos() int { return e.pos }
+
+// Error describes a syntax or type error within a filter expression.
+type Error struct {
+	// Offset is the position of the error within the expression.
+	Offset int
+	// Msg describes the problem.
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (at offset %d)", e.Msg, e.Offset)
+}
+
+func errorf(pos int, format string, args ...interface{}) error {
+	return &Error{Offset: pos, Msg: fmt.Sprintf(format, args...)}
+}
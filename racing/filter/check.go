@@ -0,0 +1,171 @@
+package filter
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// fieldKind is the type a restricted field is compared as.
+type fieldKind int
+
+const (
+	kindInt fieldKind = iota + 1
+	kindFloat
+	kindString
+	kindBool
+	kindEnum
+	kindTimestamp
+)
+
+// timestampName is the full name of the well-known Timestamp message.
+const timestampName protoreflect.FullName = "google.protobuf.Timestamp"
+
+// Check type-checks an expression against the fields of a message, converting each value into
+// the Go type of the field it restricts: int64, float64, string, bool, time.Time, or the name of
+// an enum value.
+func Check(expr Expr, md protoreflect.MessageDescriptor) error {
+	switch e := expr.(type) {
+	case nil:
+		return nil
+	case *And:
+		if err := Check(e.Left, md); err != nil {
+			return err
+		}
+
+		return Check(e.Right, md)
+	case *Or:
+		if err := Check(e.Left, md); err != nil {
+			return err
+		}
+
+		return Check(e.Right, md)
+	case *Not:
+		return Check(e.Expr, md)
+	case *Restriction:
+		return checkRestriction(e, md)
+	}
+
+	return errorf(expr.Pos(), "unsupported expression")
+}
+
+func checkRestriction(r *Restriction, md protoreflect.MessageDescriptor) error {
+	fd := md.Fields().ByName(protoreflect.Name(r.Field))
+	if fd == nil {
+		return errorf(r.pos, "unknown field %q on %s", r.Field, md.Name())
+	}
+
+	if fd.IsList() || fd.IsMap() {
+		return errorf(r.pos, "field %q is repeated and cannot be filtered", r.Field)
+	}
+
+	kind, err := kindOf(fd)
+	if err != nil {
+		return errorf(r.pos, "field %q %s", r.Field, err)
+	}
+
+	r.kind = kind
+
+	if len(r.Values) > 1 && r.Comparator != ":" {
+		return errorf(r.pos, "a list of values may only be used with the \":\" comparator")
+	}
+
+	switch r.Comparator {
+	case "<", "<=", ">", ">=":
+		if kind == kindBool || kind == kindEnum {
+			return errorf(r.pos, "field %q does not support the %q comparator", r.Field, r.Comparator)
+		}
+	}
+
+	r.args = make([]interface{}, len(r.Values))
+
+	for i, v := range r.Values {
+		arg, err := convert(v, kind, fd)
+		if err != nil {
+			return errorf(v.pos, "invalid value %q for field %q: %s", v.Text, r.Field, err)
+		}
+
+		r.args[i] = arg
+	}
+
+	return nil
+}
+
+// kindOf resolves the comparison type of a field.
+func kindOf(fd protoreflect.FieldDescriptor) (fieldKind, error) {
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Sint32Kind, protoreflect.Sint64Kind,
+		protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		return kindInt, nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return kindFloat, nil
+	case protoreflect.StringKind:
+		return kindString, nil
+	case protoreflect.BoolKind:
+		return kindBool, nil
+	case protoreflect.EnumKind:
+		return kindEnum, nil
+	case protoreflect.MessageKind:
+		if fd.Message().FullName() == timestampName {
+			return kindTimestamp, nil
+		}
+	}
+
+	return 0, errors.New("is of an unsupported type")
+}
+
+// convert parses a literal as the given kind.
+func convert(v Value, kind fieldKind, fd protoreflect.FieldDescriptor) (interface{}, error) {
+	switch kind {
+	case kindInt:
+		i, err := strconv.ParseInt(v.Text, 10, 64)
+		if err != nil {
+			return nil, errors.New("expected an integer")
+		}
+
+		return i, nil
+
+	case kindFloat:
+		f, err := strconv.ParseFloat(v.Text, 64)
+		if err != nil {
+			return nil, errors.New("expected a number")
+		}
+
+		return f, nil
+
+	case kindString:
+		return v.Text, nil
+
+	case kindBool:
+		b, err := strconv.ParseBool(v.Text)
+		if err != nil {
+			return nil, errors.New("expected true or false")
+		}
+
+		return b, nil
+
+	case kindEnum:
+		ev := fd.Enum().Values().ByName(protoreflect.Name(strings.ToUpper(v.Text)))
+		if ev == nil {
+			return nil, errors.New("not a value of " + string(fd.Enum().Name()))
+		}
+
+		return string(ev.Name()), nil
+
+	case kindTimestamp:
+		t, err := time.Parse(time.RFC3339Nano, v.Text)
+		if err != nil {
+			return nil, errors.New("expected an RFC 3339 timestamp")
+		}
+
+		return t, nil
+	}
+
+	return nil, errors.New("unsupported type")
+}
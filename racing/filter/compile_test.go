@@ -0,0 +1,145 @@
+package filter_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/racing/filter"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+var raceColumns = map[string]filter.Column{
+	"id":                    {Expr: "id"},
+	"meeting_id":            {Expr: "meeting_id"},
+	"name":                  {Expr: "name"},
+	"visible":               {Expr: "visible"},
+	"status":                {Expr: "status"},
+	"advertised_start_time": {Expr: "start"},
+}
+
+func TestCompile(t *testing.T) {
+	start := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		src    string
+		clause string
+		args   []interface{}
+	}{
+		{name: "empty", src: "", clause: "", args: nil},
+		{name: "whitespace", src: "  ", clause: "", args: nil},
+		{name: "bool", src: "visible = true", clause: "visible = ?", args: []interface{}{true}},
+		{name: "has", src: "id:3", clause: "id = ?", args: []interface{}{int64(3)}},
+		{name: "negative number", src: "id > -1", clause: "id > ?", args: []interface{}{int64(-1)}},
+		{name: "list", src: "meeting_id:(1, 2,3)", clause: "meeting_id IN (?,?,?)", args: []interface{}{int64(1), int64(2), int64(3)}},
+		{name: "enum", src: "status = OPEN", clause: "status = ?", args: []interface{}{"OPEN"}},
+		{name: "timestamp", src: `advertised_start_time >= "2026-10-18T00:00:00Z"`, clause: "start >= ?", args: []interface{}{start}},
+		{name: "string", src: `name = 'Melbourne Cup'`, clause: "name = ?", args: []interface{}{"Melbourne Cup"}},
+		{name: "escaped quote", src: `name = "a \"b\""`, clause: "name = ?", args: []interface{}{`a "b"`}},
+		{name: "wildcard", src: `name = "Cup*"`, clause: `name LIKE ? ESCAPE '\'`, args: []interface{}{"Cup%"}},
+		{name: "negated wildcard escapes LIKE metacharacters", src: `name != "50%_*"`, clause: `name NOT LIKE ? ESCAPE '\'`, args: []interface{}{`50\%\_%`}},
+		{name: "and", src: "id = 1 AND visible = true", clause: "(id = ? AND visible = ?)", args: []interface{}{int64(1), true}},
+		{name: "implicit and", src: "id = 1 visible = true", clause: "(id = ? AND visible = ?)", args: []interface{}{int64(1), true}},
+		{
+			name:   "or binds tighter than and",
+			src:    "id = 1 AND id = 2 OR id = 3",
+			clause: "(id = ? AND (id = ? OR id = ?))",
+			args:   []interface{}{int64(1), int64(2), int64(3)},
+		},
+		{
+			name:   "parentheses",
+			src:    "(id = 1 AND id = 2) OR id = 3",
+			clause: "((id = ? AND id = ?) OR id = ?)",
+			args:   []interface{}{int64(1), int64(2), int64(3)},
+		},
+		{name: "not", src: "NOT visible = true", clause: "NOT visible = ?", args: []interface{}{true}},
+		{name: "minus", src: "-id = 1", clause: "NOT id = ?", args: []interface{}{int64(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, args, err := filter.Compile(tt.src, (&racing.Race{}).ProtoReflect().Descriptor(), raceColumns)
+			if err != nil {
+				t.Fatalf("Compile(%q) returned error: %v", tt.src, err)
+			}
+
+			if clause != tt.clause {
+				t.Errorf("Compile(%q) clause = %q, want %q", tt.src, clause, tt.clause)
+			}
+
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("Compile(%q) args = %#v, want %#v", tt.src, args, tt.args)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		offset int
+		msg    string
+	}{
+		{name: "missing value", src: "id = ", offset: 5, msg: "unexpected end of filter"},
+		{name: "bang", src: "id ! 1", offset: 3, msg: `did you mean "!="`},
+		{name: "unterminated string", src: `name = "Cup`, offset: 7, msg: "unterminated string"},
+		{name: "unclosed parenthesis", src: "(id = 1", offset: 7, msg: `expected ")"`},
+		{name: "trailing token", src: "id = 1)", offset: 6, msg: `unexpected ")"`},
+		{name: "free text", src: "Cup", offset: 0, msg: "free text search is not supported"},
+		{name: "unknown field", src: "colour = 1", offset: 0, msg: `unknown field "colour"`},
+		{name: "repeated field", src: "runners = 1", offset: 0, msg: "is repeated"},
+		{name: "not a number", src: "id = abc", offset: 5, msg: "expected an integer"},
+		{name: "not a bool", src: "visible = yes", offset: 10, msg: "expected true or false"},
+		{name: "not an enum value", src: "status = SHUT", offset: 9, msg: "not a value of"},
+		{name: "not a timestamp", src: `advertised_start_time > "today"`, offset: 24, msg: "RFC 3339"},
+		{name: "ordered bool", src: "visible > true", offset: 0, msg: `does not support the ">" comparator`},
+		{name: "list without has", src: "id = (1, 2)", offset: 0, msg: `only be used with the ":" comparator`},
+		{name: "field without column", src: "number = 1", offset: 0, msg: "cannot be filtered"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := filter.Compile(tt.src, (&racing.Race{}).ProtoReflect().Descriptor(), raceColumns)
+
+			var ferr *filter.Error
+			if !errors.As(err, &ferr) {
+				t.Fatalf("Compile(%q) error = %v, want a *filter.Error", tt.src, err)
+			}
+
+			if ferr.Offset != tt.offset || !strings.Contains(ferr.Msg, tt.msg) {
+				t.Errorf("Compile(%q) error = %q at offset %d, want %q at offset %d", tt.src, ferr.Msg, ferr.Offset, tt.msg, tt.offset)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	nested := func(depth int) string {
+		return strings.Repeat("(", depth) + "id = 1" + strings.Repeat(")", depth)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		ok   bool
+	}{
+		{name: "maximum depth", src: nested(filter.MaxDepth), ok: true},
+		{name: "too deep", src: nested(filter.MaxDepth + 1)},
+		{name: "unbalanced and far too deep", src: strings.Repeat("(", filter.MaxLength)},
+		{name: "maximum length", src: "id = 1" + strings.Repeat(" ", filter.MaxLength-6), ok: true},
+		{name: "too long", src: "id = 1" + strings.Repeat(" ", filter.MaxLength-5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := filter.Parse(tt.src)
+			if ok := err == nil; ok != tt.ok {
+				t.Errorf("Parse() error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
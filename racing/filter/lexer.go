@@ -0,0 +1,160 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the type of a lexical token.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenComparator
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+// token is a single lexical token of a filter expression.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits a filter expression into tokens.
+func lex(src string) ([]token, error) {
+	var (
+		tokens []token
+		runes  = []rune(src)
+	)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+
+		case r == '=' || r == ':':
+			tokens = append(tokens, token{kind: tokenComparator, text: string(r), pos: i})
+			i++
+
+		case r == '!' || r == '<' || r == '>':
+			start := i
+			i++
+
+			if i < len(runes) && runes[i] == '=' {
+				i++
+			}
+
+			op := string(runes[start:i])
+			if op == "!" {
+				return nil, errorf(start, "unexpected %q, did you mean \"!=\"?", op)
+			}
+
+			tokens = append(tokens, token{kind: tokenComparator, text: op, pos: start})
+
+		case r == '"' || r == '\'':
+			text, end, err := lexString(runes, i)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i = end
+
+		case r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]), unicode.IsDigit(r):
+			start := i
+			i++
+
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+
+		case r == '-':
+			// A leading minus is shorthand for NOT.
+			tokens = append(tokens, token{kind: tokenNot, text: "-", pos: i})
+			i++
+
+		case isIdentRune(r):
+			start := i
+
+			for i < len(runes) && isIdentRune(runes[i]) {
+				i++
+			}
+
+			text := string(runes[start:i])
+
+			switch text {
+			case "AND":
+				tokens = append(tokens, token{kind: tokenAnd, text: text, pos: start})
+			case "OR":
+				tokens = append(tokens, token{kind: tokenOr, text: text, pos: start})
+			case "NOT":
+				tokens = append(tokens, token{kind: tokenNot, text: text, pos: start})
+			default:
+				tokens = append(tokens, token{kind: tokenIdent, text: text, pos: start})
+			}
+
+		default:
+			return nil, errorf(i, "unexpected character %q", r)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// lexString reads a quoted string starting at runes[start], returning its unescaped
+// contents and the index following the closing quote.
+func lexString(runes []rune, start int) (string, int, error) {
+	var (
+		quote = runes[start]
+		sb    strings.Builder
+	)
+
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 >= len(runes) {
+				return "", 0, errorf(i, "unterminated escape sequence")
+			}
+
+			i++
+			sb.WriteRune(runes[i])
+
+		case quote:
+			return sb.String(), i + 1, nil
+
+		default:
+			sb.WriteRune(runes[i])
+		}
+	}
+
+	return "", 0, errorf(start, "unterminated string")
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '.' || r == '*' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,236 @@
+package filter
+
+// Limits bounding the work done parsing an expression, so that a hostile filter cannot exhaust
+// the stack of the recursive descent parser.
+const (
+	// MaxLength is the maximum length of an expression, in bytes.
+	MaxLength = 4096
+	// MaxDepth is the maximum nesting depth of parenthesised expressions.
+	MaxDepth = 64
+)
+
+// Parse parses a filter expression. An empty expression yields a nil Expr. Expressions longer
+// than MaxLength, or nested deeper than MaxDepth, are rejected.
+//
+// The supported grammar is:
+//
+//	expression  = sequence { "AND" sequence }
+//	sequence    = factor { factor }
+//	factor      = term { "OR" term }
+//	term        = [ "NOT" | "-" ] simple
+//	simple      = restriction | "(" expression ")"
+//	restriction = field comparator arg
+//	arg         = value | "(" value { "," value } ")"
+func Parse(src string) (Expr, error) {
+	if len(src) > MaxLength {
+		return nil, errorf(MaxLength, "filter is longer than %d bytes", MaxLength)
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	if p.peek().kind == tokenEOF {
+		return nil, nil
+	}
+
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, errorf(tok.pos, "unexpected %q", tok.text)
+	}
+
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	// depth is the number of parenthesised expressions being parsed.
+	depth int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+
+	return tok
+}
+
+func (p *parser) expression() (Expr, error) {
+	left, err := p.sequence()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenAnd {
+		p.next()
+
+		right, err := p.sequence()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &And{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) sequence() (Expr, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	// Restrictions separated only by whitespace are implicitly combined with AND.
+	for startsTerm(p.peek()) {
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &And{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) factor() (Expr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		p.next()
+
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &Or{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) term() (Expr, error) {
+	if tok := p.peek(); tok.kind == tokenNot {
+		p.next()
+
+		expr, err := p.simple()
+		if err != nil {
+			return nil, err
+		}
+
+		return &Not{Expr: expr, pos: tok.pos}, nil
+	}
+
+	return p.simple()
+}
+
+func (p *parser) simple() (Expr, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenLParen:
+		if p.depth++; p.depth > MaxDepth {
+			return nil, errorf(tok.pos, "filter is nested deeper than %d parentheses", MaxDepth)
+		}
+
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+
+		p.depth--
+
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, errorf(closing.pos, "expected \")\"")
+		}
+
+		return expr, nil
+
+	case tokenIdent:
+		return p.restriction(tok)
+
+	case tokenEOF:
+		return nil, errorf(tok.pos, "unexpected end of filter")
+	}
+
+	return nil, errorf(tok.pos, "unexpected %q, expected a field or \"(\"", tok.text)
+}
+
+func (p *parser) restriction(field token) (Expr, error) {
+	comparator := p.next()
+	if comparator.kind != tokenComparator {
+		return nil, errorf(field.pos, "field %q must be followed by a comparator; free text search is not supported", field.text)
+	}
+
+	restriction := &Restriction{Field: field.text, Comparator: comparator.text, pos: field.pos}
+
+	if p.peek().kind != tokenLParen {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		restriction.Values = []Value{value}
+
+		return restriction, nil
+	}
+
+	p.next()
+
+	for {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		restriction.Values = append(restriction.Values, value)
+
+		switch tok := p.next(); tok.kind {
+		case tokenComma:
+			continue
+		case tokenRParen:
+			return restriction, nil
+		default:
+			return nil, errorf(tok.pos, "expected \",\" or \")\" in value list")
+		}
+	}
+}
+
+func (p *parser) value() (Value, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenString:
+		return Value{Text: tok.text, Quoted: true, pos: tok.pos}, nil
+	case tokenIdent, tokenNumber:
+		return Value{Text: tok.text, pos: tok.pos}, nil
+	case tokenEOF:
+		return Value{}, errorf(tok.pos, "unexpected end of filter, expected a value")
+	}
+
+	return Value{}, errorf(tok.pos, "unexpected %q, expected a value", tok.text)
+}
+
+// startsTerm reports whether a token may begin a term.
+func startsTerm(tok token) bool {
+	return tok.kind == tokenIdent || tok.kind == tokenLParen || tok.kind == tokenNot
+}
@@ -0,0 +1,129 @@
+package filter
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Column maps a message field onto SQL.
+type Column struct {
+	// Expr is the SQL expression holding the field's value.
+	Expr string
+	// Arg optionally converts a checked value into a query argument, e.g. to format a
+	// time.Time the way the column stores it.
+	Arg func(v interface{}) interface{}
+}
+
+// Compile parses and type-checks a filter expression against a message, then translates it
+// into a parameterised SQL condition. Only fields present in columns may be restricted. An
+// empty expression compiles to an empty condition.
+func Compile(src string, md protoreflect.MessageDescriptor, columns map[string]Column) (string, []interface{}, error) {
+	expr, err := Parse(src)
+	if err != nil || expr == nil {
+		return "", nil, err
+	}
+
+	if err := Check(expr, md); err != nil {
+		return "", nil, err
+	}
+
+	var args []interface{}
+
+	clause, err := toSQL(expr, columns, &args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return clause, args, nil
+}
+
+func toSQL(expr Expr, columns map[string]Column, args *[]interface{}) (string, error) {
+	switch e := expr.(type) {
+	case *And:
+		return binaryToSQL(e.Left, e.Right, " AND ", columns, args)
+	case *Or:
+		return binaryToSQL(e.Left, e.Right, " OR ", columns, args)
+	case *Not:
+		inner, err := toSQL(e.Expr, columns, args)
+		if err != nil {
+			return "", err
+		}
+
+		return "NOT " + inner, nil
+	case *Restriction:
+		return restrictionToSQL(e, columns, args)
+	}
+
+	return "", errorf(expr.Pos(), "unsupported expression")
+}
+
+func binaryToSQL(left, right Expr, op string, columns map[string]Column, args *[]interface{}) (string, error) {
+	l, err := toSQL(left, columns, args)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := toSQL(right, columns, args)
+	if err != nil {
+		return "", err
+	}
+
+	return "(" + l + op + r + ")", nil
+}
+
+func restrictionToSQL(r *Restriction, columns map[string]Column, args *[]interface{}) (string, error) {
+	if r.args == nil {
+		return "", errorf(r.pos, "restriction on %q has not been type-checked", r.Field)
+	}
+
+	col, ok := columns[r.Field]
+	if !ok {
+		return "", errorf(r.pos, "field %q cannot be filtered", r.Field)
+	}
+
+	arg := func(v interface{}) interface{} {
+		if col.Arg != nil {
+			return col.Arg(v)
+		}
+
+		return v
+	}
+
+	// Strings containing a * are matched as wildcards, per AIP-160.
+	if r.kind == kindString && len(r.args) == 1 && (r.Comparator == "=" || r.Comparator == "!=" || r.Comparator == ":") {
+		if pattern, ok := r.args[0].(string); ok && strings.Contains(pattern, "*") {
+			*args = append(*args, arg(likePattern(pattern)))
+
+			if r.Comparator == "!=" {
+				return col.Expr + ` NOT LIKE ? ESCAPE '\'`, nil
+			}
+
+			return col.Expr + ` LIKE ? ESCAPE '\'`, nil
+		}
+	}
+
+	if len(r.args) > 1 {
+		for _, v := range r.args {
+			*args = append(*args, arg(v))
+		}
+
+		return col.Expr + " IN (" + strings.Repeat("?,", len(r.args)-1) + "?)", nil
+	}
+
+	*args = append(*args, arg(r.args[0]))
+
+	op := r.Comparator
+	if op == ":" {
+		op = "="
+	}
+
+	return col.Expr + " " + op + " ?", nil
+}
+
+// likePattern converts a wildcard pattern into an SQL LIKE pattern, escaping LIKE metacharacters.
+func likePattern(pattern string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`)
+
+	return replacer.Replace(pattern)
+}
@@ -0,0 +1,424 @@
+// Package jump keeps an in-memory index of the races next to jump: the visible races open for
+// betting across all meetings, soonest to start first.
+//
+// The index is loaded from the races repository once, then kept up to date from the changes it
+// publishes, so that reading the index never scans the races table. Races that are open only
+// because their advertised start time has yet to pass close without a change being published;
+// they are re-read as they are passed over, once their start time has passed.
+package jump
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/net/context"
+
+	"git.neds.sh/matty/entain/logging"
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/watch"
+)
+
+const (
+	// changesBufferSize is the number of race changes buffered before the index is considered
+	// too slow to keep up, and reloaded.
+	changesBufferSize = 1024
+	// loadPageSize is the number of races read at a time while loading the index.
+	loadPageSize = 500
+	// reloadDelay is how long to wait before reloading an index that failed to apply a change.
+	reloadDelay = time.Second
+)
+
+// nextToJump selects the races the index holds.
+var nextToJump = db.RaceFilter{
+	Request:     &racing.ListRacesRequestFilter{Statuses: []racing.Race_Status{racing.Race_OPEN}},
+	VisibleOnly: true,
+}
+
+// Filter restricts the races read from the index. Empty fields match any race.
+type Filter struct {
+	// RaceTypes restricts races to those run at meetings of any of the race types.
+	RaceTypes []racing.Meeting_RaceType
+	// Jurisdictions restricts races to those run at meetings in any of the jurisdictions.
+	Jurisdictions []string
+}
+
+// matches reports whether a race, with its meeting embedded, passes the filter.
+func (f Filter) matches(race *racing.Race) bool {
+	if len(f.RaceTypes) > 0 {
+		if race.Meeting == nil || !containsRaceType(f.RaceTypes, race.Meeting.RaceType) {
+			return false
+		}
+	}
+
+	if len(f.Jurisdictions) > 0 {
+		if race.Meeting == nil || !containsString(f.Jurisdictions, race.Meeting.Jurisdiction) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// entry is a race held by the index.
+type entry struct {
+	race  *racing.Race
+	start time.Time
+	// readAt is when the race was read from the repository. A race read after its start time
+	// that was still open has had its status set explicitly, so stays open until changed.
+	readAt time.Time
+}
+
+// lapsed reports whether the race may have closed since it was read, its start time having
+// passed in between.
+func (e *entry) lapsed(now time.Time) bool {
+	return e.readAt.Before(e.start) && !now.Before(e.start)
+}
+
+// before orders entries by start time, then ID.
+func (e *entry) before(other *entry) bool {
+	if !e.start.Equal(other.start) {
+		return e.start.Before(other.start)
+	}
+
+	return e.race.Id < other.race.Id
+}
+
+// Index holds the races next to jump, with their meetings.
+type Index struct {
+	races    db.RacesRepo
+	meetings db.MeetingsRepo
+	sub      *watch.Subscription
+
+	mu        sync.RWMutex
+	byID      map[int64]*entry
+	ordered   []*entry
+	meetingOf map[int64]*racing.Meeting
+
+	// reads numbers the reads of races from the repository in the order they start. A read of a
+	// race is only applied if it started after the last reload, and after any read of the race
+	// already applied since, so that a slow read never undoes the change a later one saw.
+	reads      uint64
+	loadRead   uint64
+	readOfRace map[int64]uint64
+}
+
+// NewIndex creates an empty index of the races in the given repositories.
+func NewIndex(races db.RacesRepo, meetings db.MeetingsRepo) *Index {
+	return &Index{
+		races:      races,
+		meetings:   meetings,
+		byID:       make(map[int64]*entry),
+		meetingOf:  make(map[int64]*racing.Meeting),
+		readOfRace: make(map[int64]uint64),
+	}
+}
+
+// Load subscribes to changes to races and loads the index. It must be called once, before Run.
+func (i *Index) Load(ctx context.Context) error {
+	// Subscribe before loading, so that no change made in between is missed.
+	i.sub = i.races.Watch(changesBufferSize)
+
+	return i.reload(ctx)
+}
+
+// Run applies changes to races to the index until the context is cancelled. Should the index
+// fall behind, or fail to apply a change, it is reloaded.
+func (i *Index) Run(ctx context.Context) {
+	defer i.sub.Close()
+
+	var retry <-chan time.Time
+
+	for {
+		var err error
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-i.sub.Overflow():
+			// Buffered changes are superseded by the reload.
+			i.sub.Drain()
+			err = i.reload(ctx)
+
+		case <-retry:
+			retry = nil
+			err = i.reload(ctx)
+
+		case change := <-i.sub.Changes():
+			err = i.refresh(ctx, change.ID)
+		}
+
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Warnf("next to jump: updating index failed, reloading in %s", reloadDelay)
+			retry = time.After(reloadDelay)
+		}
+	}
+}
+
+// Next returns up to n races next to jump that pass the filter, soonest first. The races are
+// copies, so may be modified by the caller.
+func (i *Index) Next(ctx context.Context, n int, filter Filter) ([]*racing.Race, error) {
+	for {
+		races, lapsed := i.next(n, filter, time.Now())
+		if len(lapsed) == 0 {
+			return races, nil
+		}
+
+		// Races whose start time has passed are re-read, dropping those that have closed, and
+		// the index read again.
+		for _, id := range lapsed {
+			if err := i.refresh(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// next reads up to n races passing the filter from the index, returning the IDs of any lapsed
+// races passed over instead of the races should there be any.
+func (i *Index) next(n int, filter Filter, now time.Time) ([]*racing.Race, []int64) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var (
+		races  []*racing.Race
+		lapsed []int64
+	)
+
+	for _, e := range i.ordered {
+		if len(races) == n {
+			break
+		}
+
+		if !filter.matches(e.race) {
+			continue
+		}
+
+		if e.lapsed(now) {
+			lapsed = append(lapsed, e.race.Id)
+			continue
+		}
+
+		races = append(races, proto.Clone(e.race).(*racing.Race))
+	}
+
+	if len(lapsed) > 0 {
+		return nil, lapsed
+	}
+
+	return races, nil
+}
+
+// reload replaces the contents of the index with the races next to jump.
+func (i *Index) reload(ctx context.Context) error {
+	read := i.startRead()
+	now := time.Now()
+
+	var races []*racing.Race
+
+	opts := db.ListOptions{PageSize: loadPageSize}
+
+	for {
+		result, err := i.races.List(ctx, nextToJump, opts)
+		if err != nil {
+			return err
+		}
+
+		races = append(races, result.Races...)
+
+		if result.NextPageToken == "" {
+			break
+		}
+
+		opts.PageToken = result.NextPageToken
+	}
+
+	var ids []int64
+
+	seen := make(map[int64]bool)
+	for _, race := range races {
+		if !seen[race.MeetingId] {
+			seen[race.MeetingId] = true
+			ids = append(ids, race.MeetingId)
+		}
+	}
+
+	meetings, err := i.meetings.GetMany(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int64]*entry, len(races))
+	ordered := make([]*entry, 0, len(races))
+
+	for _, race := range races {
+		race.Meeting = meetings[race.MeetingId]
+
+		e, err := newEntry(race, now)
+		if err != nil {
+			return err
+		}
+
+		byID[race.Id] = e
+		ordered = append(ordered, e)
+	}
+
+	sort.Slice(ordered, func(a, b int) bool { return ordered[a].before(ordered[b]) })
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.byID = byID
+	i.ordered = ordered
+	i.meetingOf = meetings
+	i.loadRead = read
+	i.readOfRace = make(map[int64]uint64)
+
+	return nil
+}
+
+// refresh re-reads a race, adding it to the index if it is next to jump and removing it if not.
+// Should a read of the race that started later have been applied in the meantime, the race is
+// left as that read found it.
+func (i *Index) refresh(ctx context.Context, id int64) error {
+	read := i.startRead()
+	now := time.Now()
+
+	race, err := i.races.GetMatching(ctx, id, nextToJump)
+	if errors.Is(err, db.ErrNotFound) {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+
+		if i.applyRead(id, read) {
+			i.remove(id)
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if race.Meeting, err = i.meeting(ctx, race.MeetingId); err != nil {
+		return err
+	}
+
+	e, err := newEntry(race, now)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.applyRead(id, read) {
+		i.remove(id)
+		i.insert(e)
+	}
+
+	return nil
+}
+
+// startRead numbers a read of races from the repository that is about to start.
+func (i *Index) startRead() uint64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.reads++
+
+	return i.reads
+}
+
+// applyRead reports whether a read of a race is the latest to have started of those applied,
+// recording it as applied if so. The caller must hold the write lock.
+func (i *Index) applyRead(id int64, read uint64) bool {
+	if read < i.loadRead || read < i.readOfRace[id] {
+		return false
+	}
+
+	i.readOfRace[id] = read
+
+	return true
+}
+
+// meeting returns a meeting, read from the repository the first time it is needed, or nil if it
+// does not exist. Meetings are not changed once created, so are held for as long as the index.
+func (i *Index) meeting(ctx context.Context, id int64) (*racing.Meeting, error) {
+	i.mu.RLock()
+	meeting, ok := i.meetingOf[id]
+	i.mu.RUnlock()
+
+	if ok {
+		return meeting, nil
+	}
+
+	meeting, err := i.meetings.Get(ctx, id)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return nil, err
+	}
+
+	i.mu.Lock()
+	i.meetingOf[id] = meeting
+	i.mu.Unlock()
+
+	return meeting, nil
+}
+
+// insert adds an entry to the index, in order. The caller must hold the write lock.
+func (i *Index) insert(e *entry) {
+	at := sort.Search(len(i.ordered), func(k int) bool { return e.before(i.ordered[k]) })
+
+	i.ordered = append(i.ordered, nil)
+	copy(i.ordered[at+1:], i.ordered[at:])
+	i.ordered[at] = e
+	i.byID[e.race.Id] = e
+}
+
+// remove drops a race from the index, if it is held. The caller must hold the write lock.
+func (i *Index) remove(id int64) {
+	e, ok := i.byID[id]
+	if !ok {
+		return
+	}
+
+	delete(i.byID, id)
+
+	at := sort.Search(len(i.ordered), func(k int) bool { return !i.ordered[k].before(e) })
+	i.ordered = append(i.ordered[:at], i.ordered[at+1:]...)
+}
+
+// newEntry builds the entry of a race read at the given time.
+func newEntry(race *racing.Race, read time.Time) (*entry, error) {
+	start, err := ptypes.Timestamp(race.AdvertisedStartTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry{race: race, start: start, readAt: read}, nil
+}
+
+func containsRaceType(raceTypes []racing.Meeting_RaceType, raceType racing.Meeting_RaceType) bool {
+	for _, t := range raceTypes {
+		if t == raceType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,315 @@
+package jump
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/net/context"
+
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/watch"
+)
+
+// racesStub is a races repository holding races in memory, matching any filter as the races
+// next to jump. Methods other than those the index calls are left unimplemented.
+type racesStub struct {
+	db.RacesRepo
+	broker *watch.Broker
+
+	mu    sync.Mutex
+	races map[int64]*racing.Race
+	// read, if set, is called by GetMatching once it has read a race, before returning it.
+	read func(id int64)
+}
+
+func newRacesStub(races ...*racing.Race) *racesStub {
+	r := &racesStub{broker: watch.NewBroker(), races: make(map[int64]*racing.Race)}
+	for _, race := range races {
+		r.races[race.Id] = race
+	}
+
+	return r
+}
+
+// set replaces a race, as a trader changing it would.
+func (r *racesStub) set(race *racing.Race) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.races[race.Id] = race
+}
+
+func (r *racesStub) List(ctx context.Context, filter db.RaceFilter, opts db.ListOptions) (*db.ListResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var races []*racing.Race
+	for _, race := range r.races {
+		if nextToJumpStub(race) {
+			races = append(races, cloneRace(race))
+		}
+	}
+
+	return &db.ListResult{Races: races, TotalSize: int32(len(races))}, nil
+}
+
+func (r *racesStub) GetMatching(ctx context.Context, id int64, filter db.RaceFilter) (*racing.Race, error) {
+	r.mu.Lock()
+	race, ok := r.races[id]
+	if ok {
+		race = cloneRace(race)
+	}
+	read := r.read
+	r.mu.Unlock()
+
+	if read != nil {
+		read(id)
+	}
+
+	if !ok || !nextToJumpStub(race) {
+		return nil, fmt.Errorf("race %d %w", id, db.ErrNotFound)
+	}
+
+	return race, nil
+}
+
+func (r *racesStub) Watch(buffer int) *watch.Subscription {
+	return r.broker.Subscribe(buffer)
+}
+
+// nextToJumpStub matches races as the nextToJump filter does.
+func nextToJumpStub(race *racing.Race) bool {
+	return race.Visible && race.Status == racing.Race_OPEN
+}
+
+func cloneRace(race *racing.Race) *racing.Race {
+	return proto.Clone(race).(*racing.Race)
+}
+
+// meetingsStub holds meeting 1, at Flemington in VIC, and meeting 2, at Randwick in NSW.
+type meetingsStub struct {
+	db.MeetingsRepo
+}
+
+var meetings = map[int64]*racing.Meeting{
+	1: {Id: 1, Name: "Flemington", Jurisdiction: "VIC", RaceType: racing.Meeting_THOROUGHBRED},
+	2: {Id: 2, Name: "Randwick", Jurisdiction: "NSW", RaceType: racing.Meeting_THOROUGHBRED},
+}
+
+func (meetingsStub) Get(ctx context.Context, id int64) (*racing.Meeting, error) {
+	meeting, ok := meetings[id]
+	if !ok {
+		return nil, fmt.Errorf("meeting %d %w", id, db.ErrNotFound)
+	}
+
+	return meeting, nil
+}
+
+func (meetingsStub) GetMany(ctx context.Context, ids []int64) (map[int64]*racing.Meeting, error) {
+	found := make(map[int64]*racing.Meeting)
+	for _, id := range ids {
+		if meeting, ok := meetings[id]; ok {
+			found[id] = meeting
+		}
+	}
+
+	return found, nil
+}
+
+// openRace returns a visible open race of a meeting, starting after a delay.
+func openRace(t *testing.T, id, meetingID int64, delay time.Duration) *racing.Race {
+	t.Helper()
+
+	start, err := ptypes.TimestampProto(time.Now().Add(delay))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &racing.Race{Id: id, MeetingId: meetingID, Visible: true, Status: racing.Race_OPEN, AdvertisedStartTime: start}
+}
+
+// closed returns a copy of a race, closed.
+func closed(race *racing.Race) *racing.Race {
+	race = cloneRace(race)
+	race.Status = racing.Race_CLOSED
+
+	return race
+}
+
+// newLoadedIndex returns an index of the races in a repository, loaded.
+func newLoadedIndex(t *testing.T, races *racesStub) *Index {
+	t.Helper()
+
+	index := NewIndex(races, meetingsStub{})
+	if err := index.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(index.sub.Close)
+
+	return index
+}
+
+// ids returns the IDs of the races next to jump, soonest first.
+func ids(t *testing.T, index *Index, n int, filter Filter) []int64 {
+	t.Helper()
+
+	races, err := index.Next(context.Background(), n, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]int64, len(races))
+	for k, race := range races {
+		ids[k] = race.Id
+	}
+
+	return ids
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k := range a {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestNext(t *testing.T) {
+	races := newRacesStub(
+		openRace(t, 1, 1, 3*time.Hour),
+		openRace(t, 2, 2, time.Hour),
+		openRace(t, 3, 1, 2*time.Hour),
+		closed(openRace(t, 4, 1, 30*time.Minute)),
+	)
+
+	index := newLoadedIndex(t, races)
+
+	tests := []struct {
+		name   string
+		n      int
+		filter Filter
+		want   []int64
+	}{
+		{name: "soonest first", n: 10, want: []int64{2, 3, 1}},
+		{name: "limited", n: 2, want: []int64{2, 3}},
+		{name: "by jurisdiction", n: 10, filter: Filter{Jurisdictions: []string{"VIC"}}, want: []int64{3, 1}},
+		{name: "by race type", n: 10, filter: Filter{RaceTypes: []racing.Meeting_RaceType{racing.Meeting_GREYHOUND}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(t, index, tt.n, tt.filter); !equalIDs(got, tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	race := openRace(t, 1, 1, time.Hour)
+	races := newRacesStub(race)
+	index := newLoadedIndex(t, races)
+	ctx := context.Background()
+
+	races.set(closed(race))
+	if err := index.refresh(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ids(t, index, 10, Filter{}); len(got) != 0 {
+		t.Errorf("Next() once closed = %v, want none", got)
+	}
+
+	races.set(race)
+	races.set(openRace(t, 2, 2, 2*time.Hour))
+
+	for _, id := range []int64{1, 2} {
+		if err := index.refresh(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := ids(t, index, 10, Filter{}), []int64{1, 2}; !equalIDs(got, want) {
+		t.Errorf("Next() once reopened = %v, want %v", got, want)
+	}
+}
+
+func TestNextLapsed(t *testing.T) {
+	race := openRace(t, 1, 1, 50*time.Millisecond)
+	races := newRacesStub(race, openRace(t, 2, 1, time.Hour))
+	index := newLoadedIndex(t, races)
+
+	// The race closes as its start time passes, which publishes no change.
+	races.set(closed(race))
+	time.Sleep(100 * time.Millisecond)
+
+	if got, want := ids(t, index, 10, Filter{}), []int64{2}; !equalIDs(got, want) {
+		t.Errorf("Next() = %v, want %v", got, want)
+	}
+}
+
+func TestRefreshStaleRead(t *testing.T) {
+	tests := []struct {
+		name string
+		// update applies the closing of the race to the index while the stale read is in flight.
+		update func(ctx context.Context, index *Index) error
+	}{
+		{name: "later refresh", update: func(ctx context.Context, index *Index) error { return index.refresh(ctx, 1) }},
+		{name: "later reload", update: func(ctx context.Context, index *Index) error { return index.reload(ctx) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			race := openRace(t, 1, 1, time.Hour)
+			races := newRacesStub(race)
+			index := newLoadedIndex(t, races)
+
+			var (
+				read    = make(chan struct{})
+				proceed = make(chan struct{})
+				done    = make(chan error)
+			)
+
+			// The stale read finds the race open, then stalls until it has closed.
+			races.read = func(int64) {
+				races.mu.Lock()
+				races.read = nil
+				races.mu.Unlock()
+
+				close(read)
+				<-proceed
+			}
+
+			go func() { done <- index.refresh(ctx, 1) }()
+
+			<-read
+
+			races.set(closed(race))
+			if err := tt.update(ctx, index); err != nil {
+				t.Fatal(err)
+			}
+
+			close(proceed)
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+
+			if got := ids(t, index, 10, Filter{}); len(got) != 0 {
+				t.Errorf("Next() = %v, want the closed race left out", got)
+			}
+		})
+	}
+}
@@ -2,58 +2,232 @@ package main
 
 import (
 	"database/sql"
-	"flag"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/config"
+	"git.neds.sh/matty/entain/logging"
 	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/jump"
+	"git.neds.sh/matty/entain/racing/metrics"
 	"git.neds.sh/matty/entain/racing/proto/racing"
 	"git.neds.sh/matty/entain/racing/service"
+	"git.neds.sh/matty/entain/racing/tote"
+	"git.neds.sh/matty/entain/racing/watch"
+	"git.neds.sh/matty/entain/tracing"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 )
 
-var (
-	grpcEndpoint = flag.String("grpc-endpoint", "localhost:9000", "gRPC server endpoint")
-)
+// flushTimeout bounds the time spent exporting the spans yet to be exported on exit.
+const flushTimeout = 5 * time.Second
 
 func main() {
-	flag.Parse()
+	cfg := defaultConfig()
+	if err := config.Load("racing", cfg, os.Args[1:]); err != nil {
+		log.Fatalf("failed loading config: %s\n", err)
+	}
+
+	if err := logging.Setup("racing", cfg.Log); err != nil {
+		log.Fatalf("failed setting up logging: %s\n", err)
+	}
 
-	if err := run(); err != nil {
-		log.Fatalf("failed running grpc server: %s\n", err)
+	if err := run(cfg); err != nil {
+		logging.FromContext(context.Background()).WithError(err).Fatal("failed running grpc server")
 	}
 }
 
-func run() error {
-	conn, err := net.Listen("tcp", ":9000")
+func run(cfg *Config) error {
+	// The server drains on SIGTERM, or an interrupt when run from a terminal.
+	shutdown, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	flushTraces, err := tracing.Setup("racing", cfg.Tracing)
 	if err != nil {
 		return err
 	}
 
-	racingDB, err := sql.Open("sqlite3", "./db/racing.db")
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+
+		if err := flushTraces(ctx); err != nil {
+			logging.FromContext(ctx).WithError(err).Error("failed flushing traces")
+		}
+	}()
+
+	conn, err := net.Listen("tcp", cfg.GRPCEndpoint)
 	if err != nil {
 		return err
 	}
 
-	racesRepo := db.NewRacesRepo(racingDB)
-	if err := racesRepo.Init(); err != nil {
+	racingDB, err := sql.Open("sqlite3", cfg.DB.DSN)
+	if err != nil {
 		return err
 	}
 
-	grpcServer := grpc.NewServer()
+	// Changes to races, including scratchings of their runners and published results, are
+	// published to watchers.
+	raceChanges := watch.NewBroker()
+
+	if cfg.PageTokenKey == "" {
+		logging.FromContext(shutdown).Warn("page_token_key is not set, page tokens are only valid until the service restarts")
+	}
+
+	pageTokenKey, err := cfg.pageTokenKey()
+	if err != nil {
+		return err
+	}
+
+	racesRepo := db.NewRacesRepo(racingDB, raceChanges, cfg.seedOptions(), pageTokenKey)
+	meetingsRepo := db.NewMeetingsRepo(racingDB, pageTokenKey)
+	runnersRepo := db.NewRunnersRepo(racingDB, raceChanges)
+	resultsRepo := db.NewResultsRepo(racingDB, raceChanges)
+	pricesRepo := db.NewPricesRepo(racingDB, watch.NewBroker())
+	poolsRepo := db.NewPoolsRepo(racingDB)
+
+	commissions := tote.DefaultCommissions
+	if cfg.ToteCommissions != "" {
+		if commissions, err = tote.LoadCommissions(cfg.ToteCommissions); err != nil {
+			return err
+		}
+	}
+
+	// The races next to jump are held in memory, kept up to date from the changes to races.
+	nextToJump := jump.NewIndex(racesRepo, meetingsRepo)
+
+	drainer := newDrainer()
+
+	// Calls are rejected until the repositories and index are initialised, logged and counted
+	// like any other.
+	gate := &readyGate{}
+
+	opts := []grpc.ServerOption{
+		grpc.ConnectionTimeout(cfg.Timeouts.Connection),
+		grpc.KeepaliveParams(keepalive.ServerParameters{MaxConnectionIdle: cfg.Timeouts.MaxConnectionIdle}),
+		grpc.ChainUnaryInterceptor(tracing.UnaryServerInterceptor, logging.UnaryServerInterceptor, metrics.UnaryServerInterceptor, gate.UnaryInterceptor, auth.UnaryServerInterceptor),
+		grpc.ChainStreamInterceptor(tracing.StreamServerInterceptor, logging.StreamServerInterceptor, metrics.StreamServerInterceptor, gate.StreamInterceptor, auth.StreamServerInterceptor, drainer.StreamInterceptor),
+	}
+
+	if cfg.TLS.Enabled() {
+		tlsConfig, err := cfg.TLS.Config()
+		if err != nil {
+			return err
+		}
+
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	grpcServer := grpc.NewServer(opts...)
+	defer grpcServer.Stop()
+
+	// The health service reports NOT_SERVING until the database is migrated and seeded, and again
+	// once the server starts draining.
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	healthServer.SetServingStatus(racing.Racing_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
 
 	racing.RegisterRacingServer(
 		grpcServer,
 		service.NewRacingService(
 			racesRepo,
+			meetingsRepo,
+			runnersRepo,
+			resultsRepo,
+			pricesRepo,
+			poolsRepo,
+			commissions,
+			nextToJump,
 		),
 	)
 
-	log.Printf("gRPC server listening on: %s\n", *grpcEndpoint)
+	served := make(chan error, 2)
 
-	if err := grpcServer.Serve(conn); err != nil {
-		return err
+	go func() {
+		served <- grpcServer.Serve(conn)
+	}()
+
+	logger := logging.FromContext(shutdown)
+	logger.Infof("gRPC server listening on: %s", cfg.GRPCEndpoint)
+
+	if cfg.MetricsEndpoint != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler())
+
+		metricsServer := &http.Server{Addr: cfg.MetricsEndpoint, Handler: metricsMux}
+		defer metricsServer.Close()
+
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				served <- err
+			}
+		}()
+
+		logger.Infof("metrics server listening on: %s", cfg.MetricsEndpoint)
+	}
+
+	// The index is kept up to date until the server stops, serving calls as it drains.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	initialised := make(chan error, 1)
+
+	go func() {
+		initialised <- initialise(ctx, nextToJump, racesRepo, meetingsRepo, runnersRepo, resultsRepo, pricesRepo, poolsRepo)
+	}()
+
+	// The database is migrated and seeded while the server reports NOT_SERVING, which it may be
+	// stopped during.
+	for draining := false; !draining; {
+		select {
+		case err := <-served:
+			return err
+
+		case err := <-initialised:
+			if err != nil {
+				return err
+			}
+
+			go nextToJump.Run(ctx)
+			go racesRepo.PublishLapses(ctx)
+
+			gate.open()
+			healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+			healthServer.SetServingStatus(racing.Racing_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+
+		case <-shutdown.Done():
+			draining = true
+		}
 	}
 
+	logger.Infof("draining gRPC server, for up to %s", cfg.Timeouts.Shutdown)
+
+	healthServer.Shutdown()
+	drainer.drain(grpcServer, cfg.Timeouts.Shutdown)
+
+	logger.Info("gRPC server stopped")
+
 	return nil
 }
+
+// initialise migrates and seeds the repositories, then loads the races next to jump.
+func initialise(ctx context.Context, nextToJump *jump.Index, repos ...interface{ Init() error }) error {
+	for _, repo := range repos {
+		if err := repo.Init(); err != nil {
+			return err
+		}
+	}
+
+	return nextToJump.Load(ctx)
+}
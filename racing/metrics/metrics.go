@@ -0,0 +1,84 @@
+// Package metrics records the rates and latencies of the calls served by the racing service, and
+// of the queries it makes, for Prometheus to scrape.
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	handled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Total number of calls completed by the server, by method and status code.",
+	}, []string{"grpc_type", "grpc_service", "grpc_method", "grpc_code"})
+
+	handling = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Time taken by the server to complete calls, by method. Streams are timed until they end.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"grpc_type", "grpc_service", "grpc_method"})
+
+	queries = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "racing_db_query_seconds",
+		Help:    "Time taken by repository queries, by repository and query, including reading their rows.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"repo", "query"})
+)
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// UnaryServerInterceptor records each unary call.
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+	observe("unary", info.FullMethod, start, err)
+
+	return resp, err
+}
+
+// StreamServerInterceptor records each streaming call.
+func StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+
+	err := handler(srv, ss)
+	observe("server_stream", info.FullMethod, start, err)
+
+	return err
+}
+
+// ObserveQuery records the time taken by a repository query started at the given time. It is
+// intended to be deferred, e.g. defer metrics.ObserveQuery("races", "list", time.Now()).
+func ObserveQuery(repo, query string, start time.Time) {
+	queries.WithLabelValues(repo, query).Observe(time.Since(start).Seconds())
+}
+
+// observe records a call to a method, in the form /package.service/method.
+func observe(callType, fullMethod string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+
+	handled.WithLabelValues(callType, service, method, status.Code(err).String()).Inc()
+	handling.WithLabelValues(callType, service, method).Observe(time.Since(start).Seconds())
+}
+
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+
+	return "unknown", fullMethod
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// healthMethodPrefix prefixes the methods of the health service, which are served throughout.
+const healthMethodPrefix = "/grpc.health.v1.Health/"
+
+// readyGate rejects calls as Unavailable until the server is initialised: its database migrated
+// and seeded, and the races next to jump loaded. The server listens meanwhile, so that health
+// checks report it NOT_SERVING rather than failing to connect; clients retry other calls, or
+// another instance.
+type readyGate struct {
+	ready int32
+}
+
+// open lets calls through the gate.
+func (g *readyGate) open() {
+	atomic.StoreInt32(&g.ready, 1)
+}
+
+// check returns an Unavailable error for calls to a method other than a health check, until the
+// gate is open.
+func (g *readyGate) check(method string) error {
+	if atomic.LoadInt32(&g.ready) == 1 || strings.HasPrefix(method, healthMethodPrefix) {
+		return nil
+	}
+
+	return status.Error(codes.Unavailable, "the server is starting up")
+}
+
+// UnaryInterceptor rejects unary calls until the gate is open.
+func (g *readyGate) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := g.check(info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// StreamInterceptor rejects streaming calls until the gate is open.
+func (g *readyGate) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := g.check(info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// managerRoles are the roles that may manage races: create, update and delete them, move them
+// through their lifecycle, scratch runners, publish results, set prices and invest in pools.
+var managerRoles = []string{auth.RoleTrader, auth.RoleAdmin}
+
+// requireManager returns an Unauthenticated error for anonymous callers, and a PermissionDenied
+// error for callers who may not manage races.
+func requireManager(ctx context.Context) error {
+	id, ok := auth.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "authentication is required to manage races")
+	}
+
+	if !id.HasRole(managerRoles...) {
+		return status.Errorf(codes.PermissionDenied, "%q may not manage races, which requires the trader or admin role", id.Subject)
+	}
+
+	return nil
+}
+
+// canSeeHidden reports whether the caller may see races that are not visible: race managers, and
+// services, which act on races whatever their visibility, e.g. settling bets.
+func canSeeHidden(ctx context.Context) bool {
+	id, _ := auth.FromContext(ctx)
+
+	return id.HasRole(append(managerRoles, auth.RoleService)...)
+}
+
+// visibleFilter restricts the filter of a request to the races the caller may see.
+func visibleFilter(ctx context.Context, filter *racing.ListRacesRequestFilter) db.RaceFilter {
+	return db.RaceFilter{Request: filter, VisibleOnly: !canSeeHidden(ctx)}
+}
+
+// getRace returns a race by its ID, or ErrNotFound should the caller not be allowed to see it.
+func (s *racingService) getRace(ctx context.Context, id int64) (*racing.Race, error) {
+	if canSeeHidden(ctx) {
+		return s.racesRepo.Get(ctx, id)
+	}
+
+	return s.racesRepo.GetMatching(ctx, id, visibleFilter(ctx, nil))
+}
+
+// checkRaceVisible returns a NotFound error, as for a race that does not exist, should the caller
+// not be allowed to see the named race.
+func (s *racingService) checkRaceVisible(ctx context.Context, id int64, name string) error {
+	if canSeeHidden(ctx) {
+		return nil
+	}
+
+	if _, err := s.getRace(ctx, id); errors.Is(err, db.ErrNotFound) {
+		return status.Errorf(codes.NotFound, "race %q not found", name)
+	} else if err != nil {
+		return toStatusError(err)
+	}
+
+	return nil
+}
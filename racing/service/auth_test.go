@@ -0,0 +1,206 @@
+package service
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/filter"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/tote"
+	"git.neds.sh/matty/entain/racing/watch"
+)
+
+// callers are the contexts of an anonymous caller and of callers holding each role.
+var callers = []struct {
+	name string
+	ctx  context.Context
+	// manager is whether the caller may manage races; hidden whether they may see hidden races.
+	manager, hidden bool
+}{
+	{name: "anonymous", ctx: context.Background()},
+	{name: "customer", ctx: identityContext("alice")},
+	{name: "service", ctx: identityContext("betting", auth.RoleService), hidden: true},
+	{name: "trader", ctx: identityContext("trader", auth.RoleTrader), manager: true, hidden: true},
+	{name: "admin", ctx: identityContext("admin", auth.RoleAdmin), manager: true, hidden: true},
+}
+
+func identityContext(subject string, roles ...string) context.Context {
+	return auth.NewContext(context.Background(), &auth.Identity{Subject: subject, Roles: roles})
+}
+
+func TestRequireManager(t *testing.T) {
+	for _, caller := range callers {
+		want := codes.OK
+		switch {
+		case caller.name == "anonymous":
+			want = codes.Unauthenticated
+		case !caller.manager:
+			want = codes.PermissionDenied
+		}
+
+		if err := requireManager(caller.ctx); status.Code(err) != want {
+			t.Errorf("requireManager() for %s error = %v, want %s", caller.name, err, want)
+		}
+	}
+}
+
+func TestVisibleFilter(t *testing.T) {
+	request := &racing.ListRacesRequestFilter{Expression: "number = 1"}
+
+	for _, caller := range callers {
+		if got := canSeeHidden(caller.ctx); got != caller.hidden {
+			t.Errorf("canSeeHidden() for %s = %t, want %t", caller.name, got, caller.hidden)
+		}
+
+		got := visibleFilter(caller.ctx, request)
+		if got.Request != request || got.VisibleOnly == caller.hidden {
+			t.Errorf("visibleFilter() for %s = %+v, want the request filter, visible only %t", caller.name, got, !caller.hidden)
+		}
+	}
+
+	if request.Expression != "number = 1" {
+		t.Errorf("visibleFilter() changed the request expression to %q", request.Expression)
+	}
+}
+
+// newVisibilityService returns a racing service over a fresh database holding race 1, visible,
+// and race 2, hidden.
+func newVisibilityService(t *testing.T) Racing {
+	t.Helper()
+
+	racingDB, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "racing.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { racingDB.Close() })
+
+	racesRepo := db.NewRacesRepo(racingDB, watch.NewBroker(), db.SeedOptions{}, []byte("0123456789abcdef0123456789abcdef"))
+	if err := racesRepo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	start, err := ptypes.TimestampProto(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, visible := range []bool{true, false} {
+		if _, err := racesRepo.Create(context.Background(), &racing.Race{MeetingId: 1, Name: "Race", Number: 1, Visible: visible, AdvertisedStartTime: start}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return NewRacingService(racesRepo, nil, nil, nil, nil, nil, tote.DefaultCommissions, nil)
+}
+
+func TestRaceVisibility(t *testing.T) {
+	s := newVisibilityService(t)
+
+	// The longest expression allowed, which restricting to visible races must not invalidate.
+	longest := "id > 0" + strings.Repeat(" ", filter.MaxLength-6)
+
+	for _, caller := range callers {
+		t.Run(caller.name, func(t *testing.T) {
+			want := []int64{1}
+			if caller.hidden {
+				want = []int64{1, 2}
+			}
+
+			for _, expression := range []string{"", longest} {
+				resp, err := s.ListRaces(caller.ctx, &racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{Expression: expression}, OrderBy: "id"})
+				if err != nil {
+					t.Fatalf("ListRaces() with a %d byte expression error = %v", len(expression), err)
+				}
+
+				got := make([]int64, len(resp.Races))
+				for k, race := range resp.Races {
+					got[k] = race.Id
+				}
+
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("ListRaces() with a %d byte expression = %v, want %v", len(expression), got, want)
+				}
+			}
+
+			wantCode := codes.NotFound
+			if caller.hidden {
+				wantCode = codes.OK
+			}
+
+			if _, err := s.GetRace(caller.ctx, &racing.GetRaceRequest{Name: "races/2"}); status.Code(err) != wantCode {
+				t.Errorf("GetRace() of the hidden race error = %v, want %s", err, wantCode)
+			}
+		})
+	}
+}
+
+func TestManageRacesDenied(t *testing.T) {
+	// Callers are refused before the service reaches for its repositories, so it needs none.
+	s := NewRacingService(nil, nil, nil, nil, nil, nil, tote.DefaultCommissions, nil)
+
+	writes := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{name: "CreateRace", call: func(ctx context.Context) error {
+			_, err := s.CreateRace(ctx, &racing.CreateRaceRequest{Race: &racing.Race{MeetingId: 1}})
+			return err
+		}},
+		{name: "UpdateRace", call: func(ctx context.Context) error {
+			_, err := s.UpdateRace(ctx, &racing.UpdateRaceRequest{Race: &racing.Race{Id: 1}})
+			return err
+		}},
+		{name: "DeleteRace", call: func(ctx context.Context) error {
+			_, err := s.DeleteRace(ctx, &racing.DeleteRaceRequest{Name: "races/1"})
+			return err
+		}},
+		{name: "TransitionRaceStatus", call: func(ctx context.Context) error {
+			_, err := s.TransitionRaceStatus(ctx, &racing.TransitionRaceStatusRequest{Name: "races/1", Status: racing.Race_CLOSED})
+			return err
+		}},
+		{name: "ScratchRunner", call: func(ctx context.Context) error {
+			_, err := s.ScratchRunner(ctx, &racing.ScratchRunnerRequest{})
+			return err
+		}},
+		{name: "PublishResult", call: func(ctx context.Context) error {
+			_, err := s.PublishResult(ctx, &racing.PublishResultRequest{})
+			return err
+		}},
+		{name: "UpdatePrices", call: func(ctx context.Context) error {
+			_, err := s.UpdatePrices(ctx, &racing.UpdatePricesRequest{})
+			return err
+		}},
+		{name: "AddPoolInvestments", call: func(ctx context.Context) error {
+			_, err := s.AddPoolInvestments(ctx, &racing.AddPoolInvestmentsRequest{})
+			return err
+		}},
+	}
+
+	for _, caller := range callers {
+		if caller.manager {
+			continue
+		}
+
+		want := codes.PermissionDenied
+		if caller.name == "anonymous" {
+			want = codes.Unauthenticated
+		}
+
+		for _, write := range writes {
+			if err := write.call(caller.ctx); status.Code(err) != want {
+				t.Errorf("%s() by %s error = %v, want %s", write.name, caller.name, err, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"git.neds.sh/matty/entain/racing/jump"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+const (
+	// defaultNextToJumpCount is the number of races next to jump returned when no count is given.
+	defaultNextToJumpCount = 10
+	// maxNextToJumpCount is the most races next to jump returned by a single call.
+	maxNextToJumpCount = 100
+)
+
+func (s *racingService) ListNextToJump(ctx context.Context, in *racing.ListNextToJumpRequest) (*racing.ListNextToJumpResponse, error) {
+	if violations := validateListNextToJump(in); len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+
+	count := int(in.Count)
+
+	switch {
+	case count == 0:
+		count = defaultNextToJumpCount
+	case count > maxNextToJumpCount:
+		count = maxNextToJumpCount
+	}
+
+	races, err := s.nextToJump.Next(ctx, count, jump.Filter{
+		RaceTypes:     in.RaceTypes,
+		Jurisdictions: in.Jurisdictions,
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	// The index holds races with their meetings, so embedding them costs nothing.
+	if !in.IncludeMeeting {
+		for _, race := range races {
+			race.Meeting = nil
+		}
+	}
+
+	return &racing.ListNextToJumpResponse{Races: races}, nil
+}
+
+// validateListNextToJump checks the fields of a ListNextToJump request, returning a violation
+// for each invalid field.
+func validateListNextToJump(in *racing.ListNextToJumpRequest) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	violate := func(field, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: description,
+		})
+	}
+
+	if in.Count < 0 {
+		violate("count", "must not be negative")
+	}
+
+	for i, raceType := range in.RaceTypes {
+		if _, ok := racing.Meeting_RaceType_name[int32(raceType)]; !ok || raceType == racing.Meeting_RACE_TYPE_UNSPECIFIED {
+			violate(fmt.Sprintf("race_types[%d]", i), "must be THOROUGHBRED, HARNESS or GREYHOUND")
+		}
+	}
+
+	for i, jurisdiction := range in.Jurisdictions {
+		if strings.TrimSpace(jurisdiction) == "" {
+			violate(fmt.Sprintf("jurisdictions[%d]", i), "must not be empty")
+		}
+	}
+
+	return violations
+}
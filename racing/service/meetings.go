@@ -0,0 +1,57 @@
+package service
+
+import (
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"golang.org/x/net/context"
+)
+
+func (s *racingService) ListMeetings(ctx context.Context, in *racing.ListMeetingsRequest) (*racing.ListMeetingsResponse, error) {
+	meetings, next, err := s.meetingsRepo.List(ctx, db.ListOptions{
+		PageSize:  in.PageSize,
+		PageToken: in.PageToken,
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &racing.ListMeetingsResponse{Meetings: meetings, NextPageToken: next}, nil
+}
+
+func (s *racingService) GetMeeting(ctx context.Context, in *racing.GetMeetingRequest) (*racing.Meeting, error) {
+	id, err := parseMeetingName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	meeting, err := s.meetingsRepo.Get(ctx, id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return meeting, nil
+}
+
+// embedMeetings sets the meeting of each race, fetching each distinct meeting once.
+func (s *racingService) embedMeetings(ctx context.Context, races []*racing.Race) error {
+	var ids []int64
+
+	seen := make(map[int64]bool)
+	for _, race := range races {
+		if !seen[race.MeetingId] {
+			seen[race.MeetingId] = true
+			ids = append(ids, race.MeetingId)
+		}
+	}
+
+	meetings, err := s.meetingsRepo.GetMany(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, race := range races {
+		race.Meeting = meetings[race.MeetingId]
+	}
+
+	return nil
+}
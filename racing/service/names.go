@@ -0,0 +1,98 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// racesCollection is the collection ID of race resource names.
+	racesCollection = "races"
+	// meetingsCollection is the collection ID of meeting resource names.
+	meetingsCollection = "meetings"
+	// runnersCollection is the collection ID of runner resource names, nested within races.
+	runnersCollection = "runners"
+	// resultSingleton is the ID of the result nested within each race.
+	resultSingleton = "result"
+	// poolsSingleton is the ID of the tote pools nested within each race.
+	poolsSingleton = "pools"
+)
+
+// raceName builds the resource name of a race.
+func raceName(id int64) string {
+	return racesCollection + "/" + strconv.FormatInt(id, 10)
+}
+
+// parseRaceName extracts the race ID from a resource name of the form races/{id}.
+func parseRaceName(name string) (int64, error) {
+	return parseName(name, racesCollection)
+}
+
+// parseMeetingName extracts the meeting ID from a resource name of the form meetings/{id}.
+func parseMeetingName(name string) (int64, error) {
+	return parseName(name, meetingsCollection)
+}
+
+// parseRunnerName extracts the race and runner IDs from a resource name of the form
+// races/{race_id}/runners/{id}.
+func parseRunnerName(name string) (int64, int64, error) {
+	prefix := racesCollection + "/"
+	parts := strings.SplitN(strings.TrimPrefix(name, prefix), "/", 2)
+
+	if !strings.HasPrefix(name, prefix) || len(parts) != 2 {
+		return 0, 0, status.Errorf(codes.InvalidArgument, "invalid name %q: must be of the form %s{race_id}/%s/{id}", name, prefix, runnersCollection)
+	}
+
+	raceID, err := parseRaceName(prefix + parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	id, err := parseName(parts[1], runnersCollection)
+	if err != nil {
+		return 0, 0, status.Errorf(codes.InvalidArgument, "invalid name %q: must be of the form %s{race_id}/%s/{id}", name, prefix, runnersCollection)
+	}
+
+	return raceID, id, nil
+}
+
+// parseResultName extracts the race ID from a resource name of the form races/{race_id}/result.
+func parseResultName(name string) (int64, error) {
+	return parseSingletonName(name, resultSingleton)
+}
+
+// parsePoolsName extracts the race ID from a resource name of the form races/{race_id}/pools.
+func parsePoolsName(name string) (int64, error) {
+	return parseSingletonName(name, poolsSingleton)
+}
+
+// parseSingletonName extracts the race ID from the resource name of a singleton nested within
+// a race, of the form races/{race_id}/{singleton}.
+func parseSingletonName(name, singleton string) (int64, error) {
+	suffix := "/" + singleton
+
+	if !strings.HasSuffix(name, suffix) {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid name %q: must be of the form %s/{race_id}%s", name, racesCollection, suffix)
+	}
+
+	return parseRaceName(strings.TrimSuffix(name, suffix))
+}
+
+// parseName extracts the ID from a resource name of the form {collection}/{id}.
+func parseName(name, collection string) (int64, error) {
+	prefix := collection + "/"
+
+	if !strings.HasPrefix(name, prefix) {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid name %q: must be of the form %s{id}", name, prefix)
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(name, prefix), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid name %q: id must be a positive integer", name)
+	}
+
+	return id, nil
+}
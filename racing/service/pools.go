@@ -0,0 +1,245 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/tote"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *racingService) GetPoolApproximates(ctx context.Context, in *racing.GetPoolApproximatesRequest) (*racing.PoolApproximates, error) {
+	raceID, err := parsePoolsName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRaceVisible(ctx, raceID, raceName(raceID)); err != nil {
+		return nil, err
+	}
+
+	return s.poolApproximates(ctx, in.Name, raceID)
+}
+
+func (s *racingService) AddPoolInvestments(ctx context.Context, in *racing.AddPoolInvestmentsRequest) (*racing.PoolApproximates, error) {
+	if err := requireManager(ctx); err != nil {
+		return nil, err
+	}
+
+	raceID, err := parsePoolsName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if violations := validateInvestments(in.Investments); len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+
+	investments := make([]*racing.PoolInvestment, len(in.Investments))
+	for i, investment := range in.Investments {
+		investments[i] = &racing.PoolInvestment{
+			BetType:   investment.BetType,
+			RunnerIds: tote.Normalise(investment.BetType, investment.RunnerIds),
+			Amount:    investment.Amount,
+		}
+	}
+
+	err = s.poolsRepo.Invest(ctx, raceID, investments, func(current racing.Race_Status, runners []*racing.Runner) error {
+		if current != racing.Race_OPEN {
+			return status.Errorf(codes.FailedPrecondition, "the pools of a race that is %s are closed", current)
+		}
+
+		return checkInvestedRunners(in.Investments, runners)
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return s.poolApproximates(ctx, in.Name, raceID)
+}
+
+// poolApproximates returns the pools of a race, with the dividends published with its result if
+// it has one, or else approximate dividends.
+func (s *racingService) poolApproximates(ctx context.Context, name string, raceID int64) (*racing.PoolApproximates, error) {
+	jurisdiction, runners, investments, err := s.pools(ctx, raceID)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	rate := func(betType racing.Dividend_BetType) float64 {
+		return s.commissions.Rate(jurisdiction, betType)
+	}
+
+	approximates := &racing.PoolApproximates{
+		Name:         name,
+		RaceId:       raceID,
+		Jurisdiction: jurisdiction,
+	}
+
+	result, err := s.resultsRepo.Get(ctx, raceID)
+
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		approximates.Pools = tote.Approximate(investments, rate, runners)
+	case err != nil:
+		return nil, err
+	default:
+		// The dividends published with the result stand, whether declared by the pools or not.
+		approximates.Pools = tote.Approximate(investments, rate, runners)
+		approximates.Resulted = true
+
+		for _, pool := range approximates.Pools {
+			pool.Dividends = nil
+
+			for _, dividend := range result.Dividends {
+				if dividend.BetType == pool.BetType {
+					pool.Dividends = append(pool.Dividends, dividend)
+				}
+			}
+		}
+	}
+
+	return approximates, nil
+}
+
+// withToteDividends returns a result to publish, adding the dividends its placings declare in
+// each pool the result holds no dividends for.
+func (s *racingService) withToteDividends(ctx context.Context, raceID int64, result *racing.Result) (*racing.Result, error) {
+	jurisdiction, runners, investments, err := s.pools(ctx, raceID)
+	if errors.Is(err, db.ErrNotFound) {
+		// Publishing the result reports the race does not exist.
+		return result, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	supplied := make(map[racing.Dividend_BetType]bool)
+	for _, dividend := range result.Dividends {
+		supplied[dividend.BetType] = true
+	}
+
+	rate := func(betType racing.Dividend_BetType) float64 {
+		return s.commissions.Rate(jurisdiction, betType)
+	}
+
+	withDividends := &racing.Result{
+		Placings:  result.Placings,
+		Dividends: result.Dividends,
+	}
+
+	for _, pool := range tote.Declare(investments, rate, runners, result.Placings) {
+		if !supplied[pool.BetType] {
+			withDividends.Dividends = append(withDividends.Dividends, pool.Dividends...)
+		}
+	}
+
+	return withDividends, nil
+}
+
+// pools returns the jurisdiction of a race, along with its runners and the investments in its
+// pools.
+func (s *racingService) pools(ctx context.Context, raceID int64) (string, []*racing.Runner, []*racing.PoolInvestment, error) {
+	race, err := s.racesRepo.Get(ctx, raceID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	meeting, err := s.meetingsRepo.Get(ctx, race.MeetingId)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	runners, err := s.runnersRepo.List(ctx, raceID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	investments, err := s.poolsRepo.List(ctx, raceID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return meeting.Jurisdiction, runners, investments, nil
+}
+
+// validateInvestments checks the fields of investments in pools, returning a violation for each
+// invalid field.
+func validateInvestments(investments []*racing.PoolInvestment) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	violate := func(field, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: description,
+		})
+	}
+
+	if len(investments) == 0 {
+		violate("investments", "must not be empty")
+	}
+
+	for i, investment := range investments {
+		field := fmt.Sprintf("investments[%d]", i)
+
+		if !tote.IsPool(investment.BetType) {
+			violate(field+".bet_type", "must be WIN, PLACE, QUINELLA or TRIFECTA")
+		} else if selections := tote.Selections(investment.BetType); len(investment.RunnerIds) != selections {
+			violate(field+".runner_ids", fmt.Sprintf("must hold %d runners for a %s investment", selections, investment.BetType))
+		}
+
+		seen := make(map[int64]bool, len(investment.RunnerIds))
+		for _, id := range investment.RunnerIds {
+			if seen[id] {
+				violate(field+".runner_ids", fmt.Sprintf("must not select runner %d more than once", id))
+			}
+
+			seen[id] = true
+		}
+
+		if investment.Amount <= 0 {
+			violate(field+".amount", "must be a positive amount of cents")
+		}
+	}
+
+	return violations
+}
+
+// checkInvestedRunners confirms the runners invested on are entered in the race, and have not
+// been scratched.
+func checkInvestedRunners(investments []*racing.PoolInvestment, runners []*racing.Runner) error {
+	byID := make(map[int64]*racing.Runner, len(runners))
+	for _, runner := range runners {
+		byID[runner.Id] = runner
+	}
+
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	for i, investment := range investments {
+		for _, id := range investment.RunnerIds {
+			runner, ok := byID[id]
+
+			switch {
+			case !ok:
+				violations = append(violations, &errdetails.BadRequest_FieldViolation{
+					Field:       fmt.Sprintf("investments[%d].runner_ids", i),
+					Description: fmt.Sprintf("runner %d is not a runner in the race", id),
+				})
+			case runner.Scratched:
+				return status.Errorf(codes.FailedPrecondition, "runner %d has been scratched", id)
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return invalidArgument(violations)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"fmt"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *racingService) ListPrices(ctx context.Context, in *racing.ListPricesRequest) (*racing.ListPricesResponse, error) {
+	raceID, err := parseRaceName(in.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRaceVisible(ctx, raceID, in.Parent); err != nil {
+		return nil, err
+	}
+
+	prices, err := s.pricesRepo.List(ctx, raceID, in.IncludeHistory)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &racing.ListPricesResponse{Prices: prices}, nil
+}
+
+func (s *racingService) StreamPrices(in *racing.StreamPricesRequest, stream racing.Racing_StreamPricesServer) error {
+	raceID, err := parseRaceName(in.Parent)
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+
+	if err := s.checkRaceVisible(ctx, raceID, in.Parent); err != nil {
+		return err
+	}
+
+	// Subscribe before reading the current prices, so that no update made in between is missed.
+	sub := s.pricesRepo.Watch(watchBufferSize)
+	defer sub.Close()
+
+	prices, err := s.pricesRepo.List(ctx, raceID, false)
+	if err != nil {
+		return toStatusError(err)
+	}
+
+	// Prices are only ever added, so the stream resumes after the last price it has sent. The
+	// latest price of the race is always among its current prices.
+	var last int64
+
+	send := func(prices []*racing.Price) error {
+		for _, price := range prices {
+			if err := stream.Send(price); err != nil {
+				return err
+			}
+
+			if price.Id > last {
+				last = price.Id
+			}
+		}
+
+		return nil
+	}
+
+	catchUp := func() error {
+		prices, err := s.pricesRepo.ListSince(ctx, raceID, last)
+		if err != nil {
+			return toStatusError(err)
+		}
+
+		return send(prices)
+	}
+
+	if err := send(prices); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-sub.Overflow():
+			// Updates missed while the stream fell behind are read back from the price history.
+			sub.Drain()
+
+			if err := catchUp(); err != nil {
+				return err
+			}
+
+		case change := <-sub.Changes():
+			if change.ID != raceID {
+				continue
+			}
+
+			if err := catchUp(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *racingService) UpdatePrices(ctx context.Context, in *racing.UpdatePricesRequest) (*racing.UpdatePricesResponse, error) {
+	if err := requireManager(ctx); err != nil {
+		return nil, err
+	}
+
+	raceID, err := parseRaceName(in.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(in.Prices) == 0 {
+		return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: "prices", Description: "must not be empty"}})
+	}
+
+	prices, err := s.pricesRepo.Update(ctx, raceID, in.Prices, func(raceStatus racing.Race_Status, runners []*racing.Runner) error {
+		if violations := validatePrices(in.Prices, runners); len(violations) > 0 {
+			return invalidArgument(violations)
+		}
+
+		if raceStatus != racing.Race_OPEN {
+			return status.Errorf(codes.FailedPrecondition, "prices cannot be updated for a race that is %s", raceStatus)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &racing.UpdatePricesResponse{Prices: prices}, nil
+}
+
+// validatePrices checks new prices against the runners of their race, returning a violation for
+// each problem found.
+func validatePrices(prices []*racing.Price, runners []*racing.Runner) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	violate := func(field, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: description,
+		})
+	}
+
+	byID := make(map[int64]*racing.Runner, len(runners))
+	for _, runner := range runners {
+		byID[runner.Id] = runner
+	}
+
+	priced := make(map[int64]bool, len(prices))
+
+	for i, price := range prices {
+		field := fmt.Sprintf("prices[%d]", i)
+
+		if runner, ok := byID[price.RunnerId]; !ok {
+			violate(field+".runner_id", "is not a runner in the race")
+		} else if runner.Scratched {
+			violate(field+".runner_id", "has been scratched")
+		} else if priced[price.RunnerId] {
+			violate(field+".runner_id", "is priced more than once")
+		}
+
+		priced[price.RunnerId] = true
+
+		// Decimal prices include the stake, so must exceed 1 to pay out more than was staked.
+		if price.Win <= 1 {
+			violate(field+".win", "must be greater than 1")
+		}
+
+		if price.Place != 0 && (price.Place <= 1 || price.Place > price.Win) {
+			violate(field+".place", "must be greater than 1 and no more than the win price, or 0 when place betting is not offered")
+		}
+	}
+
+	return violations
+}
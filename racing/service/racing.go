@@ -1,31 +1,320 @@
 package service
 
 import (
+	"errors"
+
 	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/jump"
 	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/tote"
+	"github.com/golang/protobuf/ptypes/empty"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Racing interface {
 	// ListRaces will return a collection of races.
 	ListRaces(ctx context.Context, in *racing.ListRacesRequest) (*racing.ListRacesResponse, error)
+
+	// ListNextToJump will return the next races open for betting.
+	ListNextToJump(ctx context.Context, in *racing.ListNextToJumpRequest) (*racing.ListNextToJumpResponse, error)
+
+	// GetRace will return a single race.
+	GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.Race, error)
+
+	// CreateRace will create a new race.
+	CreateRace(ctx context.Context, in *racing.CreateRaceRequest) (*racing.Race, error)
+
+	// UpdateRace will update the selected fields of a race.
+	UpdateRace(ctx context.Context, in *racing.UpdateRaceRequest) (*racing.Race, error)
+
+	// DeleteRace will delete a race.
+	DeleteRace(ctx context.Context, in *racing.DeleteRaceRequest) (*empty.Empty, error)
+
+	// TransitionRaceStatus will move a race into a new status.
+	TransitionRaceStatus(ctx context.Context, in *racing.TransitionRaceStatusRequest) (*racing.Race, error)
+
+	// ListRaceStatusTransitions will return the status history of a race.
+	ListRaceStatusTransitions(ctx context.Context, in *racing.ListRaceStatusTransitionsRequest) (*racing.ListRaceStatusTransitionsResponse, error)
+
+	// WatchRaces will stream changes to the races matching a filter.
+	WatchRaces(in *racing.WatchRacesRequest, stream racing.Racing_WatchRacesServer) error
+
+	// ListMeetings will return a collection of meetings.
+	ListMeetings(ctx context.Context, in *racing.ListMeetingsRequest) (*racing.ListMeetingsResponse, error)
+
+	// GetMeeting will return a single meeting.
+	GetMeeting(ctx context.Context, in *racing.GetMeetingRequest) (*racing.Meeting, error)
+
+	// ListRunners will return the runners of a race.
+	ListRunners(ctx context.Context, in *racing.ListRunnersRequest) (*racing.ListRunnersResponse, error)
+
+	// ScratchRunner will withdraw a runner from its race.
+	ScratchRunner(ctx context.Context, in *racing.ScratchRunnerRequest) (*racing.Runner, error)
+
+	// GetRaceResult will return the result of a race.
+	GetRaceResult(ctx context.Context, in *racing.GetRaceResultRequest) (*racing.Result, error)
+
+	// PublishResult will publish the result of a race.
+	PublishResult(ctx context.Context, in *racing.PublishResultRequest) (*racing.Result, error)
+
+	// ListPrices will return the fixed-odds prices of the runners in a race.
+	ListPrices(ctx context.Context, in *racing.ListPricesRequest) (*racing.ListPricesResponse, error)
+
+	// StreamPrices will stream updates to the fixed-odds prices of the runners in a race.
+	StreamPrices(in *racing.StreamPricesRequest, stream racing.Racing_StreamPricesServer) error
+
+	// UpdatePrices will set new fixed-odds prices for runners in a race.
+	UpdatePrices(ctx context.Context, in *racing.UpdatePricesRequest) (*racing.UpdatePricesResponse, error)
+
+	// GetPoolApproximates will return the tote pools of a race, with their dividends.
+	GetPoolApproximates(ctx context.Context, in *racing.GetPoolApproximatesRequest) (*racing.PoolApproximates, error)
+
+	// AddPoolInvestments will invest in the tote pools of a race.
+	AddPoolInvestments(ctx context.Context, in *racing.AddPoolInvestmentsRequest) (*racing.PoolApproximates, error)
 }
 
+// tracer traces the work done by the service within its calls.
+var tracer = otel.Tracer("git.neds.sh/matty/entain/racing/service")
+
 // racingService implements the Racing interface.
 type racingService struct {
-	racesRepo db.RacesRepo
+	racesRepo    db.RacesRepo
+	meetingsRepo db.MeetingsRepo
+	runnersRepo  db.RunnersRepo
+	resultsRepo  db.ResultsRepo
+	pricesRepo   db.PricesRepo
+	poolsRepo    db.PoolsRepo
+	commissions  tote.Commissions
+	nextToJump   *jump.Index
 }
 
-// NewRacingService instantiates and returns a new racingService.
-func NewRacingService(racesRepo db.RacesRepo) Racing {
-	return &racingService{racesRepo}
+// NewRacingService instantiates and returns a new racingService, deducting the given commission
+// rates from tote pools and reading the races next to jump from the given index.
+func NewRacingService(racesRepo db.RacesRepo, meetingsRepo db.MeetingsRepo, runnersRepo db.RunnersRepo, resultsRepo db.ResultsRepo, pricesRepo db.PricesRepo, poolsRepo db.PoolsRepo, commissions tote.Commissions, nextToJump *jump.Index) Racing {
+	return &racingService{racesRepo, meetingsRepo, runnersRepo, resultsRepo, pricesRepo, poolsRepo, commissions, nextToJump}
 }
 
 func (s *racingService) ListRaces(ctx context.Context, in *racing.ListRacesRequest) (*racing.ListRacesResponse, error) {
-	races, err := s.racesRepo.List(in.Filter)
+	ctx, span := tracer.Start(ctx, "racingService.ListRaces", trace.WithAttributes(
+		attribute.Int("races.page_size", int(in.PageSize)),
+		attribute.String("races.order_by", in.OrderBy),
+		attribute.Bool("races.include_meeting", in.IncludeMeeting),
+		attribute.Bool("races.include_runners", in.IncludeRunners),
+	))
+	defer span.End()
+
+	result, err := s.racesRepo.List(ctx, visibleFilter(ctx, in.Filter), db.ListOptions{
+		PageSize:  in.PageSize,
+		PageToken: in.PageToken,
+		OrderBy:   in.OrderBy,
+	})
+	if err != nil {
+		return nil, traceError(span, toStatusError(err))
+	}
+
+	span.SetAttributes(attribute.Int("races.count", len(result.Races)), attribute.Int("races.total_size", int(result.TotalSize)))
+
+	if in.IncludeMeeting {
+		if err := s.embedMeetings(ctx, result.Races); err != nil {
+			return nil, traceError(span, toStatusError(err))
+		}
+	}
+
+	if in.IncludeRunners {
+		if err := s.embedRunners(ctx, result.Races); err != nil {
+			return nil, traceError(span, toStatusError(err))
+		}
+	}
+
+	return &racing.ListRacesResponse{
+		Races:         result.Races,
+		NextPageToken: result.NextPageToken,
+		TotalSize:     result.TotalSize,
+	}, nil
+}
+
+func (s *racingService) GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.Race, error) {
+	id, err := parseRaceName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	race, err := s.getRace(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "race %q not found", in.Name)
+		}
+
+		return nil, toStatusError(err)
+	}
+
+	if in.IncludeRunners {
+		if err := s.embedRunners(ctx, []*racing.Race{race}); err != nil {
+			return nil, toStatusError(err)
+		}
+	}
+
+	return race, nil
+}
+
+func (s *racingService) CreateRace(ctx context.Context, in *racing.CreateRaceRequest) (*racing.Race, error) {
+	if err := requireManager(ctx); err != nil {
+		return nil, err
+	}
+
+	if in.Race == nil {
+		return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: "race", Description: "must be set"}})
+	}
+
+	violations, err := s.validateRace(ctx, in.Race, raceMutableFields)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+
+	race, err := s.racesRepo.Create(ctx, in.Race)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return race, nil
+}
+
+func (s *racingService) UpdateRace(ctx context.Context, in *racing.UpdateRaceRequest) (*racing.Race, error) {
+	if err := requireManager(ctx); err != nil {
+		return nil, err
+	}
+
+	if in.Race == nil {
+		return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: "race", Description: "must be set"}})
+	}
+
+	if in.Race.Id <= 0 {
+		return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: "race.id", Description: "must identify the race to update"}})
+	}
+
+	paths, violations := updatePaths(in.Race, in.UpdateMask.GetPaths())
+	if len(violations) == 0 {
+		var err error
+		if violations, err = s.validateRace(ctx, in.Race, paths); err != nil {
+			return nil, toStatusError(err)
+		}
+	}
+
+	if len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+
+	race, err := s.racesRepo.Update(ctx, in.Race, paths)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return race, nil
+}
+
+func (s *racingService) DeleteRace(ctx context.Context, in *racing.DeleteRaceRequest) (*empty.Empty, error) {
+	if err := requireManager(ctx); err != nil {
+		return nil, err
+	}
+
+	id, err := parseRaceName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.racesRepo.Delete(ctx, id); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &empty.Empty{}, nil
+}
+
+func (s *racingService) TransitionRaceStatus(ctx context.Context, in *racing.TransitionRaceStatusRequest) (*racing.Race, error) {
+	if err := requireManager(ctx); err != nil {
+		return nil, err
+	}
+
+	id, err := parseRaceName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := raceStatusTransitions[in.Status]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid status %s", in.Status)
+	}
+
+	race, err := s.racesRepo.TransitionStatus(ctx, id, in.Status, in.Reason, func(from racing.Race_Status) error {
+		if err := checkRaceStatusTransition(from, in.Status); err != nil {
+			return err
+		}
+
+		// A race is only declared final once it has a result, which is published by PublishResult.
+		if in.Status == racing.Race_FINAL {
+			if _, err := s.resultsRepo.Get(ctx, id); errors.Is(err, db.ErrNotFound) {
+				return status.Errorf(codes.FailedPrecondition, "race %q cannot be made FINAL without a result", in.Name)
+			} else if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return race, nil
+}
+
+func (s *racingService) ListRaceStatusTransitions(ctx context.Context, in *racing.ListRaceStatusTransitionsRequest) (*racing.ListRaceStatusTransitionsResponse, error) {
+	id, err := parseRaceName(in.Parent)
 	if err != nil {
 		return nil, err
 	}
 
-	return &racing.ListRacesResponse{Races: races}, nil
+	if err := s.checkRaceVisible(ctx, id, in.Parent); err != nil {
+		return nil, err
+	}
+
+	transitions, err := s.racesRepo.ListStatusTransitions(ctx, id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &racing.ListRaceStatusTransitionsResponse{Transitions: transitions}, nil
+}
+
+// toStatusError maps repository errors onto their gRPC status equivalents. A busy database is
+// Unavailable, so that callers retry.
+func toStatusError(err error) error {
+	var invalid *db.InvalidArgumentError
+
+	switch {
+	case errors.As(err, &invalid):
+		return status.Error(codes.InvalidArgument, invalid.Error())
+	case errors.Is(err, db.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case db.IsBusy(err):
+		return status.Error(codes.Unavailable, "the racing database is busy, retry the call")
+	}
+
+	return err
+}
+
+// traceError records an error returned by a call on its span, returning the error.
+func traceError(span trace.Span, err error) error {
+	span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+
+	return err
 }
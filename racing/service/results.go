@@ -0,0 +1,185 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// dividendSelections is the number of runners making up the selection of each bet type.
+var dividendSelections = map[racing.Dividend_BetType]int{
+	racing.Dividend_WIN:        1,
+	racing.Dividend_PLACE:      1,
+	racing.Dividend_QUINELLA:   2,
+	racing.Dividend_EXACTA:     2,
+	racing.Dividend_TRIFECTA:   3,
+	racing.Dividend_FIRST_FOUR: 4,
+}
+
+func (s *racingService) GetRaceResult(ctx context.Context, in *racing.GetRaceResultRequest) (*racing.Result, error) {
+	raceID, err := parseResultName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRaceVisible(ctx, raceID, raceName(raceID)); err != nil {
+		return nil, err
+	}
+
+	result, err := s.resultsRepo.Get(ctx, raceID)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	result.Name = in.Name
+
+	return result, nil
+}
+
+func (s *racingService) PublishResult(ctx context.Context, in *racing.PublishResultRequest) (*racing.Result, error) {
+	if err := requireManager(ctx); err != nil {
+		return nil, err
+	}
+
+	raceID, err := parseResultName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Result == nil {
+		return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: "result", Description: "must be set"}})
+	}
+
+	// Dividends not supplied are declared by the tote pools of the race.
+	withDividends, err := s.withToteDividends(ctx, raceID, in.Result)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	result, err := s.resultsRepo.Publish(ctx, raceID, withDividends, func(from racing.Race_Status, runners []*racing.Runner) ([]racing.Race_Status, error) {
+		if violations := validateResult(withDividends, runners); len(violations) > 0 {
+			return nil, invalidArgument(violations)
+		}
+
+		var statuses []racing.Race_Status
+
+		switch from {
+		case racing.Race_CLOSED:
+			statuses = append(statuses, racing.Race_INTERIM)
+		case racing.Race_INTERIM:
+		default:
+			return nil, status.Errorf(codes.FailedPrecondition, "a result cannot be published for a race that is %s", from)
+		}
+
+		if in.Final {
+			statuses = append(statuses, racing.Race_FINAL)
+		}
+
+		return statuses, nil
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	result.Name = in.Name
+
+	return result, nil
+}
+
+// validateResult checks the placings and dividends of a result against the runners of its race,
+// returning a violation for each problem found.
+func validateResult(result *racing.Result, runners []*racing.Runner) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	violate := func(field, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       "result." + field,
+			Description: description,
+		})
+	}
+
+	byID := make(map[int64]*racing.Runner, len(runners))
+	for _, runner := range runners {
+		byID[runner.Id] = runner
+	}
+
+	if len(result.Placings) == 0 {
+		violate("placings", "must not be empty")
+	}
+
+	placed := make(map[int64]int64, len(result.Placings))
+
+	for i, placing := range result.Placings {
+		field := fmt.Sprintf("placings[%d]", i)
+
+		if runner, ok := byID[placing.RunnerId]; !ok {
+			violate(field+".runner_id", "is not a runner in the race")
+		} else if runner.Scratched {
+			violate(field+".runner_id", "has been scratched")
+		} else if _, ok := placed[placing.RunnerId]; ok {
+			violate(field+".runner_id", "is placed more than once")
+		}
+
+		if placing.Position <= 0 {
+			violate(field+".position", "must be a positive position")
+		}
+
+		if placing.Margin < 0 {
+			violate(field+".margin", "must not be negative")
+		}
+
+		placed[placing.RunnerId] = placing.Position
+	}
+
+	// Positions follow standard competition ranking: runners that dead heat share a position,
+	// and as many positions as there are extra runners in the dead heat are then skipped.
+	sorted := make([]*racing.Placing, len(result.Placings))
+	copy(sorted, result.Placings)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Position < sorted[j].Position })
+
+	for i, placing := range sorted {
+		deadHeat := i > 0 && sorted[i-1].Position == placing.Position
+
+		switch {
+		case !deadHeat && placing.Position != int64(i+1):
+			violate("placings", fmt.Sprintf("runner %d cannot be placed %d with %d runners placed ahead of it; positions are only skipped after a dead heat", placing.RunnerId, placing.Position, i))
+		case (deadHeat || placing.Position == 1) && placing.Margin != 0:
+			violate("placings", fmt.Sprintf("runner %d must have no margin, as it won or dead heated", placing.RunnerId))
+		}
+	}
+
+	for i, dividend := range result.Dividends {
+		field := fmt.Sprintf("dividends[%d]", i)
+
+		selections, ok := dividendSelections[dividend.BetType]
+		if !ok {
+			violate(field+".bet_type", "must be set")
+			continue
+		}
+
+		if len(dividend.RunnerIds) != selections {
+			violate(field+".runner_ids", fmt.Sprintf("must hold %d runners for a %s dividend", selections, dividend.BetType))
+		}
+
+		for _, id := range dividend.RunnerIds {
+			if _, ok := placed[id]; !ok {
+				violate(field+".runner_ids", fmt.Sprintf("runner %d is not placed", id))
+			}
+		}
+
+		if dividend.BetType == racing.Dividend_WIN && len(dividend.RunnerIds) == 1 && placed[dividend.RunnerIds[0]] != 1 {
+			violate(field+".runner_ids", "a WIN dividend must be paid on a winner")
+		}
+
+		if dividend.Amount < 1 {
+			violate(field+".amount", "must be at least 1, as dividends include the stake")
+		}
+	}
+
+	return violations
+}
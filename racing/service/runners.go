@@ -0,0 +1,81 @@
+package service
+
+import (
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// scratchableStatuses are the race statuses in which a runner may be scratched. Runners can be
+// scratched up until the race jumps, including at the barrier once betting has closed.
+var scratchableStatuses = map[racing.Race_Status]bool{
+	racing.Race_OPEN:      true,
+	racing.Race_POSTPONED: true,
+	racing.Race_CLOSED:    true,
+}
+
+func (s *racingService) ListRunners(ctx context.Context, in *racing.ListRunnersRequest) (*racing.ListRunnersResponse, error) {
+	raceID, err := parseRaceName(in.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRaceVisible(ctx, raceID, in.Parent); err != nil {
+		return nil, err
+	}
+
+	runners, err := s.runnersRepo.List(ctx, raceID)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &racing.ListRunnersResponse{Runners: runners}, nil
+}
+
+func (s *racingService) ScratchRunner(ctx context.Context, in *racing.ScratchRunnerRequest) (*racing.Runner, error) {
+	if err := requireManager(ctx); err != nil {
+		return nil, err
+	}
+
+	raceID, id, err := parseRunnerName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	runner, err := s.runnersRepo.Scratch(ctx, raceID, id, func(runner *racing.Runner, raceStatus racing.Race_Status) error {
+		if runner.Scratched {
+			return status.Errorf(codes.FailedPrecondition, "runner %q is already scratched", in.Name)
+		}
+
+		if !scratchableStatuses[raceStatus] {
+			return status.Errorf(codes.FailedPrecondition, "runners cannot be scratched from a race that is %s", raceStatus)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return runner, nil
+}
+
+// embedRunners sets the runners of each race, fetching the runners of every race at once.
+func (s *racingService) embedRunners(ctx context.Context, races []*racing.Race) error {
+	ids := make([]int64, len(races))
+	for i, race := range races {
+		ids[i] = race.Id
+	}
+
+	runners, err := s.runnersRepo.ListForRaces(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, race := range races {
+		race.Runners = runners[race.Id]
+	}
+
+	return nil
+}
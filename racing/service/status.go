@@ -0,0 +1,30 @@
+package service
+
+import (
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// raceStatusTransitions is the race lifecycle state machine, listing the statuses each status may
+// move into. A FINAL race may return to INTERIM, so that a result can be corrected after a protest.
+var raceStatusTransitions = map[racing.Race_Status][]racing.Race_Status{
+	racing.Race_OPEN:      {racing.Race_CLOSED, racing.Race_POSTPONED, racing.Race_ABANDONED},
+	racing.Race_POSTPONED: {racing.Race_OPEN, racing.Race_ABANDONED},
+	racing.Race_CLOSED:    {racing.Race_OPEN, racing.Race_INTERIM, racing.Race_ABANDONED},
+	racing.Race_INTERIM:   {racing.Race_FINAL, racing.Race_ABANDONED},
+	racing.Race_FINAL:     {racing.Race_INTERIM},
+	racing.Race_ABANDONED: {},
+}
+
+// checkRaceStatusTransition returns a FailedPrecondition error if a race may not move between
+// the given statuses.
+func checkRaceStatusTransition(from, to racing.Race_Status) error {
+	for _, allowed := range raceStatusTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+
+	return status.Errorf(codes.FailedPrecondition, "race cannot transition from %s to %s", from, to)
+}
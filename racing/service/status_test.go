@@ -0,0 +1,188 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/tote"
+)
+
+// racesStub is a races repository holding the status of race 1, the only race. Methods other
+// than those a test needs are left unimplemented.
+type racesStub struct {
+	db.RacesRepo
+	status racing.Race_Status
+}
+
+func (r *racesStub) TransitionStatus(ctx context.Context, id int64, to racing.Race_Status, reason string, allow func(from racing.Race_Status) error) (*racing.Race, error) {
+	if id != 1 {
+		return nil, fmt.Errorf("race %d %w", id, db.ErrNotFound)
+	}
+
+	if err := allow(r.status); err != nil {
+		return nil, err
+	}
+
+	r.status = to
+
+	return &racing.Race{Id: id, Status: to}, nil
+}
+
+// resultsStub is a results repository holding the result of race 1, should it have one.
+type resultsStub struct {
+	db.ResultsRepo
+	result *racing.Result
+}
+
+func (r *resultsStub) Get(ctx context.Context, raceID int64) (*racing.Result, error) {
+	if raceID != 1 || r.result == nil {
+		return nil, fmt.Errorf("result of race %d %w", raceID, db.ErrNotFound)
+	}
+
+	return r.result, nil
+}
+
+func TestCheckRaceStatusTransition(t *testing.T) {
+	tests := []struct {
+		from, to racing.Race_Status
+		legal    bool
+	}{
+		{from: racing.Race_OPEN, to: racing.Race_CLOSED, legal: true},
+		{from: racing.Race_OPEN, to: racing.Race_POSTPONED, legal: true},
+		{from: racing.Race_OPEN, to: racing.Race_ABANDONED, legal: true},
+		{from: racing.Race_POSTPONED, to: racing.Race_OPEN, legal: true},
+		{from: racing.Race_POSTPONED, to: racing.Race_ABANDONED, legal: true},
+		{from: racing.Race_CLOSED, to: racing.Race_OPEN, legal: true},
+		{from: racing.Race_CLOSED, to: racing.Race_INTERIM, legal: true},
+		{from: racing.Race_CLOSED, to: racing.Race_ABANDONED, legal: true},
+		{from: racing.Race_INTERIM, to: racing.Race_FINAL, legal: true},
+		{from: racing.Race_INTERIM, to: racing.Race_ABANDONED, legal: true},
+		{from: racing.Race_FINAL, to: racing.Race_INTERIM, legal: true},
+
+		{from: racing.Race_OPEN, to: racing.Race_OPEN},
+		{from: racing.Race_OPEN, to: racing.Race_INTERIM},
+		{from: racing.Race_OPEN, to: racing.Race_FINAL},
+		{from: racing.Race_POSTPONED, to: racing.Race_CLOSED},
+		{from: racing.Race_CLOSED, to: racing.Race_FINAL},
+		{from: racing.Race_INTERIM, to: racing.Race_OPEN},
+		{from: racing.Race_INTERIM, to: racing.Race_CLOSED},
+		{from: racing.Race_FINAL, to: racing.Race_OPEN},
+		{from: racing.Race_FINAL, to: racing.Race_ABANDONED},
+		{from: racing.Race_ABANDONED, to: racing.Race_OPEN},
+		{from: racing.Race_ABANDONED, to: racing.Race_POSTPONED},
+		{from: racing.Race_OPEN, to: racing.Race_STATUS_UNSPECIFIED},
+	}
+
+	for _, tt := range tests {
+		err := checkRaceStatusTransition(tt.from, tt.to)
+
+		switch {
+		case tt.legal && err != nil:
+			t.Errorf("checkRaceStatusTransition(%s, %s) error = %v, want legal", tt.from, tt.to, err)
+		case !tt.legal && status.Code(err) != codes.FailedPrecondition:
+			t.Errorf("checkRaceStatusTransition(%s, %s) error = %v, want %s", tt.from, tt.to, err, codes.FailedPrecondition)
+		}
+	}
+}
+
+func TestTransitionRaceStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   racing.Race_Status
+		result bool
+		req    *racing.TransitionRaceStatusRequest
+		code   codes.Code
+		status racing.Race_Status
+	}{
+		{
+			name:   "legal transition",
+			from:   racing.Race_OPEN,
+			req:    &racing.TransitionRaceStatusRequest{Name: "races/1", Status: racing.Race_CLOSED},
+			status: racing.Race_CLOSED,
+		},
+		{
+			name:   "illegal transition",
+			from:   racing.Race_OPEN,
+			req:    &racing.TransitionRaceStatusRequest{Name: "races/1", Status: racing.Race_FINAL},
+			code:   codes.FailedPrecondition,
+			status: racing.Race_OPEN,
+		},
+		{
+			name:   "final with a result",
+			from:   racing.Race_INTERIM,
+			result: true,
+			req:    &racing.TransitionRaceStatusRequest{Name: "races/1", Status: racing.Race_FINAL},
+			status: racing.Race_FINAL,
+		},
+		{
+			name:   "final without a result",
+			from:   racing.Race_INTERIM,
+			req:    &racing.TransitionRaceStatusRequest{Name: "races/1", Status: racing.Race_FINAL},
+			code:   codes.FailedPrecondition,
+			status: racing.Race_INTERIM,
+		},
+		{
+			name:   "abandoned races are final",
+			from:   racing.Race_ABANDONED,
+			req:    &racing.TransitionRaceStatusRequest{Name: "races/1", Status: racing.Race_OPEN},
+			code:   codes.FailedPrecondition,
+			status: racing.Race_ABANDONED,
+		},
+		{
+			name:   "unspecified status",
+			from:   racing.Race_OPEN,
+			req:    &racing.TransitionRaceStatusRequest{Name: "races/1"},
+			code:   codes.InvalidArgument,
+			status: racing.Race_OPEN,
+		},
+		{
+			name:   "unknown race",
+			from:   racing.Race_OPEN,
+			req:    &racing.TransitionRaceStatusRequest{Name: "races/2", Status: racing.Race_CLOSED},
+			code:   codes.NotFound,
+			status: racing.Race_OPEN,
+		},
+		{
+			name:   "invalid name",
+			from:   racing.Race_OPEN,
+			req:    &racing.TransitionRaceStatusRequest{Name: "meetings/1", Status: racing.Race_CLOSED},
+			code:   codes.InvalidArgument,
+			status: racing.Race_OPEN,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			races := &racesStub{status: tt.from}
+
+			results := &resultsStub{}
+			if tt.result {
+				results.result = &racing.Result{RaceId: 1}
+			}
+
+			s := NewRacingService(races, nil, nil, results, nil, nil, tote.DefaultCommissions, nil)
+
+			ctx := auth.NewContext(context.Background(), &auth.Identity{Subject: "trader", Roles: []string{auth.RoleTrader}})
+
+			race, err := s.TransitionRaceStatus(ctx, tt.req)
+			if status.Code(err) != tt.code {
+				t.Fatalf("TransitionRaceStatus() error = %v, want %s", err, tt.code)
+			}
+
+			if err == nil && race.Status != tt.status {
+				t.Errorf("TransitionRaceStatus() status = %s, want %s", race.Status, tt.status)
+			}
+
+			if races.status != tt.status {
+				t.Errorf("race status = %s, want %s", races.status, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// raceMutableFields lists the Race fields a caller may set, in proto field order.
+var raceMutableFields = []string{"meeting_id", "name", "number", "visible", "advertised_start_time"}
+
+// raceOutputOnlyFields lists the Race fields that are assigned by the server.
+var raceOutputOnlyFields = map[string]bool{"id": true, "status": true}
+
+// validateRaceFields checks the named fields of a race, returning a violation for each invalid
+// field. Violations are reported against fields of the request, so are prefixed with "race.".
+func validateRaceFields(race *racing.Race, paths []string) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	violate := func(field, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       "race." + field,
+			Description: description,
+		})
+	}
+
+	for _, path := range paths {
+		switch path {
+		case "meeting_id":
+			if race.MeetingId <= 0 {
+				violate(path, "must be a positive meeting id")
+			}
+		case "name":
+			if strings.TrimSpace(race.Name) == "" {
+				violate(path, "must not be empty")
+			}
+		case "number":
+			if race.Number <= 0 {
+				violate(path, "must be a positive number")
+			}
+		case "advertised_start_time":
+			if race.AdvertisedStartTime == nil {
+				violate(path, "must be set")
+			} else if _, err := ptypes.Timestamp(race.AdvertisedStartTime); err != nil {
+				violate(path, "must be a valid timestamp")
+			}
+		}
+	}
+
+	return violations
+}
+
+// validateRace checks the named fields of a race, including that the meeting it belongs to exists.
+func (s *racingService) validateRace(ctx context.Context, race *racing.Race, paths []string) ([]*errdetails.BadRequest_FieldViolation, error) {
+	violations := validateRaceFields(race, paths)
+
+	for _, path := range paths {
+		if path != "meeting_id" || race.MeetingId <= 0 {
+			continue
+		}
+
+		if _, err := s.meetingsRepo.Get(ctx, race.MeetingId); err != nil {
+			if !errors.Is(err, db.ErrNotFound) {
+				return nil, err
+			}
+
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       "race.meeting_id",
+				Description: "meeting does not exist",
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+// updatePaths resolves the fields an UpdateRace call will change. An empty mask selects every
+// mutable field populated in the race.
+func updatePaths(race *racing.Race, mask []string) ([]string, []*errdetails.BadRequest_FieldViolation) {
+	if len(mask) == 0 {
+		var paths []string
+
+		if race.MeetingId != 0 {
+			paths = append(paths, "meeting_id")
+		}
+		if race.Name != "" {
+			paths = append(paths, "name")
+		}
+		if race.Number != 0 {
+			paths = append(paths, "number")
+		}
+		if race.Visible {
+			paths = append(paths, "visible")
+		}
+		if race.AdvertisedStartTime != nil {
+			paths = append(paths, "advertised_start_time")
+		}
+
+		return paths, nil
+	}
+
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	for _, path := range mask {
+		switch {
+		case raceOutputOnlyFields[path]:
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       "update_mask",
+				Description: "field " + path + " is assigned by the server and cannot be updated",
+			})
+		case !isMutableRaceField(path):
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       "update_mask",
+				Description: "unknown field " + path,
+			})
+		}
+	}
+
+	return mask, violations
+}
+
+func isMutableRaceField(path string) bool {
+	for _, field := range raceMutableFields {
+		if field == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// invalidArgument builds an InvalidArgument status carrying field-level details of the violations.
+func invalidArgument(violations []*errdetails.BadRequest_FieldViolation) error {
+	descriptions := make([]string, len(violations))
+	for i, v := range violations {
+		descriptions[i] = v.Field + " " + v.Description
+	}
+
+	st := status.New(codes.InvalidArgument, "invalid request: "+strings.Join(descriptions, "; "))
+
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/watch"
+)
+
+const (
+	// watchBufferSize is the number of changes buffered for each watcher before it is considered
+	// too slow, and made to resynchronise.
+	watchBufferSize = 256
+	// snapshotPageSize is the number of races read at a time while taking a snapshot.
+	snapshotPageSize = 500
+)
+
+// raceWatcher tracks the races a single WatchRaces stream has been told about.
+type raceWatcher struct {
+	repo   db.RacesRepo
+	filter db.RaceFilter
+	stream racing.Racing_WatchRacesServer
+	known  map[int64]bool
+}
+
+func (s *racingService) WatchRaces(in *racing.WatchRacesRequest, stream racing.Racing_WatchRacesServer) error {
+	// Subscribe before taking the snapshot, so that no change made in between is missed.
+	sub := s.racesRepo.Watch(watchBufferSize)
+	defer sub.Close()
+
+	w := &raceWatcher{repo: s.racesRepo, filter: visibleFilter(stream.Context(), in.Filter), stream: stream}
+
+	if err := w.snapshot(); err != nil {
+		return toStatusError(err)
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+
+		case <-sub.Overflow():
+			// Buffered changes are superseded by the fresh snapshot.
+			sub.Drain()
+
+			if err := stream.Send(&racing.WatchRacesResponse{Type: racing.WatchRacesResponse_RESYNC}); err != nil {
+				return err
+			}
+
+			if err := w.snapshot(); err != nil {
+				return toStatusError(err)
+			}
+
+		case change := <-sub.Changes():
+			if err := w.apply(change); err != nil {
+				return toStatusError(err)
+			}
+		}
+	}
+}
+
+// snapshot sends an ADDED event for every race matching the filter, followed by SYNCED.
+func (w *raceWatcher) snapshot() error {
+	w.known = make(map[int64]bool)
+
+	opts := db.ListOptions{PageSize: snapshotPageSize}
+
+	for {
+		result, err := w.repo.List(w.stream.Context(), w.filter, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, race := range result.Races {
+			w.known[race.Id] = true
+
+			if err := w.send(racing.WatchRacesResponse_ADDED, race); err != nil {
+				return err
+			}
+		}
+
+		if result.NextPageToken == "" {
+			break
+		}
+
+		opts.PageToken = result.NextPageToken
+	}
+
+	return w.stream.Send(&racing.WatchRacesResponse{Type: racing.WatchRacesResponse_SYNCED})
+}
+
+// apply re-reads a changed race and sends the event it represents to this watcher, if any.
+func (w *raceWatcher) apply(change watch.Change) error {
+	race, err := w.repo.GetMatching(w.stream.Context(), change.ID, w.filter)
+
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		if !w.known[change.ID] {
+			return nil
+		}
+
+		delete(w.known, change.ID)
+
+		// The race no longer matches the filter, or no longer exists. Only callers who may see
+		// hidden races are sent what it has become, as it may have been hidden.
+		ctx := w.stream.Context()
+		if !canSeeHidden(ctx) {
+			return w.send(racing.WatchRacesResponse_REMOVED, &racing.Race{Id: change.ID})
+		}
+
+		race, err = w.repo.Get(ctx, change.ID)
+		if errors.Is(err, db.ErrNotFound) {
+			race, err = &racing.Race{Id: change.ID}, nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return w.send(racing.WatchRacesResponse_REMOVED, race)
+
+	case err != nil:
+		return err
+
+	case w.known[change.ID]:
+		return w.send(racing.WatchRacesResponse_MODIFIED, race)
+	}
+
+	w.known[change.ID] = true
+
+	return w.send(racing.WatchRacesResponse_ADDED, race)
+}
+
+func (w *raceWatcher) send(eventType racing.WatchRacesResponse_Type, race *racing.Race) error {
+	return w.stream.Send(&racing.WatchRacesResponse{Type: eventType, Race: race})
+}
@@ -0,0 +1,90 @@
+package tote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// Rates are the commission rates deducted from each pool, keyed by the name of the pool's bet
+// type, e.g. "WIN".
+type Rates map[string]float64
+
+// Commissions holds the commission rates of each jurisdiction. Pools in jurisdictions without
+// their own rate use the default rate.
+type Commissions struct {
+	Default       Rates            `json:"default"`
+	Jurisdictions map[string]Rates `json:"jurisdictions"`
+}
+
+// DefaultCommissions are the commission rates used unless others are configured.
+var DefaultCommissions = Commissions{
+	Default: Rates{"WIN": 0.15, "PLACE": 0.15, "QUINELLA": 0.175, "TRIFECTA": 0.21},
+	Jurisdictions: map[string]Rates{
+		"NSW": {"WIN": 0.1475, "PLACE": 0.1475, "QUINELLA": 0.175, "TRIFECTA": 0.20},
+		"VIC": {"WIN": 0.16, "PLACE": 0.1425, "QUINELLA": 0.1775, "TRIFECTA": 0.21},
+		"QLD": {"WIN": 0.16, "PLACE": 0.1425, "QUINELLA": 0.1775, "TRIFECTA": 0.21},
+		"SA":  {"WIN": 0.16, "PLACE": 0.15, "QUINELLA": 0.18, "TRIFECTA": 0.22},
+		"WA":  {"WIN": 0.155, "PLACE": 0.155, "QUINELLA": 0.18, "TRIFECTA": 0.22},
+	},
+}
+
+// LoadCommissions reads commission rates from a JSON file of the same shape as Commissions.
+func LoadCommissions(path string) (Commissions, error) {
+	var c Commissions
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("reading commissions from %s: %w", path, err)
+	}
+
+	if err := c.validate(); err != nil {
+		return c, fmt.Errorf("reading commissions from %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Rate returns the commission rate deducted from a pool in a jurisdiction.
+func (c Commissions) Rate(jurisdiction string, betType racing.Dividend_BetType) float64 {
+	if rate, ok := c.Jurisdictions[jurisdiction][betType.String()]; ok {
+		return rate
+	}
+
+	return c.Default[betType.String()]
+}
+
+// validate checks every rate is for a pool that is run, and leaves something to pay out.
+func (c Commissions) validate() error {
+	check := func(where string, rates Rates) error {
+		for name, rate := range rates {
+			if !IsPool(racing.Dividend_BetType(racing.Dividend_BetType_value[name])) {
+				return fmt.Errorf("%s: no %q pool is run", where, name)
+			}
+
+			if rate < 0 || rate >= 1 {
+				return fmt.Errorf("%s: the %s rate must be at least 0 and less than 1", where, name)
+			}
+		}
+
+		return nil
+	}
+
+	if err := check("default", c.Default); err != nil {
+		return err
+	}
+
+	for jurisdiction, rates := range c.Jurisdictions {
+		if err := check(jurisdiction, rates); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,372 @@
+// Package tote calculates the dividends of the parimutuel pools run on races: the amount
+// invested in a pool, less commission, is shared between the investments on the winning
+// selections.
+package tote
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// selections is the number of runners making up a selection in each pool that is run.
+var selections = map[racing.Dividend_BetType]int{
+	racing.Dividend_WIN:      1,
+	racing.Dividend_PLACE:    1,
+	racing.Dividend_QUINELLA: 2,
+	racing.Dividend_TRIFECTA: 3,
+}
+
+// PoolTypes are the bet types pools are run on, in the order they are reported.
+var PoolTypes = []racing.Dividend_BetType{
+	racing.Dividend_WIN,
+	racing.Dividend_PLACE,
+	racing.Dividend_QUINELLA,
+	racing.Dividend_TRIFECTA,
+}
+
+// IsPool reports whether a pool is run on a bet type.
+func IsPool(betType racing.Dividend_BetType) bool {
+	_, ok := selections[betType]
+	return ok
+}
+
+// Selections returns the number of runners making up a selection in a pool.
+func Selections(betType racing.Dividend_BetType) int {
+	return selections[betType]
+}
+
+// Normalise returns the runners of a selection in the order they are pooled: QUINELLA
+// selections are unordered, so are pooled in ascending order of runner.
+func Normalise(betType racing.Dividend_BetType, runnerIDs []int64) []int64 {
+	ids := append([]int64(nil), runnerIDs...)
+	if betType == racing.Dividend_QUINELLA {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+
+	return ids
+}
+
+// PlacesPaid returns the number of places paid for a field of starters: three for eight or
+// more, two for five to seven, and none for smaller fields.
+func PlacesPaid(starters int) int {
+	switch {
+	case starters >= 8:
+		return 3
+	case starters >= 5:
+		return 2
+	}
+
+	return 0
+}
+
+// pool is the investments in a single pool, keyed by selection.
+type pool struct {
+	betType    racing.Dividend_BetType
+	total      int64
+	commission float64
+	invested   map[string]int64
+	selections map[string][]int64
+}
+
+// net returns the amount to be paid out of the pool, in cents.
+func (p *pool) net() float64 {
+	return float64(p.total) * (1 - p.commission)
+}
+
+// Approximate returns each pool with the dividend each selection invested in would pay, should
+// it win. Approximate PLACE dividends assume the runner places alongside the runners most heavily
+// invested in. Investments on selections including scratched runners are refunded, so are left
+// out of the pools.
+func Approximate(investments []*racing.PoolInvestment, rate func(racing.Dividend_BetType) float64, runners []*racing.Runner) []*racing.Pool {
+	pools, starters := gather(investments, rate, runners)
+	places := PlacesPaid(starters)
+
+	reported := make([]*racing.Pool, len(pools))
+
+	for i, p := range pools {
+		reported[i] = report(p)
+
+		if p.betType != racing.Dividend_PLACE {
+			for key, ids := range p.selections {
+				reported[i].Dividends = append(reported[i].Dividends, dividend(p.betType, ids, p.net()/float64(p.invested[key])))
+			}
+
+			sortDividends(reported[i].Dividends)
+
+			continue
+		}
+
+		if places == 0 {
+			continue
+		}
+
+		// The runners a placed runner is assumed to share the places with, most invested first.
+		favourites := make([]string, 0, len(p.invested))
+		for key := range p.invested {
+			favourites = append(favourites, key)
+		}
+
+		sort.Slice(favourites, func(a, b int) bool { return p.invested[favourites[a]] > p.invested[favourites[b]] })
+
+		for key, ids := range p.selections {
+			placed := []string{key}
+			for _, other := range favourites {
+				if len(placed) == places {
+					break
+				}
+
+				if other != key {
+					placed = append(placed, other)
+				}
+			}
+
+			reported[i].Dividends = append(reported[i].Dividends, placeDividend(p, placed, key, ids, 1/float64(places)))
+		}
+
+		sortDividends(reported[i].Dividends)
+	}
+
+	return reported
+}
+
+// Declare returns each pool with the dividends declared on the placings of a race. Runners
+// that dead heat share the dividend they would have been paid alone. Selections nobody invested
+// in declare no dividend.
+func Declare(investments []*racing.PoolInvestment, rate func(racing.Dividend_BetType) float64, runners []*racing.Runner, placings []*racing.Placing) []*racing.Pool {
+	pools, starters := gather(investments, rate, runners)
+	places := PlacesPaid(starters)
+
+	reported := make([]*racing.Pool, len(pools))
+
+	for i, p := range pools {
+		reported[i] = report(p)
+
+		if p.betType == racing.Dividend_PLACE {
+			if places == 0 {
+				continue
+			}
+
+			var placed []string
+			shares := make(map[string]float64)
+
+			for _, placing := range placings {
+				tied := tiedAt(placings, placing.Position)
+				if share := PlaceShare(placing.Position, tied, int64(places)) / float64(places); share > 0 {
+					key := selectionKey([]int64{placing.RunnerId})
+					placed = append(placed, key)
+					shares[key] = share
+				}
+			}
+
+			for _, placing := range placings {
+				key := selectionKey([]int64{placing.RunnerId})
+				if shares[key] > 0 && p.invested[key] > 0 {
+					reported[i].Dividends = append(reported[i].Dividends, placeDividend(p, placed, key, []int64{placing.RunnerId}, shares[key]))
+				}
+			}
+
+			continue
+		}
+
+		// The pool is shared equally between the selections that won it, which number more than
+		// one should runners dead heat.
+		winning := winningSelections(p.betType, placings)
+
+		for _, ids := range winning {
+			key := selectionKey(ids)
+			if p.invested[key] == 0 {
+				continue
+			}
+
+			amount := p.net() / float64(len(winning)) / float64(p.invested[key])
+			reported[i].Dividends = append(reported[i].Dividends, dividend(p.betType, ids, amount))
+		}
+	}
+
+	return reported
+}
+
+// gather sums the investments in each pool, leaving out those on selections including
+// scratched runners, and counts the starters in the race.
+func gather(investments []*racing.PoolInvestment, rate func(racing.Dividend_BetType) float64, runners []*racing.Runner) ([]*pool, int) {
+	scratched := make(map[int64]bool)
+	starters := 0
+
+	for _, runner := range runners {
+		if runner.Scratched {
+			scratched[runner.Id] = true
+		} else {
+			starters++
+		}
+	}
+
+	pools := make([]*pool, len(PoolTypes))
+	byType := make(map[racing.Dividend_BetType]*pool, len(PoolTypes))
+
+	for i, betType := range PoolTypes {
+		pools[i] = &pool{
+			betType:    betType,
+			commission: rate(betType),
+			invested:   make(map[string]int64),
+			selections: make(map[string][]int64),
+		}
+		byType[betType] = pools[i]
+	}
+
+investments:
+	for _, investment := range investments {
+		p, ok := byType[investment.BetType]
+		if !ok {
+			continue
+		}
+
+		for _, id := range investment.RunnerIds {
+			if scratched[id] {
+				continue investments
+			}
+		}
+
+		ids := Normalise(investment.BetType, investment.RunnerIds)
+		key := selectionKey(ids)
+
+		p.total += investment.Amount
+		p.invested[key] += investment.Amount
+		p.selections[key] = ids
+	}
+
+	return pools, starters
+}
+
+// placeDividend returns the PLACE dividend of a runner placed alongside others, when it is paid
+// the given share of the pool's profit: the net pool less the amount invested on the placed
+// runners, which is returned to them.
+func placeDividend(p *pool, placed []string, key string, ids []int64, share float64) *racing.Dividend {
+	profit := p.net()
+	for _, k := range placed {
+		profit -= float64(p.invested[k])
+	}
+
+	if profit < 0 {
+		profit = 0
+	}
+
+	return dividend(p.betType, ids, 1+profit*share/float64(p.invested[key]))
+}
+
+// winningSelections returns the selections that won an exotic pool on the placings of a race.
+// Runners that dead heat occupy each of the positions they share, so may win in either order.
+func winningSelections(betType racing.Dividend_BetType, placings []*racing.Placing) [][]int64 {
+	n := Selections(betType)
+
+	var (
+		winning [][]int64
+		seen    = make(map[string]bool)
+		current []int64
+		used    = make(map[int64]bool)
+	)
+
+	var pick func(slot int64)
+	pick = func(slot int64) {
+		if len(current) == n {
+			ids := Normalise(betType, current)
+			if key := selectionKey(ids); !seen[key] {
+				seen[key] = true
+				winning = append(winning, ids)
+			}
+
+			return
+		}
+
+		for _, placing := range placings {
+			tied := tiedAt(placings, placing.Position)
+			if used[placing.RunnerId] || slot < placing.Position || slot > placing.Position+tied-1 {
+				continue
+			}
+
+			used[placing.RunnerId] = true
+			current = append(current, placing.RunnerId)
+
+			pick(slot + 1)
+
+			current = current[:len(current)-1]
+			used[placing.RunnerId] = false
+		}
+	}
+
+	pick(1)
+
+	return winning
+}
+
+// tiedAt returns the number of runners placed at a position.
+func tiedAt(placings []*racing.Placing, position int64) int64 {
+	var tied int64
+	for _, placing := range placings {
+		if placing.Position == position {
+			tied++
+		}
+	}
+
+	return tied
+}
+
+// PlaceShare returns the fraction of a paid position a runner placed at a position is paid on,
+// when it shares the position with tied runners in total and the first places positions are
+// paid. Tied runners occupy the positions from theirs onwards, of which only those paid are
+// shared: two runners dead heating for third when three places are paid each take half of it.
+func PlaceShare(position, tied, places int64) float64 {
+	if position == 0 || position > places {
+		return 0
+	}
+
+	paid := tied
+	if last := position + tied - 1; last > places {
+		paid -= last - places
+	}
+
+	return float64(paid) / float64(tied)
+}
+
+// report returns the pool as reported, without its dividends.
+func report(p *pool) *racing.Pool {
+	return &racing.Pool{BetType: p.betType, Total: p.total, Commission: p.commission}
+}
+
+// dividend builds a dividend of the given amount, rounded down to the nearest 10 cents and paying
+// at least the stake back.
+func dividend(betType racing.Dividend_BetType, runnerIDs []int64, amount float64) *racing.Dividend {
+	amount = math.Floor(amount*10+1e-9) / 10
+	if amount < 1 {
+		amount = 1
+	}
+
+	return &racing.Dividend{BetType: betType, RunnerIds: runnerIDs, Amount: amount}
+}
+
+// sortDividends orders dividends by their selections.
+func sortDividends(dividends []*racing.Dividend) {
+	sort.Slice(dividends, func(i, j int) bool {
+		a, b := dividends[i].RunnerIds, dividends[j].RunnerIds
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+
+		return len(a) < len(b)
+	})
+}
+
+// selectionKey identifies a selection within a pool.
+func selectionKey(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,202 @@
+package tote_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"git.neds.sh/matty/entain/racing/tote"
+)
+
+// tenPercent deducts ten percent commission from every pool.
+func tenPercent(racing.Dividend_BetType) float64 { return 0.1 }
+
+// starters returns a field of runners with IDs from 1, scratching the given IDs.
+func starters(n int64, scratched ...int64) []*racing.Runner {
+	runners := make([]*racing.Runner, n)
+	for i := range runners {
+		id := int64(i + 1)
+		runners[i] = &racing.Runner{Id: id, SaddleNumber: id}
+
+		for _, s := range scratched {
+			runners[i].Scratched = runners[i].Scratched || s == id
+		}
+	}
+
+	return runners
+}
+
+func invest(betType racing.Dividend_BetType, amount int64, ids ...int64) *racing.PoolInvestment {
+	return &racing.PoolInvestment{BetType: betType, RunnerIds: ids, Amount: amount}
+}
+
+// dividends flattens the dividends of pools, keyed by bet type and selection.
+func dividends(pools []*racing.Pool) map[string]float64 {
+	amounts := make(map[string]float64)
+	for _, p := range pools {
+		for _, d := range p.Dividends {
+			amounts[fmt.Sprint(d.BetType, d.RunnerIds)] = d.Amount
+		}
+	}
+
+	return amounts
+}
+
+var investments = []*racing.PoolInvestment{
+	invest(racing.Dividend_WIN, 600, 1),
+	invest(racing.Dividend_WIN, 300, 2),
+	invest(racing.Dividend_WIN, 100, 3),
+	invest(racing.Dividend_PLACE, 100, 1),
+	invest(racing.Dividend_PLACE, 100, 2),
+	invest(racing.Dividend_PLACE, 100, 3),
+	invest(racing.Dividend_PLACE, 700, 4),
+	invest(racing.Dividend_QUINELLA, 200, 2, 1),
+	invest(racing.Dividend_QUINELLA, 800, 1, 3),
+}
+
+func TestDeclare(t *testing.T) {
+	tests := []struct {
+		name     string
+		runners  []*racing.Runner
+		placings []*racing.Placing
+		want     map[string]float64
+	}{
+		{
+			name:     "placings without dead heats",
+			runners:  starters(8),
+			placings: []*racing.Placing{{RunnerId: 1, Position: 1}, {RunnerId: 2, Position: 2}, {RunnerId: 3, Position: 3}},
+			want: map[string]float64{
+				"WIN [1]":        1.5,
+				"PLACE [1]":      3,
+				"PLACE [2]":      3,
+				"PLACE [3]":      3,
+				"QUINELLA [1 2]": 4.5,
+			},
+		},
+		{
+			name:     "dead heat for first shares the win pool",
+			runners:  starters(8),
+			placings: []*racing.Placing{{RunnerId: 1, Position: 1}, {RunnerId: 2, Position: 1}, {RunnerId: 3, Position: 3}},
+			want: map[string]float64{
+				"WIN [1]":        1,
+				"WIN [2]":        1.5,
+				"PLACE [1]":      3,
+				"PLACE [2]":      3,
+				"PLACE [3]":      3,
+				"QUINELLA [1 2]": 4.5,
+			},
+		},
+		{
+			name:     "two places are paid on seven starters",
+			runners:  starters(7),
+			placings: []*racing.Placing{{RunnerId: 1, Position: 1}, {RunnerId: 2, Position: 2}, {RunnerId: 3, Position: 3}},
+			want: map[string]float64{
+				"WIN [1]":        1.5,
+				"PLACE [1]":      4.5,
+				"PLACE [2]":      4.5,
+				"QUINELLA [1 2]": 4.5,
+			},
+		},
+		{
+			name:     "no places are paid on four starters",
+			runners:  starters(8, 5, 6, 7, 8),
+			placings: []*racing.Placing{{RunnerId: 2, Position: 1}, {RunnerId: 3, Position: 2}},
+			want: map[string]float64{
+				"WIN [2]":        3,
+				"QUINELLA [2 3]": 0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := make(map[string]float64)
+			for k, v := range tt.want {
+				// Selections nobody invested in declare no dividend.
+				if v != 0 {
+					want[k] = v
+				}
+			}
+
+			if got := dividends(tote.Declare(investments, tenPercent, tt.runners, tt.placings)); !reflect.DeepEqual(got, want) {
+				t.Errorf("Declare() dividends = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApproximate(t *testing.T) {
+	pools := tote.Approximate(investments, tenPercent, starters(8, 3))
+
+	if len(pools) != len(tote.PoolTypes) {
+		t.Fatalf("Approximate() returned %d pools, want %d", len(pools), len(tote.PoolTypes))
+	}
+
+	// Investments on the scratched runner 3 are left out of the pools.
+	if win := pools[0]; win.BetType != racing.Dividend_WIN || win.Total != 900 || win.Commission != 0.1 {
+		t.Errorf("Approximate() WIN pool = %v, want a total of 900 at 0.1 commission", win)
+	}
+
+	want := map[string]float64{
+		"WIN [1]":   1.3,
+		"WIN [2]":   2.7,
+		"PLACE [1]": 1,
+		"PLACE [2]": 1,
+		"PLACE [4]": 1,
+		// Only the 200 on 1-2 is left in the pool, which pays less than the $1 minimum.
+		"QUINELLA [1 2]": 1,
+	}
+
+	if got := dividends(pools); !reflect.DeepEqual(got, want) {
+		t.Errorf("Approximate() dividends = %v, want %v", got, want)
+	}
+}
+
+func TestPlacesPaid(t *testing.T) {
+	for starters, want := range map[int]int{0: 0, 4: 0, 5: 2, 7: 2, 8: 3, 24: 3} {
+		if got := tote.PlacesPaid(starters); got != want {
+			t.Errorf("PlacesPaid(%d) = %d, want %d", starters, got, want)
+		}
+	}
+}
+
+func TestPlaceShare(t *testing.T) {
+	tests := []struct {
+		position, tied, places int64
+		want                   float64
+	}{
+		{position: 1, tied: 1, places: 3, want: 1},
+		{position: 3, tied: 1, places: 3, want: 1},
+		{position: 4, tied: 1, places: 3, want: 0},
+		{position: 0, tied: 1, places: 3, want: 0},
+		{position: 1, tied: 2, places: 1, want: 0.5},
+		{position: 2, tied: 2, places: 3, want: 1},
+		{position: 3, tied: 2, places: 3, want: 0.5},
+		{position: 2, tied: 3, places: 3, want: 2.0 / 3},
+	}
+
+	for _, tt := range tests {
+		if got := tote.PlaceShare(tt.position, tt.tied, tt.places); got != tt.want {
+			t.Errorf("PlaceShare(%d, %d, %d) = %v, want %v", tt.position, tt.tied, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestCommissionsRate(t *testing.T) {
+	tests := []struct {
+		jurisdiction string
+		betType      racing.Dividend_BetType
+		want         float64
+	}{
+		{jurisdiction: "NSW", betType: racing.Dividend_TRIFECTA, want: 0.20},
+		{jurisdiction: "VIC", betType: racing.Dividend_PLACE, want: 0.1425},
+		{jurisdiction: "TAS", betType: racing.Dividend_WIN, want: 0.15},
+	}
+
+	for _, tt := range tests {
+		if got := tote.DefaultCommissions.Rate(tt.jurisdiction, tt.betType); got != tt.want {
+			t.Errorf("Rate(%q, %s) = %v, want %v", tt.jurisdiction, tt.betType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package watch fans out notifications of repository changes to subscribers.
+//
+// Publishing never blocks: each subscription has a bounded buffer, and a subscriber that falls
+// behind has changes dropped and is signalled to resynchronise instead.
+package watch
+
+import "sync"
+
+// ChangeType describes how an entity changed.
+type ChangeType int
+
+const (
+	// Created indicates a new entity.
+	Created ChangeType = iota + 1
+	// Updated indicates an existing entity was modified.
+	Updated
+	// Deleted indicates an entity was removed.
+	Deleted
+)
+
+// Change notifies subscribers that an entity has changed. Subscribers re-read the entity to
+// learn its new state.
+type Change struct {
+	// Type is how the entity changed.
+	Type ChangeType
+	// ID identifies the entity that changed.
+	ID int64
+}
+
+// Broker publishes changes to its subscribers.
+type Broker struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// NewBroker creates a new broker with no subscribers.
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe registers a new subscription that buffers up to buffer changes.
+func (b *Broker) Subscribe(buffer int) *Subscription {
+	sub := &Subscription{
+		broker:   b,
+		changes:  make(chan Change, buffer),
+		overflow: make(chan struct{}, 1),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Publish delivers a change to every subscriber without blocking. Subscribers whose buffer is
+// full miss the change, and are signalled on their Overflow channel.
+func (b *Broker) Publish(change Change) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		select {
+		case sub.changes <- change:
+		default:
+			select {
+			case sub.overflow <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Subscription receives the changes published to a broker.
+type Subscription struct {
+	broker   *Broker
+	changes  chan Change
+	overflow chan struct{}
+	once     sync.Once
+}
+
+// Changes returns the channel changes are delivered on.
+func (s *Subscription) Changes() <-chan Change {
+	return s.changes
+}
+
+// Overflow returns a channel that is signalled when changes have been dropped because the
+// subscriber fell behind. The subscriber should discard its state and re-read from the source.
+func (s *Subscription) Overflow() <-chan struct{} {
+	return s.overflow
+}
+
+// Drain discards any buffered changes, typically ahead of a resynchronisation.
+func (s *Subscription) Drain() {
+	for {
+		select {
+		case <-s.changes:
+		default:
+			return
+		}
+	}
+}
+
+// Close unsubscribes from the broker. No changes are delivered after Close returns.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		delete(s.broker.subs, s)
+		s.broker.mu.Unlock()
+	})
+}
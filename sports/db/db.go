@@ -0,0 +1,53 @@
+package db
+
+import (
+	"encoding/json"
+	"math/rand"
+	"time"
+
+	"syreclabs.com/go/faker"
+)
+
+// seedEvents is the number of events seeded.
+const seedEvents = 100
+
+// seedCompetitions are the competitions seeded events are drawn from.
+var seedCompetitions = []string{
+	"AFL Premiership",
+	"NRL Premiership",
+	"A-League Men",
+	"NBA",
+	"English Premier League",
+	"Super Rugby Pacific",
+}
+
+// seed creates the events table and populates it with fixtures between two teams.
+func (r *eventsRepo) seed() error {
+	statement, err := r.db.Prepare(`CREATE TABLE IF NOT EXISTS events (id INTEGER PRIMARY KEY, name TEXT, competition TEXT, participants TEXT, advertised_start_time DATETIME, visible INTEGER)`)
+	if err == nil {
+		_, err = statement.Exec()
+	}
+
+	for i := 1; i <= seedEvents; i++ {
+		home, away := faker.Team().Name(), faker.Team().Name()
+
+		participants, jsonErr := json.Marshal([]string{home, away})
+		if jsonErr != nil {
+			return jsonErr
+		}
+
+		statement, err = r.db.Prepare(`INSERT OR IGNORE INTO events(id, name, competition, participants, advertised_start_time, visible) VALUES (?,?,?,?,?,?)`)
+		if err == nil {
+			_, err = statement.Exec(
+				i,
+				home+" v "+away,
+				seedCompetitions[rand.Intn(len(seedCompetitions))],
+				string(participants),
+				faker.Time().Between(time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 2)).Format(time.RFC3339),
+				faker.Number().Between(0, 1),
+			)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	_ "github.com/mattn/go-sqlite3"
+
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// EventsRepo provides repository access to sporting events.
+type EventsRepo interface {
+	// Init will initialise our events repository.
+	Init() error
+
+	// List will return a list of events, ordered by advertised start time.
+	List(filter *sports.ListEventsRequestFilter) ([]*sports.Event, error)
+}
+
+type eventsRepo struct {
+	db   *sql.DB
+	init sync.Once
+}
+
+// NewEventsRepo creates a new events repository.
+func NewEventsRepo(db *sql.DB) EventsRepo {
+	return &eventsRepo{db: db}
+}
+
+// Init prepares the events repository schema and dummy data.
+func (r *eventsRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		// For test/example purposes, we seed the DB with some dummy events.
+		err = r.seed()
+	})
+
+	return err
+}
+
+func (r *eventsRepo) List(filter *sports.ListEventsRequestFilter) ([]*sports.Event, error) {
+	query, args := r.applyFilter(getEventQueries()[eventsList], filter)
+
+	rows, err := r.db.Query(query+" ORDER BY advertised_start_time, id", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.scanEvents(rows)
+}
+
+func (r *eventsRepo) applyFilter(query string, filter *sports.ListEventsRequestFilter) (string, []interface{}) {
+	var (
+		clauses []string
+		args    []interface{}
+	)
+
+	if filter == nil {
+		return query, args
+	}
+
+	if len(filter.Competitions) > 0 {
+		clauses = append(clauses, "competition IN ("+strings.Repeat("?,", len(filter.Competitions)-1)+"?)")
+
+		for _, competition := range filter.Competitions {
+			args = append(args, competition)
+		}
+	}
+
+	if filter.VisibleOnly {
+		clauses = append(clauses, "visible = 1")
+	}
+
+	if len(clauses) != 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+
+	return query, args
+}
+
+func (r *eventsRepo) scanEvents(rows *sql.Rows) ([]*sports.Event, error) {
+	defer rows.Close()
+
+	var events []*sports.Event
+
+	for rows.Next() {
+		var (
+			event           sports.Event
+			participants    string
+			advertisedStart time.Time
+		)
+
+		if err := rows.Scan(&event.Id, &event.Name, &event.Competition, &participants, &advertisedStart, &event.Visible); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(participants), &event.Participants); err != nil {
+			return nil, err
+		}
+
+		ts, err := ptypes.TimestampProto(advertisedStart)
+		if err != nil {
+			return nil, err
+		}
+
+		event.AdvertisedStartTime = ts
+
+		events = append(events, &event)
+	}
+
+	return events, rows.Err()
+}
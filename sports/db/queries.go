@@ -0,0 +1,20 @@
+package db
+
+const (
+	eventsList = "list"
+)
+
+func getEventQueries() map[string]string {
+	return map[string]string{
+		eventsList: `
+			SELECT
+				id,
+				name,
+				competition,
+				participants,
+				advertised_start_time,
+				visible
+			FROM events
+		`,
+	}
+}
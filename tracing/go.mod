@@ -0,0 +1,11 @@
+module git.neds.sh/matty/entain/tracing
+
+go 1.16
+
+require (
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
+	google.golang.org/grpc v1.36.0
+)
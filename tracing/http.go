@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler continues the trace propagated in the headers of each request handled by next, or
+// starts one, in a span around the request. The span is named for the method of the request
+// until renamed, e.g. by SetRoute once the request is routed.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := otel.Tracer(instrumentation).Start(ctx, "HTTP "+r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", "", r)...),
+		)
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(sw.code)...)
+
+		if code, message := semconv.SpanStatusFromHTTPStatusCode(sw.code); code == otelcodes.Error {
+			span.SetStatus(code, message)
+		}
+	})
+}
+
+// SetRoute names the span of a request for the route it was matched to.
+func SetRoute(ctx context.Context, route string) {
+	span := trace.SpanFromContext(ctx)
+	span.SetName(route)
+	span.SetAttributes(semconv.HTTPRouteKey.String(route))
+}
+
+// statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code = code
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush passes flushes through, so that streamed responses are not buffered.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
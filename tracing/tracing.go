@@ -0,0 +1,158 @@
+// Package tracing traces requests through the gateway and the services behind it with
+// OpenTelemetry, propagating W3C trace context between them: from HTTP headers into gRPC
+// metadata, and from gRPC metadata into the contexts of calls.
+//
+// Spans are exported by a pluggable exporter: none, stdout or file are built in, and others may
+// be added with RegisterExporter.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// Config configures the tracing of a binary.
+type Config struct {
+	Exporter    string  `config:"exporter" usage:"Exporter of spans: none, stdout, file, or one registered with RegisterExporter"`
+	File        string  `config:"file" usage:"File spans are appended to, as JSON, by the file exporter"`
+	SampleRatio float64 `config:"sample_ratio" usage:"Ratio of the traces started here that are sampled, from 0 to 1; traces continued from callers follow their sampling"`
+}
+
+// DefaultConfig propagates trace context without exporting spans.
+var DefaultConfig = Config{Exporter: "none", SampleRatio: 1}
+
+// ExporterFactory builds the exporter of spans from its configuration.
+type ExporterFactory func(cfg Config) (sdktrace.SpanExporter, error)
+
+var (
+	mu        sync.RWMutex
+	exporters = map[string]ExporterFactory{
+		"stdout": newStdoutExporter,
+		"file":   newFileExporter,
+	}
+)
+
+// RegisterExporter makes an exporter available to be configured by name. It must be called
+// before Setup, e.g. from main.
+func RegisterExporter(name string, factory ExporterFactory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	exporters[name] = factory
+}
+
+// Validate checks the tracing configured under key.
+func (c Config) Validate(key string) error {
+	mu.RLock()
+	_, ok := exporters[c.Exporter]
+	mu.RUnlock()
+
+	if !ok && c.Exporter != "none" {
+		return fmt.Errorf("%s.exporter must be one of %s", key, strings.Join(exporterNames(), ", "))
+	}
+
+	if c.Exporter == "file" && c.File == "" {
+		return fmt.Errorf("%s.file must be set for the file exporter", key)
+	}
+
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("%s.sample_ratio must be between 0 and 1", key)
+	}
+
+	return nil
+}
+
+// Setup installs the tracer provider of the named service, and the W3C trace context
+// propagator. The returned func flushes any spans yet to be exported, and should be called
+// before the service exits.
+func Setup(service string, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if cfg.Exporter == "none" {
+		// Trace context is still propagated through the service, so that traces are unbroken.
+		return func(context.Context) error { return nil }, nil
+	}
+
+	mu.RLock()
+	factory, ok := exporters[cfg.Exporter]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown exporter %q", cfg.Exporter)
+	}
+
+	exporter, err := factory(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(service))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// newStdoutExporter writes spans to stdout, for local use.
+func newStdoutExporter(Config) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+}
+
+// newFileExporter appends spans to a file, one JSON object per span.
+func newFileExporter(cfg Config) (sdktrace.SpanExporter, error) {
+	f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &fileExporter{Exporter: exporter, file: f}, nil
+}
+
+// fileExporter closes its file once shut down.
+type fileExporter struct {
+	*stdouttrace.Exporter
+	file *os.File
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	if err := e.Exporter.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	return e.file.Close()
+}
+
+func exporterNames() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := []string{"none"}
+	for name := range exporters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names[1:])
+
+	return names
+}
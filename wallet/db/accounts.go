@@ -0,0 +1,179 @@
+package db
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	_ "github.com/mattn/go-sqlite3"
+
+	"git.neds.sh/matty/entain/migrate"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+)
+
+// AccountsRepo provides repository access to accounts and the ledger of their transactions.
+type AccountsRepo interface {
+	// Init will initialise our accounts repository.
+	Init() error
+
+	// Create will open an account with empty balances, or return ErrAlreadyExists if it is
+	// already open.
+	Create(id string) (*wallet.Account, error)
+
+	// Get will return a single account with its balances, or ErrNotFound if it does not exist.
+	Get(id string) (*wallet.Account, error)
+
+	// Transact will record a transaction on an account in the ledger, along with the entries
+	// moving its funds between ledger accounts, returning it with its assigned ID, amount and
+	// entries. If the request has already made a transaction, that transaction is returned
+	// instead and created is false.
+	Transact(requestID string, txn *wallet.Transaction) (made *wallet.Transaction, created bool, err error)
+
+	// ListTransactions will return the transactions of an account with their entries, oldest
+	// first, or ErrNotFound if the account does not exist.
+	ListTransactions(accountID string) ([]*wallet.Transaction, error)
+}
+
+type accountsRepo struct {
+	db   *sql.DB
+	init sync.Once
+}
+
+// NewAccountsRepo creates a new accounts repository. Transactions are only consistent when the
+// database begins its transactions immediately, e.g. with _txlock=immediate, so that concurrent
+// transactions on an account are serialised.
+func NewAccountsRepo(db *sql.DB) AccountsRepo {
+	return &accountsRepo{db: db}
+}
+
+// Init migrates the accounts repository schema.
+func (r *accountsRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		err = migrate.Apply(r.db, migrations)
+	})
+
+	return err
+}
+
+func (r *accountsRepo) Create(id string) (*wallet.Account, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT OR IGNORE INTO accounts (id, created_at) VALUES (?,?)`, id, time.Now().UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, err
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if inserted == 0 {
+		return nil, accountExists(id)
+	}
+
+	if _, err := tx.Exec(`INSERT INTO ledger_accounts (name) VALUES (?), (?)`, availableLedger(id), reservedLedger(id)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.Get(id)
+}
+
+func (r *accountsRepo) Get(id string) (*wallet.Account, error) {
+	var (
+		account   wallet.Account
+		createdAt time.Time
+	)
+
+	err := r.db.QueryRow(getAccountQueries()[accountsGet], id).Scan(&account.Id, &createdAt, &account.Available, &account.Reserved)
+	if err == sql.ErrNoRows {
+		return nil, accountNotFound(id)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if account.CreateTime, err = ptypes.TimestampProto(createdAt); err != nil {
+		return nil, err
+	}
+
+	account.Name = "accounts/" + account.Id
+	account.Balance = account.Available + account.Reserved
+
+	return &account, nil
+}
+
+func (r *accountsRepo) ListTransactions(accountID string) ([]*wallet.Transaction, error) {
+	if _, err := r.Get(accountID); err != nil {
+		return nil, err
+	}
+
+	// A single statement, so that no transaction is seen without all of its entries.
+	rows, err := r.db.Query(getAccountQueries()[transactionsList]+" WHERE t.account_id = ? ORDER BY t.id, e.id", accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTransactions(rows)
+}
+
+// scanTransactions reads transactions from rows selected by the transactionsList query, ordered
+// by transaction.
+func scanTransactions(rows *sql.Rows) ([]*wallet.Transaction, error) {
+	defer rows.Close()
+
+	var transactions []*wallet.Transaction
+
+	for rows.Next() {
+		var (
+			txn       wallet.Transaction
+			txnType   string
+			createdAt time.Time
+			entry     wallet.Entry
+		)
+
+		if err := rows.Scan(
+			&txn.Id,
+			&txn.AccountId,
+			&txnType,
+			&txn.Amount,
+			&txn.Reference,
+			&txn.RequestId,
+			&createdAt,
+			&entry.LedgerAccount,
+			&entry.Amount,
+		); err != nil {
+			return nil, err
+		}
+
+		if n := len(transactions); n > 0 && transactions[n-1].Id == txn.Id {
+			transactions[n-1].Entries = append(transactions[n-1].Entries, &entry)
+			continue
+		}
+
+		ts, err := ptypes.TimestampProto(createdAt)
+		if err != nil {
+			return nil, err
+		}
+
+		txn.Type = wallet.Transaction_Type(wallet.Transaction_Type_value[txnType])
+		txn.CreateTime = ts
+		txn.Entries = []*wallet.Entry{&entry}
+
+		transactions = append(transactions, &txn)
+	}
+
+	return transactions, rows.Err()
+}
@@ -0,0 +1,194 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"git.neds.sh/matty/entain/wallet/db"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+)
+
+// newRepo returns an accounts repository over a fresh database, with an account "alice" holding
+// 1000 cents.
+func newRepo(t *testing.T) db.AccountsRepo {
+	t.Helper()
+
+	walletDB, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "wallet.db")+"?_txlock=immediate&_foreign_keys=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { walletDB.Close() })
+
+	repo := db.NewAccountsRepo(walletDB)
+	if err := repo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Create("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := repo.Transact("deposit", &wallet.Transaction{AccountId: "alice", Type: wallet.Transaction_DEPOSIT, Amount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+// step is a transaction made by a request.
+type step struct {
+	requestID string
+	txn       *wallet.Transaction
+}
+
+func deposit(requestID string, amount int64) step {
+	return step{requestID, &wallet.Transaction{AccountId: "alice", Type: wallet.Transaction_DEPOSIT, Amount: amount}}
+}
+
+func withdraw(requestID string, amount int64) step {
+	return step{requestID, &wallet.Transaction{AccountId: "alice", Type: wallet.Transaction_WITHDRAWAL, Amount: amount}}
+}
+
+func reserve(requestID, reference string, amount int64) step {
+	return step{requestID, &wallet.Transaction{AccountId: "alice", Type: wallet.Transaction_RESERVATION, Amount: amount, Reference: reference}}
+}
+
+func release(requestID, reference string) step {
+	return step{requestID, &wallet.Transaction{AccountId: "alice", Type: wallet.Transaction_RELEASE, Reference: reference}}
+}
+
+func settle(requestID, reference string, payout int64) step {
+	return step{requestID, &wallet.Transaction{AccountId: "alice", Type: wallet.Transaction_SETTLEMENT, Amount: payout, Reference: reference}}
+}
+
+func reverse(requestID, reference string) step {
+	return step{requestID, &wallet.Transaction{AccountId: "alice", Type: wallet.Transaction_REVERSAL, Reference: reference}}
+}
+
+func TestTransact(t *testing.T) {
+	tests := []struct {
+		name string
+		// steps are made in order; all but the last must succeed.
+		steps []step
+		err   error
+		// amount is that of the last transaction made.
+		amount              int64
+		available, reserved int64
+	}{
+		{name: "deposit", steps: []step{deposit("d", 500)}, amount: 500, available: 1500},
+		{name: "withdraw", steps: []step{withdraw("w", 400)}, amount: 400, available: 600},
+		{name: "withdraw the whole balance", steps: []step{withdraw("w", 1000)}, amount: 1000, available: 0},
+		{name: "withdraw more than available", steps: []step{withdraw("w", 1001)}, err: db.ErrInsufficientFunds, available: 1000},
+		{name: "reserve", steps: []step{reserve("r", "bets/1", 300)}, amount: 300, available: 700, reserved: 300},
+		{name: "reserve more than available", steps: []step{reserve("r", "bets/1", 1001)}, err: db.ErrInsufficientFunds, available: 1000},
+		{name: "withdraw reserved funds", steps: []step{reserve("r", "bets/1", 300), withdraw("w", 800)}, err: db.ErrInsufficientFunds, available: 700, reserved: 300},
+		{name: "reserve twice for a reference", steps: []step{reserve("r1", "bets/1", 300), reserve("r2", "bets/1", 100)}, err: db.ErrAlreadyExists, available: 700, reserved: 300},
+		{name: "release the amount reserved", steps: []step{reserve("r", "bets/1", 300), release("x", "bets/1")}, amount: 300, available: 1000},
+		{name: "release an unknown reservation", steps: []step{release("x", "bets/1")}, err: db.ErrNotFound, available: 1000},
+		{name: "settle a losing bet", steps: []step{reserve("r", "bets/1", 300), settle("s", "bets/1", 0)}, amount: 0, available: 700},
+		{name: "settle a winning bet", steps: []step{reserve("r", "bets/1", 300), settle("s", "bets/1", 900)}, amount: 900, available: 1600},
+		{name: "settle a released reservation", steps: []step{reserve("r", "bets/1", 300), release("x", "bets/1"), settle("s", "bets/1", 900)}, err: db.ErrReservationClosed, available: 1000},
+		{name: "settle twice", steps: []step{reserve("r", "bets/1", 300), settle("s1", "bets/1", 900), settle("s2", "bets/1", 900)}, err: db.ErrReservationClosed, available: 1600},
+		{name: "reverse a winning settlement", steps: []step{reserve("r", "bets/1", 300), settle("s", "bets/1", 900), reverse("v", "bets/1")}, amount: 900, available: 700, reserved: 300},
+		{name: "reverse a losing settlement", steps: []step{reserve("r", "bets/1", 300), settle("s", "bets/1", 0), reverse("v", "bets/1")}, amount: 0, available: 700, reserved: 300},
+		{name: "reverse a release", steps: []step{reserve("r", "bets/1", 300), release("x", "bets/1"), reverse("v", "bets/1")}, amount: 300, available: 700, reserved: 300},
+		{name: "settle again once reversed", steps: []step{reserve("r", "bets/1", 300), settle("s1", "bets/1", 900), reverse("v", "bets/1"), settle("s2", "bets/1", 0)}, amount: 0, available: 700},
+		{name: "reverse an open reservation", steps: []step{reserve("r", "bets/1", 300), reverse("v", "bets/1")}, err: db.ErrReservationOpen, available: 700, reserved: 300},
+		{name: "reverse an unknown reservation", steps: []step{reverse("v", "bets/1")}, err: db.ErrNotFound, available: 1000},
+		{name: "reverse a payout since withdrawn", steps: []step{reserve("r", "bets/1", 300), settle("s", "bets/1", 900), withdraw("w", 1500), reverse("v", "bets/1")}, err: db.ErrInsufficientFunds, available: 100},
+		{name: "retried reversal makes one transaction", steps: []step{reserve("r", "bets/1", 300), settle("s", "bets/1", 900), reverse("v", "bets/1"), reverse("v", "bets/1")}, amount: 900, available: 700, reserved: 300},
+		{name: "retried request makes one transaction", steps: []step{deposit("d", 500), deposit("d", 500)}, amount: 500, available: 1500},
+		{
+			name:  "transact on an unknown account",
+			steps: []step{{"d", &wallet.Transaction{AccountId: "bob", Type: wallet.Transaction_DEPOSIT, Amount: 500}}},
+			err:   db.ErrNotFound, available: 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+
+			var (
+				made *wallet.Transaction
+				err  error
+			)
+
+			for i, s := range tt.steps {
+				made, _, err = repo.Transact(s.requestID, s.txn)
+				if i < len(tt.steps)-1 && err != nil {
+					t.Fatalf("Transact() step %d error = %v", i, err)
+				}
+			}
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Transact() error = %v, want %v", err, tt.err)
+			}
+
+			if err == nil && made.Amount != tt.amount {
+				t.Errorf("Transact() amount = %d, want %d", made.Amount, tt.amount)
+			}
+
+			account, err := repo.Get("alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if account.Available != tt.available || account.Reserved != tt.reserved || account.Balance != tt.available+tt.reserved {
+				t.Errorf("Get() balances = %d available, %d reserved, %d in all; want %d available, %d reserved", account.Available, account.Reserved, account.Balance, tt.available, tt.reserved)
+			}
+
+			transactions, err := repo.ListTransactions("alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, txn := range transactions {
+				var sum int64
+				for _, entry := range txn.Entries {
+					sum += entry.Amount
+				}
+
+				if sum != 0 {
+					t.Errorf("transaction %d entries sum to %d, want 0", txn.Id, sum)
+				}
+			}
+		})
+	}
+}
+
+func TestTransactRetry(t *testing.T) {
+	repo := newRepo(t)
+
+	first, created, err := repo.Transact("r", &wallet.Transaction{AccountId: "alice", Type: wallet.Transaction_RESERVATION, Amount: 300, Reference: "bets/1"})
+	if err != nil || !created {
+		t.Fatalf("Transact() = %v, %v; want a created transaction", created, err)
+	}
+
+	retried, created, err := repo.Transact("r", &wallet.Transaction{AccountId: "alice", Type: wallet.Transaction_RESERVATION, Amount: 300, Reference: "bets/1"})
+	if err != nil || created {
+		t.Fatalf("Transact() retried = %v, %v; want the transaction already made", created, err)
+	}
+
+	if retried.Id != first.Id || retried.Amount != first.Amount || len(retried.Entries) != len(first.Entries) {
+		t.Errorf("Transact() retried = %v, want %v", retried, first)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	repo := newRepo(t)
+
+	if _, err := repo.Create("alice"); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Errorf("Create() error = %v, want %v", err, db.ErrAlreadyExists)
+	}
+
+	if _, err := repo.Get("bob"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, db.ErrNotFound)
+	}
+
+	if _, err := repo.ListTransactions("bob"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("ListTransactions() error = %v, want %v", err, db.ErrNotFound)
+	}
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned, wrapped, when a requested account or reservation does not exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrAlreadyExists is returned, wrapped, when an account or reservation to create already
+	// exists.
+	ErrAlreadyExists = errors.New("already exists")
+
+	// ErrInsufficientFunds is returned, wrapped, when a transaction would take more than the
+	// available balance of an account.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrReservationClosed is returned, wrapped, when a reservation to release or settle has
+	// already been released or settled.
+	ErrReservationClosed = errors.New("reservation closed")
+
+	// ErrReservationOpen is returned, wrapped, when a reservation to reverse the release or
+	// settlement of has yet to be released or settled.
+	ErrReservationOpen = errors.New("reservation open")
+)
+
+func accountNotFound(id string) error {
+	return fmt.Errorf("account %q %w", id, ErrNotFound)
+}
+
+func accountExists(id string) error {
+	return fmt.Errorf("account %q %w", id, ErrAlreadyExists)
+}
+
+func reservationNotFound(accountID, reference string) error {
+	return fmt.Errorf("reservation %q of account %q %w", reference, accountID, ErrNotFound)
+}
+
+func reservationExists(accountID, reference string) error {
+	return fmt.Errorf("reservation %q of account %q %w", reference, accountID, ErrAlreadyExists)
+}
+
+func reservationClosed(accountID, reference string) error {
+	return fmt.Errorf("reservation %q of account %q has been released or settled: %w", reference, accountID, ErrReservationClosed)
+}
+
+func reservationOpen(accountID, reference string) error {
+	return fmt.Errorf("reservation %q of account %q has not been released or settled: %w", reference, accountID, ErrReservationOpen)
+}
+
+func insufficientFunds(accountID string, available, amount int64) error {
+	return fmt.Errorf("account %q holds %d available, short of %d: %w", accountID, available, amount, ErrInsufficientFunds)
+}
@@ -0,0 +1,260 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+)
+
+const (
+	// cashLedger is the ledger account that is the counterpart of deposits and withdrawals.
+	cashLedger = "cash"
+	// houseLedger is the ledger account that is the counterpart of the stakes taken and payouts
+	// paid.
+	houseLedger = "house"
+)
+
+// availableLedger names the ledger account holding the available balance of an account.
+func availableLedger(accountID string) string {
+	return "accounts/" + accountID + "/available"
+}
+
+// reservedLedger names the ledger account holding the reserved balance of an account.
+func reservedLedger(accountID string) string {
+	return "accounts/" + accountID + "/reserved"
+}
+
+// transfer returns the entries moving an amount from one ledger account to another.
+func transfer(from, to string, amount int64) []*wallet.Entry {
+	return []*wallet.Entry{
+		{LedgerAccount: from, Amount: -amount},
+		{LedgerAccount: to, Amount: amount},
+	}
+}
+
+func (r *accountsRepo) Transact(requestID string, in *wallet.Transaction) (*wallet.Transaction, bool, error) {
+	// The transaction begins immediately, holding the database's write lock until it ends, so
+	// the balances checked below cannot change before the entries are recorded. Concurrent
+	// retries of a request are serialised too, so exactly one makes the transaction.
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	existing, err := queryTransactionsTx(tx, " WHERE t.request_id = ? ORDER BY e.id", requestID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(existing) > 0 {
+		return existing[0], false, nil
+	}
+
+	var available int64
+	if err := tx.QueryRow(`SELECT balance FROM ledger_accounts WHERE name = ?`, availableLedger(in.AccountId)).Scan(&available); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, accountNotFound(in.AccountId)
+		}
+
+		return nil, false, err
+	}
+
+	txn := &wallet.Transaction{
+		AccountId: in.AccountId,
+		Type:      in.Type,
+		Amount:    in.Amount,
+		Reference: in.Reference,
+		RequestId: requestID,
+	}
+
+	switch in.Type {
+	case wallet.Transaction_DEPOSIT:
+		txn.Entries = transfer(cashLedger, availableLedger(in.AccountId), in.Amount)
+
+	case wallet.Transaction_WITHDRAWAL:
+		if available < in.Amount {
+			return nil, false, insufficientFunds(in.AccountId, available, in.Amount)
+		}
+
+		txn.Entries = transfer(availableLedger(in.AccountId), cashLedger, in.Amount)
+
+	case wallet.Transaction_RESERVATION:
+		if available < in.Amount {
+			return nil, false, insufficientFunds(in.AccountId, available, in.Amount)
+		}
+
+		res, err := tx.Exec(`INSERT OR IGNORE INTO reservations (account_id, reference, amount) VALUES (?,?,?)`, in.AccountId, in.Reference, in.Amount)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if inserted, err := res.RowsAffected(); err != nil {
+			return nil, false, err
+		} else if inserted == 0 {
+			return nil, false, reservationExists(in.AccountId, in.Reference)
+		}
+
+		txn.Entries = transfer(availableLedger(in.AccountId), reservedLedger(in.AccountId), in.Amount)
+
+	case wallet.Transaction_RELEASE:
+		reserved, err := openReservation(tx, in.AccountId, in.Reference)
+		if err != nil {
+			return nil, false, err
+		}
+
+		txn.Amount = reserved
+		txn.Entries = transfer(reservedLedger(in.AccountId), availableLedger(in.AccountId), reserved)
+
+	case wallet.Transaction_SETTLEMENT:
+		reserved, err := openReservation(tx, in.AccountId, in.Reference)
+		if err != nil {
+			return nil, false, err
+		}
+
+		// The reserved funds are taken as the stake, and the payout paid out of the house.
+		txn.Entries = transfer(reservedLedger(in.AccountId), houseLedger, reserved)
+		if in.Amount > 0 {
+			txn.Entries = append(txn.Entries, transfer(houseLedger, availableLedger(in.AccountId), in.Amount)...)
+		}
+
+	case wallet.Transaction_REVERSAL:
+		closing, err := closingTransaction(tx, in.AccountId, in.Reference)
+		if err != nil {
+			return nil, false, err
+		}
+
+		// The entries of the release or settlement are reversed, taking back what it paid into
+		// the available balance, so long as the balance still holds it.
+		var credited int64
+		for _, entry := range closing.Entries {
+			if entry.LedgerAccount == availableLedger(in.AccountId) {
+				credited += entry.Amount
+			}
+
+			txn.Entries = append(txn.Entries, &wallet.Entry{LedgerAccount: entry.LedgerAccount, Amount: -entry.Amount})
+		}
+
+		if available < credited {
+			return nil, false, insufficientFunds(in.AccountId, available, credited)
+		}
+
+		txn.Amount = closing.Amount
+
+	default:
+		return nil, false, fmt.Errorf("unknown transaction type %s", in.Type)
+	}
+
+	now := time.Now().UTC()
+
+	res, err := tx.Exec(
+		`INSERT INTO transactions (request_id, account_id, type, amount, reference, created_at) VALUES (?,?,?,?,?,?)`,
+		requestID, txn.AccountId, txn.Type.String(), txn.Amount, txn.Reference, now.Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if txn.Id, err = res.LastInsertId(); err != nil {
+		return nil, false, err
+	}
+
+	for _, entry := range txn.Entries {
+		if _, err := tx.Exec(`INSERT INTO entries (transaction_id, ledger_account, amount) VALUES (?,?,?)`, txn.Id, entry.LedgerAccount, entry.Amount); err != nil {
+			return nil, false, err
+		}
+
+		if _, err := tx.Exec(`UPDATE ledger_accounts SET balance = balance + ? WHERE name = ?`, entry.Amount, entry.LedgerAccount); err != nil {
+			return nil, false, err
+		}
+	}
+
+	switch txn.Type {
+	case wallet.Transaction_RELEASE, wallet.Transaction_SETTLEMENT:
+		if _, err := tx.Exec(`UPDATE reservations SET closed_by = ? WHERE account_id = ? AND reference = ?`, txn.Id, txn.AccountId, txn.Reference); err != nil {
+			return nil, false, err
+		}
+	case wallet.Transaction_REVERSAL:
+		if _, err := tx.Exec(`UPDATE reservations SET closed_by = NULL WHERE account_id = ? AND reference = ?`, txn.AccountId, txn.Reference); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	if txn.CreateTime, err = ptypes.TimestampProto(now); err != nil {
+		return nil, false, err
+	}
+
+	return txn, true, nil
+}
+
+// openReservation returns the amount reserved by a reservation that has yet to be released or
+// settled.
+func openReservation(tx *sql.Tx, accountID, reference string) (int64, error) {
+	var (
+		amount   int64
+		closedBy sql.NullInt64
+	)
+
+	err := tx.QueryRow(`SELECT amount, closed_by FROM reservations WHERE account_id = ? AND reference = ?`, accountID, reference).Scan(&amount, &closedBy)
+	if err == sql.ErrNoRows {
+		return 0, reservationNotFound(accountID, reference)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if closedBy.Valid {
+		return 0, reservationClosed(accountID, reference)
+	}
+
+	return amount, nil
+}
+
+// closingTransaction returns the transaction that released or settled a reservation, with its
+// entries.
+func closingTransaction(tx *sql.Tx, accountID, reference string) (*wallet.Transaction, error) {
+	var closedBy sql.NullInt64
+
+	err := tx.QueryRow(`SELECT closed_by FROM reservations WHERE account_id = ? AND reference = ?`, accountID, reference).Scan(&closedBy)
+	if err == sql.ErrNoRows {
+		return nil, reservationNotFound(accountID, reference)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !closedBy.Valid {
+		return nil, reservationOpen(accountID, reference)
+	}
+
+	closing, err := queryTransactionsTx(tx, " WHERE t.id = ? ORDER BY e.id", closedBy.Int64)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(closing) == 0 {
+		return nil, fmt.Errorf("transaction %d closing reservation %q of account %q is missing", closedBy.Int64, reference, accountID)
+	}
+
+	return closing[0], nil
+}
+
+// queryTransactionsTx selects the transactions matching a condition within a transaction.
+func queryTransactionsTx(tx *sql.Tx, condition string, args ...interface{}) ([]*wallet.Transaction, error) {
+	rows, err := tx.Query(getAccountQueries()[transactionsList]+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTransactions(rows)
+}
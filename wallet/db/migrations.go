@@ -0,0 +1,43 @@
+package db
+
+// migrations bring the wallet schema up to date. Each is applied once, in order, and recorded in
+// the schema_migrations table. Released migrations must never be edited; append a new one instead.
+var migrations = []string{
+	// 1: accounts and the double-entry ledger. Every transaction records entries that sum to zero
+	// against ledger accounts, whose balances are kept alongside in the same database transaction.
+	// The ledger accounts of customers may never be overdrawn.
+	`CREATE TABLE accounts (
+		id TEXT PRIMARY KEY,
+		created_at DATETIME NOT NULL
+	);
+	CREATE TABLE ledger_accounts (
+		name TEXT PRIMARY KEY,
+		balance INTEGER NOT NULL DEFAULT 0,
+		CHECK (balance >= 0 OR name IN ('cash', 'house'))
+	);
+	INSERT INTO ledger_accounts (name) VALUES ('cash'), ('house');
+	CREATE TABLE transactions (
+		id INTEGER PRIMARY KEY,
+		request_id TEXT NOT NULL UNIQUE,
+		account_id TEXT NOT NULL REFERENCES accounts(id),
+		type TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		reference TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	);
+	CREATE INDEX transactions_account_id ON transactions(account_id);
+	CREATE TABLE entries (
+		id INTEGER PRIMARY KEY,
+		transaction_id INTEGER NOT NULL REFERENCES transactions(id),
+		ledger_account TEXT NOT NULL REFERENCES ledger_accounts(name),
+		amount INTEGER NOT NULL
+	);
+	CREATE INDEX entries_transaction_id ON entries(transaction_id);
+	CREATE TABLE reservations (
+		account_id TEXT NOT NULL REFERENCES accounts(id),
+		reference TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		closed_by INTEGER REFERENCES transactions(id),
+		PRIMARY KEY (account_id, reference)
+	);`,
+}
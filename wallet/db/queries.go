@@ -0,0 +1,40 @@
+package db
+
+const (
+	accountsGet      = "get"
+	transactionsList = "transactions"
+)
+
+func getAccountQueries() map[string]string {
+	return map[string]string{
+		// The balances of an account are read by a single statement, so are consistent with each
+		// other however many transactions are being made.
+		accountsGet: `
+			SELECT
+				a.id,
+				a.created_at,
+				available.balance,
+				reserved.balance
+			FROM accounts a
+			JOIN ledger_accounts available ON available.name = 'accounts/' || a.id || '/available'
+			JOIN ledger_accounts reserved ON reserved.name = 'accounts/' || a.id || '/reserved'
+			WHERE a.id = ?
+		`,
+		// The transactions of an account are selected along with their entries, one row per
+		// entry, in the order they were recorded.
+		transactionsList: `
+			SELECT
+				t.id,
+				t.account_id,
+				t.type,
+				t.amount,
+				t.reference,
+				t.request_id,
+				t.created_at,
+				e.ledger_account,
+				e.amount
+			FROM transactions t
+			JOIN entries e ON e.transaction_id = t.id
+		`,
+	}
+}
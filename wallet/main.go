@@ -0,0 +1,64 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"log"
+	"net"
+
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/wallet/db"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+	"git.neds.sh/matty/entain/wallet/service"
+	"google.golang.org/grpc"
+)
+
+var (
+	grpcEndpoint = flag.String("grpc-endpoint", "localhost:9003", "gRPC server endpoint")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		log.Fatalf("failed running grpc server: %s\n", err)
+	}
+}
+
+func run() error {
+	conn, err := net.Listen("tcp", *grpcEndpoint)
+	if err != nil {
+		return err
+	}
+
+	// Transactions begin immediately, so that concurrent transactions on an account are
+	// serialised rather than failing to commit.
+	walletDB, err := sql.Open("sqlite3", "file:./db/wallet.db?_txlock=immediate&_busy_timeout=5000&_foreign_keys=1")
+	if err != nil {
+		return err
+	}
+
+	accountsRepo := db.NewAccountsRepo(walletDB)
+	if err := accountsRepo.Init(); err != nil {
+		return err
+	}
+
+	// Accounts are accessed by their owners, identified by the gateway, and funds moved by the
+	// services calling on their own behalf.
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(auth.UnaryServerInterceptor))
+
+	wallet.RegisterWalletServer(
+		grpcServer,
+		service.NewWalletService(
+			accountsRepo,
+		),
+	)
+
+	log.Printf("gRPC server listening on: %s\n", *grpcEndpoint)
+
+	if err := grpcServer.Serve(conn); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// requireOwner returns an Unauthenticated error for anonymous callers, and a PermissionDenied
+// error for callers other than the owner of an account: the caller whose subject is its ID, who
+// alone may open it. Services may act on any account.
+func requireOwner(ctx context.Context, accountID string) error {
+	id, ok := auth.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "authentication is required to access accounts")
+	}
+
+	if id.Subject != accountID && !id.HasRole(auth.RoleService) {
+		return status.Errorf(codes.PermissionDenied, "%q may only access their own account", id.Subject)
+	}
+
+	return nil
+}
+
+// requireService returns an Unauthenticated error for anonymous callers, and a PermissionDenied
+// error for callers other than services, which alone deposit funds, and reserve, release, settle
+// and reverse them for bets.
+func requireService(ctx context.Context, txnType wallet.Transaction_Type) error {
+	id, ok := auth.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "authentication is required to transact on accounts")
+	}
+
+	if !id.HasRole(auth.RoleService) {
+		return status.Errorf(codes.PermissionDenied, "%q may not make %s transactions, which requires the service role", id.Subject, txnType)
+	}
+
+	return nil
+}
+
+// authorizeTransaction checks the caller may make a transaction: owners may withdraw from their
+// own accounts, and services may make any transaction.
+func authorizeTransaction(ctx context.Context, txn *wallet.Transaction) error {
+	if txn.Type == wallet.Transaction_WITHDRAWAL {
+		return requireOwner(ctx, txn.AccountId)
+	}
+
+	return requireService(ctx, txn.Type)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/auth"
+	"git.neds.sh/matty/entain/wallet/db"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+)
+
+// newService returns a wallet service over a fresh database.
+func newService(t *testing.T) Wallet {
+	t.Helper()
+
+	walletDB, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "wallet.db")+"?_txlock=immediate&_busy_timeout=5000&_foreign_keys=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { walletDB.Close() })
+
+	repo := db.NewAccountsRepo(walletDB)
+	if err := repo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewWalletService(repo)
+}
+
+// callerContext returns a context for a call made by the subject with the roles.
+func callerContext(subject string, roles ...string) context.Context {
+	return auth.NewContext(context.Background(), &auth.Identity{Subject: subject, Roles: roles})
+}
+
+func TestCreateAccountAuthorization(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{name: "owner", ctx: callerContext("alice")},
+		{name: "service", ctx: callerContext("payments", auth.RoleService)},
+		{name: "another account", ctx: callerContext("bob"), code: codes.PermissionDenied},
+		{name: "admin", ctx: callerContext("bob", auth.RoleAdmin), code: codes.PermissionDenied},
+		{name: "anonymous", ctx: context.Background(), code: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService(t)
+
+			if _, err := s.CreateAccount(tt.ctx, &wallet.CreateAccountRequest{AccountId: "alice"}); status.Code(err) != tt.code {
+				t.Errorf("CreateAccount() error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestTransactionAuthorization(t *testing.T) {
+	var (
+		owner   = callerContext("alice")
+		service = callerContext("payments", auth.RoleService)
+		other   = callerContext("bob")
+	)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		withdraw bool
+		code     codes.Code
+	}{
+		{name: "service deposits", ctx: service},
+		{name: "owner deposits", ctx: owner, code: codes.PermissionDenied},
+		{name: "anonymous deposits", ctx: context.Background(), code: codes.Unauthenticated},
+		{name: "owner withdraws", ctx: owner, withdraw: true},
+		{name: "service withdraws", ctx: service, withdraw: true},
+		{name: "another account withdraws", ctx: other, withdraw: true, code: codes.PermissionDenied},
+		{name: "anonymous withdraws", ctx: context.Background(), withdraw: true, code: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService(t)
+
+			if _, err := s.CreateAccount(owner, &wallet.CreateAccountRequest{AccountId: "alice"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := s.Deposit(service, &wallet.DepositRequest{Name: "accounts/alice", RequestId: "funding", Amount: 1000}); err != nil {
+				t.Fatal(err)
+			}
+
+			var err error
+			if tt.withdraw {
+				_, err = s.Withdraw(tt.ctx, &wallet.WithdrawRequest{Name: "accounts/alice", RequestId: "w", Amount: 100})
+			} else {
+				_, err = s.Deposit(tt.ctx, &wallet.DepositRequest{Name: "accounts/alice", RequestId: "d", Amount: 100})
+			}
+
+			if status.Code(err) != tt.code {
+				t.Errorf("error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
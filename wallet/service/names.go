@@ -0,0 +1,27 @@
+package service
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// accountsCollection is the collection ID of account resource names.
+const accountsCollection = "accounts"
+
+// parseAccountName extracts the account ID from a resource name of the form accounts/{id}.
+func parseAccountName(name string) (string, error) {
+	prefix := accountsCollection + "/"
+
+	if !strings.HasPrefix(name, prefix) {
+		return "", status.Errorf(codes.InvalidArgument, "invalid name %q: must be of the form %s{id}", name, prefix)
+	}
+
+	id := strings.TrimPrefix(name, prefix)
+	if !validAccountID(id) {
+		return "", status.Errorf(codes.InvalidArgument, "invalid name %q: id must be made up of letters, digits, '-' and '_'", name)
+	}
+
+	return id, nil
+}
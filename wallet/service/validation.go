@@ -0,0 +1,110 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+)
+
+const (
+	// maxIDLength is the longest account_id, request_id or reference accepted.
+	maxIDLength = 128
+	// maxAmount is the largest amount of cents a single transaction may move, so that balances
+	// can never overflow.
+	maxAmount = 1000000000000
+)
+
+// validAccountID reports whether an account ID is safe to use in resource and ledger account
+// names.
+func validAccountID(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateCreateAccount checks the fields of a CreateAccount request, returning a violation for
+// each invalid field.
+func validateCreateAccount(in *wallet.CreateAccountRequest) []*errdetails.BadRequest_FieldViolation {
+	if validAccountID(in.AccountId) {
+		return nil
+	}
+
+	return []*errdetails.BadRequest_FieldViolation{{
+		Field:       "account_id",
+		Description: fmt.Sprintf("must be made up of letters, digits, '-' and '_', and be no longer than %d characters", maxIDLength),
+	}}
+}
+
+// validateTransaction checks the fields of a request to make a transaction, returning a
+// violation for each invalid field. The payout of a settlement is its amount.
+func validateTransaction(requestID string, txn *wallet.Transaction) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	violate := func(field, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: description,
+		})
+	}
+
+	switch {
+	case strings.TrimSpace(requestID) == "":
+		violate("request_id", "must be set, so that the transaction can be safely retried")
+	case len(requestID) > maxIDLength:
+		violate("request_id", fmt.Sprintf("must be no longer than %d characters", maxIDLength))
+	}
+
+	switch txn.Type {
+	case wallet.Transaction_DEPOSIT, wallet.Transaction_WITHDRAWAL, wallet.Transaction_RESERVATION:
+		if txn.Amount <= 0 || txn.Amount > maxAmount {
+			violate("amount", fmt.Sprintf("must be a positive amount of cents, no more than %d", maxAmount))
+		}
+	case wallet.Transaction_SETTLEMENT:
+		if txn.Amount < 0 || txn.Amount > maxAmount {
+			violate("payout", fmt.Sprintf("must be an amount of cents from 0 to %d", maxAmount))
+		}
+	}
+
+	if txn.Type == wallet.Transaction_DEPOSIT || txn.Type == wallet.Transaction_WITHDRAWAL {
+		return violations
+	}
+
+	switch {
+	case strings.TrimSpace(txn.Reference) == "":
+		violate("reference", "must not be empty")
+	case len(txn.Reference) > maxIDLength:
+		violate("reference", fmt.Sprintf("must be no longer than %d characters", maxIDLength))
+	}
+
+	return violations
+}
+
+// invalidArgument builds an InvalidArgument status carrying field-level details of the violations.
+func invalidArgument(violations []*errdetails.BadRequest_FieldViolation) error {
+	descriptions := make([]string, len(violations))
+	for i, v := range violations {
+		descriptions[i] = v.Field + " " + v.Description
+	}
+
+	st := status.New(codes.InvalidArgument, "invalid request: "+strings.Join(descriptions, "; "))
+
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+
+	"git.neds.sh/matty/entain/wallet/db"
+	"git.neds.sh/matty/entain/wallet/proto/wallet"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Wallet interface {
+	// CreateAccount will open an account.
+	CreateAccount(ctx context.Context, in *wallet.CreateAccountRequest) (*wallet.Account, error)
+
+	// GetAccount will return a single account.
+	GetAccount(ctx context.Context, in *wallet.GetAccountRequest) (*wallet.Account, error)
+
+	// Deposit will add funds to an account.
+	Deposit(ctx context.Context, in *wallet.DepositRequest) (*wallet.Transaction, error)
+
+	// Withdraw will take funds from an account.
+	Withdraw(ctx context.Context, in *wallet.WithdrawRequest) (*wallet.Transaction, error)
+
+	// ReserveFunds will set aside funds of an account for a bet.
+	ReserveFunds(ctx context.Context, in *wallet.ReserveFundsRequest) (*wallet.Transaction, error)
+
+	// ReleaseFunds will return the funds reserved for a bet.
+	ReleaseFunds(ctx context.Context, in *wallet.ReleaseFundsRequest) (*wallet.Transaction, error)
+
+	// SettleFunds will settle the funds reserved for a bet.
+	SettleFunds(ctx context.Context, in *wallet.SettleFundsRequest) (*wallet.Transaction, error)
+
+	// ReverseFunds will reverse the release or settlement of the funds reserved for a bet.
+	ReverseFunds(ctx context.Context, in *wallet.ReverseFundsRequest) (*wallet.Transaction, error)
+
+	// ListTransactions will return the transactions of an account.
+	ListTransactions(ctx context.Context, in *wallet.ListTransactionsRequest) (*wallet.ListTransactionsResponse, error)
+}
+
+// walletService implements the Wallet interface.
+type walletService struct {
+	accountsRepo db.AccountsRepo
+}
+
+// NewWalletService instantiates and returns a new walletService.
+func NewWalletService(accountsRepo db.AccountsRepo) Wallet {
+	return &walletService{accountsRepo}
+}
+
+func (s *walletService) CreateAccount(ctx context.Context, in *wallet.CreateAccountRequest) (*wallet.Account, error) {
+	if violations := validateCreateAccount(in); len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+
+	if err := requireOwner(ctx, in.AccountId); err != nil {
+		return nil, err
+	}
+
+	account, err := s.accountsRepo.Create(in.AccountId)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return account, nil
+}
+
+func (s *walletService) GetAccount(ctx context.Context, in *wallet.GetAccountRequest) (*wallet.Account, error) {
+	id, err := parseAccountName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireOwner(ctx, id); err != nil {
+		return nil, err
+	}
+
+	account, err := s.accountsRepo.Get(id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return account, nil
+}
+
+func (s *walletService) Deposit(ctx context.Context, in *wallet.DepositRequest) (*wallet.Transaction, error) {
+	return s.transact(ctx, in.Name, in.RequestId, &wallet.Transaction{
+		Type:   wallet.Transaction_DEPOSIT,
+		Amount: in.Amount,
+	})
+}
+
+func (s *walletService) Withdraw(ctx context.Context, in *wallet.WithdrawRequest) (*wallet.Transaction, error) {
+	return s.transact(ctx, in.Name, in.RequestId, &wallet.Transaction{
+		Type:   wallet.Transaction_WITHDRAWAL,
+		Amount: in.Amount,
+	})
+}
+
+func (s *walletService) ReserveFunds(ctx context.Context, in *wallet.ReserveFundsRequest) (*wallet.Transaction, error) {
+	return s.transact(ctx, in.Name, in.RequestId, &wallet.Transaction{
+		Type:      wallet.Transaction_RESERVATION,
+		Amount:    in.Amount,
+		Reference: in.Reference,
+	})
+}
+
+func (s *walletService) ReleaseFunds(ctx context.Context, in *wallet.ReleaseFundsRequest) (*wallet.Transaction, error) {
+	return s.transact(ctx, in.Name, in.RequestId, &wallet.Transaction{
+		Type:      wallet.Transaction_RELEASE,
+		Reference: in.Reference,
+	})
+}
+
+func (s *walletService) SettleFunds(ctx context.Context, in *wallet.SettleFundsRequest) (*wallet.Transaction, error) {
+	return s.transact(ctx, in.Name, in.RequestId, &wallet.Transaction{
+		Type:      wallet.Transaction_SETTLEMENT,
+		Amount:    in.Payout,
+		Reference: in.Reference,
+	})
+}
+
+func (s *walletService) ReverseFunds(ctx context.Context, in *wallet.ReverseFundsRequest) (*wallet.Transaction, error) {
+	return s.transact(ctx, in.Name, in.RequestId, &wallet.Transaction{
+		Type:      wallet.Transaction_REVERSAL,
+		Reference: in.Reference,
+	})
+}
+
+func (s *walletService) ListTransactions(ctx context.Context, in *wallet.ListTransactionsRequest) (*wallet.ListTransactionsResponse, error) {
+	id, err := parseAccountName(in.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireOwner(ctx, id); err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.accountsRepo.ListTransactions(id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &wallet.ListTransactionsResponse{Transactions: transactions}, nil
+}
+
+// transact authorizes, validates and makes a transaction on the named account. A retried request
+// returns the transaction it already made.
+func (s *walletService) transact(ctx context.Context, name, requestID string, txn *wallet.Transaction) (*wallet.Transaction, error) {
+	id, err := parseAccountName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	txn.AccountId = id
+
+	if err := authorizeTransaction(ctx, txn); err != nil {
+		return nil, err
+	}
+
+	if violations := validateTransaction(requestID, txn); len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+
+	made, created, err := s.accountsRepo.Transact(requestID, txn)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if !created {
+		return sameTransaction(requestID, txn, made)
+	}
+
+	return made, nil
+}
+
+// sameTransaction returns the transaction already made by a request, provided the request is a
+// retry of the one that made it. The amount of a release is that of its reservation, and of a
+// reversal that of the transaction it reversed, so neither is compared.
+func sameTransaction(requestID string, in, existing *wallet.Transaction) (*wallet.Transaction, error) {
+	if in.AccountId != existing.AccountId ||
+		in.Type != existing.Type ||
+		in.Reference != existing.Reference ||
+		in.Type != wallet.Transaction_RELEASE && in.Type != wallet.Transaction_REVERSAL && in.Amount != existing.Amount {
+		return nil, status.Errorf(codes.AlreadyExists, "a different transaction has already been made with request_id %q", requestID)
+	}
+
+	return existing, nil
+}
+
+// toStatusError maps repository errors onto the gRPC status returned to the caller.
+func toStatusError(err error) error {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, db.ErrAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, db.ErrInsufficientFunds), errors.Is(err, db.ErrReservationClosed), errors.Is(err, db.ErrReservationOpen):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return err
+}